@@ -0,0 +1,86 @@
+package ttrace
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultMaxBatchLinks is the number of links [StartBatch] adds at most when
+// [BatchOptions].MaxLinks is zero. It matches the default link count limit of the OpenTelemetry
+// SDK, which drops further links anyway.
+const (
+	DefaultMaxBatchLinks = 128
+)
+
+// Span attributes recorded by [StartBatch].
+const (
+	// BatchParentsKey holds the number of distinct valid upstream span contexts of a batch.
+	BatchParentsKey = attribute.Key("ttrace.batch.parents")
+	// BatchLinksDroppedKey holds the number of distinct upstream span contexts beyond
+	// [BatchOptions].MaxLinks that were not linked.
+	BatchLinksDroppedKey = attribute.Key("ttrace.batch.links_dropped")
+)
+
+// BatchOptions configures [StartBatch].
+type BatchOptions struct {
+	// MaxLinks is the number of links added at most. Zero means [DefaultMaxBatchLinks]; raise it
+	// together with the span link count limit of the TracerProvider.
+	MaxLinks int
+}
+
+// StartBatch starts a new root span named spanName for work on items that originated from
+// different traces, linked to each upstream span context in parents. Invalid and duplicate span
+// contexts are skipped, and at most options.MaxLinks links are added, in the order of parents.
+// Values of ctx, such as baggage, are kept, but the span in ctx does not become the parent.
+func StartBatch(ctx context.Context, spanName string, parents []trace.SpanContext, options BatchOptions, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	maxLinks := options.MaxLinks
+	if maxLinks <= 0 {
+		maxLinks = DefaultMaxBatchLinks
+	}
+
+	type spanKey struct {
+		traceId trace.TraceID
+		spanId  trace.SpanID
+	}
+
+	links := make([]trace.Link, 0, min(len(parents), maxLinks))
+	seen := make(map[spanKey]struct{}, len(parents))
+
+	for _, parent := range parents {
+		if !parent.IsValid() {
+			continue
+		}
+
+		key := spanKey{traceId: parent.TraceID(), spanId: parent.SpanID()}
+
+		_, ok := seen[key]
+		if ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		if len(links) < maxLinks {
+			links = append(links, trace.Link{SpanContext: parent})
+		}
+	}
+
+	attributes := []attribute.KeyValue{
+		BatchParentsKey.Int(len(seen)),
+	}
+
+	dropped := len(seen) - len(links)
+	if dropped > 0 {
+		attributes = append(attributes, BatchLinksDroppedKey.Int(dropped))
+	}
+
+	opts = append([]trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attributes...),
+	}, opts...)
+
+	return Start(ctx, spanName, opts...)
+}
@@ -0,0 +1,99 @@
+package ttrace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestSpanContexts returns n sampled span contexts with distinct span IDs in one trace.
+func newTestSpanContexts(n int) []trace.SpanContext {
+	spanContexts := make([]trace.SpanContext, n)
+
+	for i := range spanContexts {
+		spanContexts[i] = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    newTestSpanContext().TraceID(),
+			SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, byte(i >> 8), byte(i) + 1},
+			TraceFlags: trace.FlagsSampled,
+		})
+	}
+
+	return spanContexts
+}
+
+func TestStartBatchDedupesLinks(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	parents := newTestSpanContexts(3)
+	parents = append(parents, parents[1], trace.SpanContext{}, parents[0].WithRemote(true))
+
+	ctx, request := Start(context.Background(), "request")
+
+	_, span := StartBatch(ctx, "batch", parents, BatchOptions{})
+	span.End()
+	request.End()
+
+	batch := spanNamed(t, exporter, "batch")
+	if batch.Parent.IsValid() {
+		t.Errorf("got parent %v, want a new root", batch.Parent)
+	}
+
+	if len(batch.Links) != 3 {
+		t.Fatalf("got %d links, want 3", len(batch.Links))
+	}
+
+	for i, link := range batch.Links {
+		if link.SpanContext.SpanID() != parents[i].SpanID() {
+			t.Errorf("got link %d to %s, want %s", i, link.SpanContext.SpanID(), parents[i].SpanID())
+		}
+	}
+
+	attrs := attributeMap(batch.Attributes)
+	if attrs[BatchParentsKey].AsInt64() != 3 {
+		t.Errorf("got %s %d, want 3", BatchParentsKey, attrs[BatchParentsKey].AsInt64())
+	}
+
+	_, ok := attrs[BatchLinksDroppedKey]
+	if ok {
+		t.Errorf("got %s without dropped links, want none", BatchLinksDroppedKey)
+	}
+}
+
+func TestStartBatchCapsLinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		parents   int
+		options   BatchOptions
+		wantLinks int
+	}{
+		{"default cap", DefaultMaxBatchLinks + 2, BatchOptions{}, DefaultMaxBatchLinks},
+		{"custom cap", 10, BatchOptions{MaxLinks: 4}, 4},
+		{"below cap", 3, BatchOptions{MaxLinks: 4}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := installTestTracing(t)
+
+			_, span := StartBatch(context.Background(), "batch", newTestSpanContexts(tt.parents), tt.options)
+			span.End()
+
+			batch := spanNamed(t, exporter, "batch")
+			if len(batch.Links) != tt.wantLinks {
+				t.Errorf("got %d links, want %d", len(batch.Links), tt.wantLinks)
+			}
+
+			attrs := attributeMap(batch.Attributes)
+			if attrs[BatchParentsKey].AsInt64() != int64(tt.parents) {
+				t.Errorf("got %s %d, want %d", BatchParentsKey, attrs[BatchParentsKey].AsInt64(), tt.parents)
+			}
+
+			wantDropped := int64(tt.parents - tt.wantLinks)
+			dropped, ok := attrs[BatchLinksDroppedKey]
+			if wantDropped > 0 && dropped.AsInt64() != wantDropped || wantDropped == 0 && ok {
+				t.Errorf("got %s %v, want %d", BatchLinksDroppedKey, dropped.Emit(), wantDropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package ttrace
+
+import (
+	"strings"
+)
+
+// KafkaHeadersCarrier adapts a slice of Kafka record headers to [propagation.TextMapCarrier]. H is
+// the header type of the client library, such as kafka.Header of segmentio/kafka-go and
+// confluent-kafka-go, sarama.RecordHeader, or kgo.RecordHeader of franz-go, so that ttrace does not
+// depend on any of them.
+//
+// Keys match case-insensitively. Get returns the value of the last matching header, and Set
+// removes every matching header before appending the new one, so re-injecting a forwarded record
+// never leaves two traceparent headers behind. Values are copied in both directions, so headers
+// backed by reused buffers are safe to use.
+type KafkaHeadersCarrier[H any] struct {
+	headers *[]H
+	split   func(header H) (string, []byte)
+	join    func(key string, value []byte) H
+}
+
+// NewKafkaHeadersCarrier returns a [KafkaHeadersCarrier] reading and updating *headers. split
+// returns the key and value of a header and join builds a header; for segmentio/kafka-go:
+//
+//	ttrace.NewKafkaHeadersCarrier(&msg.Headers,
+//		func(h kafka.Header) (string, []byte) { return h.Key, h.Value },
+//		func(key string, value []byte) kafka.Header { return kafka.Header{Key: key, Value: value} })
+func NewKafkaHeadersCarrier[H any](headers *[]H, split func(header H) (string, []byte), join func(key string, value []byte) H) *KafkaHeadersCarrier[H] {
+	return &KafkaHeadersCarrier[H]{
+		headers: headers,
+		split:   split,
+		join:    join,
+	}
+}
+
+// Get returns the value of the last header named key, or "" when there is none.
+func (c *KafkaHeadersCarrier[H]) Get(key string) string {
+	headers := *c.headers
+
+	for i := len(headers) - 1; i >= 0; i-- {
+		headerKey, value := c.split(headers[i])
+		if strings.EqualFold(headerKey, key) {
+			return string(value)
+		}
+	}
+
+	return ""
+}
+
+// Set replaces every header named key with a single header holding value.
+func (c *KafkaHeadersCarrier[H]) Set(key, value string) {
+	headers := (*c.headers)[:0:0]
+
+	for _, header := range *c.headers {
+		headerKey, _ := c.split(header)
+		if !strings.EqualFold(headerKey, key) {
+			headers = append(headers, header)
+		}
+	}
+
+	*c.headers = append(headers, c.join(key, []byte(value)))
+}
+
+// Keys returns the header keys in their first-seen spelling and order. Keys that differ only in
+// case are reported once, as they are by Get and Set.
+func (c *KafkaHeadersCarrier[H]) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	seen := make(map[string]struct{}, len(*c.headers))
+
+	for _, header := range *c.headers {
+		key, _ := c.split(header)
+
+		lowerKey := strings.ToLower(key)
+
+		_, ok := seen[lowerKey]
+		if ok {
+			continue
+		}
+
+		seen[lowerKey] = struct{}{}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// AMQPTableCarrier adapts AMQP 0-9-1 message headers, such as amqp091.Table converted with
+// AMQPTableCarrier(publishing.Headers), to [propagation.TextMapCarrier]. Get accepts string and
+// []byte values, since brokers and client libraries differ in which one they deliver, and Set
+// writes strings. The table must not be nil when injecting.
+type AMQPTableCarrier map[string]any
+
+// Get returns the value of key, matching case-insensitively when there is no exact match.
+func (c AMQPTableCarrier) Get(key string) string {
+	value, _ := lookupFold(c, key)
+
+	switch value := value.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	default:
+		return ""
+	}
+}
+
+// Set removes every entry matching key case-insensitively and stores value under key.
+func (c AMQPTableCarrier) Set(key, value string) {
+	deleteFold(c, key)
+	c[key] = value
+}
+
+// Keys returns the table keys.
+func (c AMQPTableCarrier) Keys() []string {
+	return mapKeys(c)
+}
+
+// NATSHeaderCarrier adapts NATS message headers, such as nats.Header converted with
+// NATSHeaderCarrier(msg.Header), to [propagation.TextMapCarrier]. Unlike
+// [propagation.HeaderCarrier], it does not canonicalize keys, which NATS preserves as sent. The
+// header must not be nil when injecting.
+type NATSHeaderCarrier map[string][]string
+
+// Get returns the first value of key, matching case-insensitively when there is no exact match.
+func (c NATSHeaderCarrier) Get(key string) string {
+	values, _ := lookupFold(c, key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set removes every entry matching key case-insensitively and stores value as the only value of
+// key.
+func (c NATSHeaderCarrier) Set(key, value string) {
+	deleteFold(c, key)
+	c[key] = []string{value}
+}
+
+// Keys returns the header keys.
+func (c NATSHeaderCarrier) Keys() []string {
+	return mapKeys(c)
+}
+
+// BytesMapCarrier adapts message headers held as map[string][]byte to
+// [propagation.TextMapCarrier]. Values are copied in both directions. The map must not be nil when
+// injecting.
+type BytesMapCarrier map[string][]byte
+
+// Get returns the value of key, matching case-insensitively when there is no exact match.
+func (c BytesMapCarrier) Get(key string) string {
+	value, _ := lookupFold(c, key)
+
+	return string(value)
+}
+
+// Set removes every entry matching key case-insensitively and stores value under key.
+func (c BytesMapCarrier) Set(key, value string) {
+	deleteFold(c, key)
+	c[key] = []byte(value)
+}
+
+// Keys returns the map keys.
+func (c BytesMapCarrier) Keys() []string {
+	return mapKeys(c)
+}
+
+// lookupFold returns the value of key in m, falling back to a case-insensitive match.
+func lookupFold[V any](m map[string]V, key string) (V, bool) {
+	value, ok := m[key]
+	if ok {
+		return value, true
+	}
+
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	var zero V
+
+	return zero, false
+}
+
+// deleteFold removes every key of m matching key case-insensitively.
+func deleteFold[V any](m map[string]V, key string) {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			delete(m, k)
+		}
+	}
+}
+
+// mapKeys returns the keys of m.
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,150 @@
+package ttrace
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testHeader mimics the record header type of a Kafka client library.
+type testHeader struct {
+	Key   string
+	Value []byte
+}
+
+// newTestKafkaCarrier returns a [KafkaHeadersCarrier] over headers.
+func newTestKafkaCarrier(headers *[]testHeader) *KafkaHeadersCarrier[testHeader] {
+	return NewKafkaHeadersCarrier(headers,
+		func(h testHeader) (string, []byte) { return h.Key, h.Value },
+		func(key string, value []byte) testHeader { return testHeader{Key: key, Value: value} })
+}
+
+func TestCarriersRoundTrip(t *testing.T) {
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := newTestSpanContext()
+	ctx := baggage.ContextWithBaggage(trace.ContextWithSpanContext(context.Background(), want), bag)
+
+	var kafkaHeaders []testHeader
+
+	tests := []struct {
+		name    string
+		carrier propagation.TextMapCarrier
+	}{
+		{"kafka", newTestKafkaCarrier(&kafkaHeaders)},
+		{"amqp", AMQPTableCarrier{}},
+		{"nats", NATSHeaderCarrier{}},
+		{"bytes", BytesMapCarrier{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propagator.Inject(ctx, tt.carrier)
+
+			keys := tt.carrier.Keys()
+			if !slices.Contains(keys, "traceparent") || !slices.Contains(keys, "baggage") {
+				t.Errorf("got keys %v, want traceparent and baggage", keys)
+			}
+
+			extracted := propagator.Extract(context.Background(), tt.carrier)
+
+			got := trace.SpanContextFromContext(extracted)
+			if got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() || !got.IsSampled() {
+				t.Errorf("got span context %v/%v, want %v/%v", got.TraceID(), got.SpanID(), want.TraceID(), want.SpanID())
+			}
+
+			tenant := baggage.FromContext(extracted).Member("tenant").Value()
+			if tenant != "acme" {
+				t.Errorf("got baggage tenant %q, want acme", tenant)
+			}
+		})
+	}
+}
+
+func TestKafkaHeadersCarrier(t *testing.T) {
+	headers := []testHeader{
+		{Key: "Traceparent", Value: []byte("first")},
+		{Key: "key", Value: []byte("value")},
+		{Key: "traceparent", Value: []byte("second")},
+	}
+	carrier := newTestKafkaCarrier(&headers)
+
+	got := carrier.Get("TRACEPARENT")
+	if got != "second" {
+		t.Errorf("got %q, want the last matching header", got)
+	}
+
+	keys := carrier.Keys()
+	if !slices.Equal(keys, []string{"Traceparent", "key"}) {
+		t.Errorf("got keys %v, want [Traceparent key]", keys)
+	}
+
+	carrier.Set("traceparent", "third")
+
+	want := []testHeader{
+		{Key: "key", Value: []byte("value")},
+		{Key: "traceparent", Value: []byte("third")},
+	}
+	if !slices.EqualFunc(headers, want, func(a, b testHeader) bool { return a.Key == b.Key && string(a.Value) == string(b.Value) }) {
+		t.Errorf("got headers %v, want %v", headers, want)
+	}
+
+	if carrier.Get("missing") != "" {
+		t.Error("got a value for a missing header, want none")
+	}
+}
+
+func TestKafkaHeadersCarrierCopiesValues(t *testing.T) {
+	buffer := []byte("value")
+	headers := []testHeader{{Key: "key", Value: buffer}}
+	carrier := newTestKafkaCarrier(&headers)
+
+	got := carrier.Get("key")
+	copy(buffer, "reuse")
+
+	if got != "value" {
+		t.Errorf("got %q after the buffer was reused, want value", got)
+	}
+}
+
+func TestMapCarriersMatchCaseInsensitively(t *testing.T) {
+	amqp := AMQPTableCarrier{"Traceparent": []byte("bytes"), "count": 1}
+	if amqp.Get("traceparent") != "bytes" || amqp.Get("count") != "" {
+		t.Errorf("got %q and %q, want bytes and an empty non-string value", amqp.Get("traceparent"), amqp.Get("count"))
+	}
+
+	amqp.Set("traceparent", "string")
+	if len(amqp) != 2 || amqp["traceparent"] != "string" {
+		t.Errorf("got table %v, want Traceparent replaced by traceparent", amqp)
+	}
+
+	nats := NATSHeaderCarrier{"TraceParent": {"first", "second"}}
+	if nats.Get("traceparent") != "first" {
+		t.Errorf("got %q, want the first value", nats.Get("traceparent"))
+	}
+
+	nats.Set("traceparent", "new")
+	if len(nats) != 1 || !slices.Equal(nats["traceparent"], []string{"new"}) {
+		t.Errorf("got header %v, want only traceparent", nats)
+	}
+
+	bytesMap := BytesMapCarrier{"TRACEPARENT": []byte("old")}
+	bytesMap.Set("traceparent", "new")
+	if len(bytesMap) != 1 || bytesMap.Get("TraceParent") != "new" {
+		t.Errorf("got map %v, want only traceparent", bytesMap)
+	}
+}
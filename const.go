@@ -19,19 +19,133 @@ const (
 	DeploymentEnvironmentName = "DEPLOYMENT_ENVIRONMENT_NAME"
 
 	// TracerMode selects the trace exporter. Valid values are [TracerModeDisable],
-	// [TracerModeStdout], and [TracerModeOTLP].
+	// [TracerModeStdout], [TracerModeOTLP], [TracerModeOTLPGRPC], and [TracerModeFile].
 	TracerMode = "TRACER_MODE"
 
-	// OTLPEndpoint is the tcfg key for the OTLP/HTTP trace endpoint (host:port) used when
-	// [TracerMode] is [TracerModeOTLP].
+	// OTLPEndpoint is the tcfg key for the OTLP trace endpoint (host:port) used when [TracerMode] is
+	// [TracerModeOTLP] (OTLP/HTTP, typically port 4318) or [TracerModeOTLPGRPC] (OTLP/gRPC,
+	// typically port 4317).
 	OTLPEndpoint = "TRACER_OTLP_ENDPOINT"
 
+	// OTLPTLS is the tcfg key that enables TLS for the OTLP exporter. It is implied when any of the
+	// other OTLP TLS keys is set.
+	OTLPTLS = "TRACER_OTLP_TLS"
+	// OTLPCAFile is the tcfg key for a PEM CA bundle used to verify the collector certificate.
+	OTLPCAFile = "TRACER_OTLP_CA_FILE"
+	// OTLPCertFile is the tcfg key for the PEM client certificate presented for mutual TLS.
+	OTLPCertFile = "TRACER_OTLP_CERT_FILE"
+	// OTLPKeyFile is the tcfg key for the PEM private key matching [OTLPCertFile].
+	OTLPKeyFile = "TRACER_OTLP_KEY_FILE"
+	// OTLPServerName is the tcfg key that overrides the server name used for TLS verification.
+	OTLPServerName = "TRACER_OTLP_SERVER_NAME"
+
+	// OTLPHeaders is the tcfg key for headers sent with each OTLP export request, as a
+	// comma-separated list of key=value pairs with optionally percent-encoded values, matching the
+	// OTEL_EXPORTER_OTLP_HEADERS format.
+	OTLPHeaders = "TRACER_OTLP_HEADERS"
+	// OTLPCompression is the tcfg key for the OTLP payload compression, [CompressionGzip] or
+	// [CompressionNone].
+	OTLPCompression = "TRACER_OTLP_COMPRESSION"
+	// OTLPURLPath is the tcfg key that overrides the OTLP/HTTP request path (default "/v1/traces").
+	OTLPURLPath = "TRACER_OTLP_URL_PATH"
+
+	// TracerFilePath is the tcfg key for the file that [TracerModeFile] appends spans to, one JSON
+	// object per line.
+	TracerFilePath = "TRACER_FILE_PATH"
+	// TracerFileFormat is the tcfg key for the line format of [TracerModeFile], [FileFormatOTLP]
+	// (the default) or [FileFormatStdout].
+	TracerFileFormat = "TRACER_FILE_FORMAT"
+	// TracerFileMaxSizeMB is the tcfg key for the size in mebibytes at which the span file is
+	// rotated. Unset means 100.
+	TracerFileMaxSizeMB = "TRACER_FILE_MAX_SIZE_MB"
+	// TracerFileMaxAge is the tcfg key for the age, such as "24h", at which the span file is
+	// rotated. Unset disables age-based rotation.
+	TracerFileMaxAge = "TRACER_FILE_MAX_AGE"
+	// TracerFileMaxBackups is the tcfg key for the number of rotated span files retained. Unset
+	// keeps all rotated files.
+	TracerFileMaxBackups = "TRACER_FILE_MAX_BACKUPS"
+	// TracerFileCompress is the tcfg key that enables gzip compression of rotated span files.
+	TracerFileCompress = "TRACER_FILE_COMPRESS"
+
+	// TracerQueueDir is the tcfg key for a directory where batches that fail to export are spooled
+	// and replayed from once the exporter recovers. Unset disables the persistent queue. See
+	// [PersistentQueueExporter].
+	TracerQueueDir = "TRACER_QUEUE_DIR"
+	// TracerQueueMaxSizeMB is the tcfg key for the disk budget of the persistent queue in
+	// mebibytes. Unset means 512.
+	TracerQueueMaxSizeMB = "TRACER_QUEUE_MAX_SIZE_MB"
+	// TracerQueueRetryInterval is the tcfg key for how often spooled batches are replayed, such as
+	// "5s".
+	TracerQueueRetryInterval = "TRACER_QUEUE_RETRY_INTERVAL"
+
+	// TracerPropagators is the tcfg key for a comma-separated, ordered list of propagators to
+	// install, for example "tracecontext,baggage,b3". See [NewPropagator] for accepted names.
+	TracerPropagators = "TRACER_PROPAGATORS"
+
 	// TracerSamplingFraction is the tcfg key for the trace ID ratio stage used by
 	// [GuaranteedThroughputProbabilitySampler]. Set it to -1 to disable ratio sampling.
 	TracerSamplingFraction = "TRACER_SAMPLING_FRACTION"
 	// TracerMaxTracesPerSec is the tcfg key for the per-second root-trace cap applied after ratio
 	// sampling. Set it to -1 to disable the throughput cap.
 	TracerMaxTracesPerSec = "TRACER_MAX_TRACES_PER_SEC"
+	// TracerSamplingReloadInterval is the tcfg key for the interval, such as "30s", at which
+	// [InitFromEnv] re-reads the sampling keys and applies them to [Sampler]. Zero or unset disables
+	// reloading.
+	TracerSamplingReloadInterval = "TRACER_SAMPLING_RELOAD_INTERVAL"
+	// TracerSamplingFile is the tcfg key for a file of KEY=VALUE lines that, when set, is re-read
+	// instead of tcfg on every [TracerSamplingReloadInterval]. See [FileSamplingSource].
+	TracerSamplingFile = "TRACER_SAMPLING_FILE"
+	// TracerSamplingServerURL is the tcfg key for a Jaeger-compatible sampling strategy endpoint,
+	// such as "http://jaeger-agent:5778/sampling". When set, root sampling follows the strategy it
+	// serves, with the [TracerSamplingFraction] and [TracerMaxTracesPerSec] sampler as fallback. See
+	// [RemoteSampler].
+	TracerSamplingServerURL = "TRACER_SAMPLING_SERVER_URL"
+	// TracerSamplingRefreshInterval is the tcfg key for the interval, such as "1m", at which the
+	// [TracerSamplingServerURL] strategy is polled. Unset means one minute.
+	TracerSamplingRefreshInterval = "TRACER_SAMPLING_REFRESH_INTERVAL"
+	// TracerSamplingRulesFile is the tcfg key for a YAML or JSON rule set loaded with
+	// [LoadSamplingRules]. Root spans matching no rule use the rule set default or, without one, the
+	// configured ratio and cap sampler.
+	TracerSamplingRulesFile = "TRACER_SAMPLING_RULES_FILE"
+	// TracerSamplingTargetTracesPerSec is the tcfg key for the root-trace throughput targeted by an
+	// [AdaptiveSampler]. When set, the adaptive sampler replaces the fixed [TracerSamplingFraction]
+	// and [TracerMaxTracesPerSec] stages.
+	TracerSamplingTargetTracesPerSec = "TRACER_SAMPLING_TARGET_TRACES_PER_SEC"
+	// TracerSamplingLowerBoundTracesPerSec is the tcfg key for the guaranteed minimum root-trace
+	// rate of the [TracerSamplingTargetTracesPerSec] adaptive sampler. Unset means no floor.
+	TracerSamplingLowerBoundTracesPerSec = "TRACER_SAMPLING_LOWER_BOUND_TRACES_PER_SEC"
+
+	// TracerTailSampling is the tcfg key that enables tail sampling, which keeps head-sampled
+	// traces plus traces containing an error or a span slower than
+	// [TracerTailSamplingLatencyThreshold]. See [TailSamplingProcessor].
+	TracerTailSampling = "TRACER_TAIL_SAMPLING"
+	// TracerTailSamplingDecisionWait is the tcfg key for how long spans of a trace are buffered
+	// before the tail sampling decision, such as "10s".
+	TracerTailSamplingDecisionWait = "TRACER_TAIL_SAMPLING_DECISION_WAIT"
+	// TracerTailSamplingLatencyThreshold is the tcfg key for the span duration, such as "500ms",
+	// at which tail sampling keeps a trace. Unset disables the latency policy.
+	TracerTailSamplingLatencyThreshold = "TRACER_TAIL_SAMPLING_LATENCY_THRESHOLD"
+	// TracerTailSamplingMaxTraces is the tcfg key for the number of traces tail sampling buffers at
+	// once.
+	TracerTailSamplingMaxTraces = "TRACER_TAIL_SAMPLING_MAX_TRACES"
+	// TracerTailSamplingMaxSpansPerTrace is the tcfg key for the number of spans tail sampling
+	// buffers per trace.
+	TracerTailSamplingMaxSpansPerTrace = "TRACER_TAIL_SAMPLING_MAX_SPANS_PER_TRACE"
+	// TracerTailSamplingMaxPendingSpans is the tcfg key for the number of spans of kept traces that
+	// tail sampling holds until the next export.
+	TracerTailSamplingMaxPendingSpans = "TRACER_TAIL_SAMPLING_MAX_PENDING_SPANS"
+
+	// TracerDebugBaggageKey is the tcfg key naming a baggage member, such as "ttrace.debug", that
+	// forces sampling. See [DebugSampler].
+	TracerDebugBaggageKey = "TRACER_DEBUG_BAGGAGE_KEY"
+	// TracerDebugHeader is the tcfg key naming a request header, such as "ttrace-debug-id", that
+	// forces sampling.
+	TracerDebugHeader = "TRACER_DEBUG_HEADER"
+	// TracerDebugTraceStateKey is the tcfg key naming a tracestate key that forces sampling.
+	TracerDebugTraceStateKey = "TRACER_DEBUG_TRACESTATE_KEY"
+	// TracerDebugMaxTracesPerSec is the tcfg key for the per-second limit on traces forced by a debug
+	// signal. Unset means [DefaultDebugMaxTracesPerSecond].
+	TracerDebugMaxTracesPerSec = "TRACER_DEBUG_MAX_TRACES_PER_SEC"
 )
 
 // Numeric values accepted by the [TracerMode] configuration key (environment variable TRACER_MODE).
@@ -39,4 +153,6 @@ const (
 	TracerModeDisable = iota
 	TracerModeStdout
 	TracerModeOTLP
+	TracerModeOTLPGRPC
+	TracerModeFile
 )
@@ -0,0 +1,385 @@
+package ttrace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// destinationQueueSize is the number of spans an [asyncExporter] holds for one destination in tail
+// sampling mode, matching the default queue size of a batch span processor.
+const (
+	destinationQueueSize = 2048
+)
+
+// Destination describes an additional export target configured with [WithDestinations]. Each
+// destination has its own batch span processor, so a slow or unreachable destination does not
+// delay the others. With [WithTailSampling], the [TailSamplingProcessor] hands kept traces to a
+// bounded queue per destination instead, so it never waits for a destination to export.
+type Destination struct {
+	// Name identifies the destination in configuration errors.
+	Name string
+	// Options configure the destination exporter the way they configure the primary one:
+	// [WithMode], [WithEndpoint], [WithTLS], [WithHeaders], [WithHeaderProvider], [WithCompression],
+	// [WithURLPath], [WithFile], [WithPersistentQueue], and [WithExporter]. Destinations do not
+	// inherit the options of the primary exporter, and other options are ignored.
+	Options []Option
+	// Filter selects the spans exported to the destination, for example [ErrorSpans]. Nil exports
+	// every sampled span.
+	Filter func(span sdktrace.ReadOnlySpan) bool
+}
+
+// ErrorSpans is a [Destination] filter that selects spans with error status.
+func ErrorSpans(span sdktrace.ReadOnlySpan) bool {
+	return span.Status().Code == codes.Error
+}
+
+// newSpanProcessors returns a batch span processor for primary, when not nil, followed by one for
+// each destination. When a destination fails, the processors built for the others are shut down;
+// primary is left to the caller.
+func newSpanProcessors(ctx context.Context, primary sdktrace.SpanExporter, destinations []Destination) ([]sdktrace.SpanProcessor, error) {
+	var processors []sdktrace.SpanProcessor
+
+	for _, destination := range destinations {
+		exporter, err := newDestinationExporter(ctx, destination)
+		if err != nil {
+			return nil, errors.Join(err, shutdownSpanProcessors(ctx, processors))
+		}
+
+		var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter)
+
+		if destination.Filter != nil {
+			processor = filteringSpanProcessor{
+				filter: destination.Filter,
+				next:   processor,
+			}
+		}
+
+		processors = append(processors, processor)
+	}
+
+	if primary != nil {
+		processors = append([]sdktrace.SpanProcessor{sdktrace.NewBatchSpanProcessor(primary)}, processors...)
+	}
+
+	return processors, nil
+}
+
+// newFanoutExporter returns an exporter passing spans to primary, when not nil, and to each
+// destination through its filter, each behind its own [asyncExporter]. It feeds a
+// [TailSamplingProcessor], whose spans are not sampled and would be dropped by a batch span
+// processor. When a destination fails, the exporters built for the others are shut down; primary is
+// left to the caller.
+func newFanoutExporter(ctx context.Context, primary sdktrace.SpanExporter, destinations []Destination) (sdktrace.SpanExporter, error) {
+	var exporters []sdktrace.SpanExporter
+
+	for _, destination := range destinations {
+		exporter, err := newDestinationExporter(ctx, destination)
+		if err != nil {
+			return nil, errors.Join(err, fanoutExporter{exporters: exporters}.Shutdown(ctx))
+		}
+
+		exporter = newAsyncExporter(exporter, destinationQueueSize)
+
+		if destination.Filter != nil {
+			exporter = filteringExporter{
+				filter: destination.Filter,
+				next:   exporter,
+			}
+		}
+
+		exporters = append(exporters, exporter)
+	}
+
+	if primary != nil {
+		exporters = append([]sdktrace.SpanExporter{newAsyncExporter(primary, destinationQueueSize)}, exporters...)
+	}
+
+	return fanoutExporter{exporters: exporters}, nil
+}
+
+// newDestinationExporter builds the exporter described by destination.
+func newDestinationExporter(ctx context.Context, destination Destination) (sdktrace.SpanExporter, error) {
+	destinationCfg := newConfig(destination.Options...)
+	if destinationCfg.err != nil {
+		return nil, fmt.Errorf("ttrace: configure destination %s: %w", destination.Name, destinationCfg.err)
+	}
+
+	if destinationCfg.exporter == nil && destinationCfg.mode == TracerModeDisable {
+		return nil, fmt.Errorf("ttrace: configure destination %s: no exporter or mode set", destination.Name)
+	}
+
+	exporter, _, err := newQueuedExporter(ctx, destinationCfg)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: configure destination %s: %w", destination.Name, err)
+	}
+
+	return exporter, nil
+}
+
+// shutdownSpanProcessors shuts down processors and joins their errors.
+func shutdownSpanProcessors(ctx context.Context, processors []sdktrace.SpanProcessor) error {
+	var errs []error
+
+	for _, processor := range processors {
+		errs = append(errs, processor.Shutdown(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdownExporter shuts down exporter, which may be nil when only destinations are configured.
+func shutdownExporter(ctx context.Context, exporter sdktrace.SpanExporter) error {
+	if exporter == nil {
+		return nil
+	}
+
+	return exporter.Shutdown(ctx)
+}
+
+// filteringSpanProcessor passes ended spans accepted by filter to next.
+type filteringSpanProcessor struct {
+	filter func(span sdktrace.ReadOnlySpan) bool
+	next   sdktrace.SpanProcessor
+}
+
+// OnStart forwards span to next.
+func (p filteringSpanProcessor) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, span)
+}
+
+// OnEnd forwards span to next when the filter accepts it.
+func (p filteringSpanProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	if p.filter(span) {
+		p.next.OnEnd(span)
+	}
+}
+
+// Shutdown shuts down next.
+func (p filteringSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes next.
+func (p filteringSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// filteringExporter passes the spans accepted by filter to next.
+type filteringExporter struct {
+	filter func(span sdktrace.ReadOnlySpan) bool
+	next   sdktrace.SpanExporter
+}
+
+// ExportSpans exports the accepted spans, skipping the call when none is accepted.
+func (e filteringExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	var accepted []sdktrace.ReadOnlySpan
+
+	for _, span := range spans {
+		if e.filter(span) {
+			accepted = append(accepted, span)
+		}
+	}
+
+	if len(accepted) == 0 {
+		return nil
+	}
+
+	return e.next.ExportSpans(ctx, accepted)
+}
+
+// ForceFlush flushes next when it supports flushing.
+func (e filteringExporter) ForceFlush(ctx context.Context) error {
+	return forceFlushExporter(ctx, e.next)
+}
+
+// Shutdown shuts down next.
+func (e filteringExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
+
+// fanoutExporter is a [sdktrace.SpanExporter] passing every batch to several exporters in turn.
+// [newFanoutExporter] puts each behind an [asyncExporter], so a slow one does not delay the others.
+type fanoutExporter struct {
+	exporters []sdktrace.SpanExporter
+}
+
+// ExportSpans exports spans to every exporter and joins their errors.
+func (e fanoutExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	var errs []error
+
+	for _, exporter := range e.exporters {
+		errs = append(errs, exporter.ExportSpans(ctx, spans))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ForceFlush flushes every exporter that supports flushing.
+func (e fanoutExporter) ForceFlush(ctx context.Context) error {
+	var errs []error
+
+	for _, exporter := range e.exporters {
+		errs = append(errs, forceFlushExporter(ctx, exporter))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Shutdown shuts down every exporter.
+func (e fanoutExporter) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	for _, exporter := range e.exporters {
+		errs = append(errs, exporter.Shutdown(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// forceFlushExporter flushes exporter when it supports flushing.
+func forceFlushExporter(ctx context.Context, exporter sdktrace.SpanExporter) error {
+	flusher, ok := exporter.(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return nil
+	}
+
+	return flusher.ForceFlush(ctx)
+}
+
+// asyncExporter is a [sdktrace.SpanExporter] that queues batches for next and exports them from
+// its own goroutine. Unlike a batch span processor, it does not drop spans that are not sampled.
+// When the queue holds maxSpans spans, further batches are dropped. Export errors are logged.
+type asyncExporter struct {
+	next     sdktrace.SpanExporter
+	maxSpans int
+
+	lock   sync.Mutex
+	closed bool
+	queued int
+
+	queue chan asyncExport
+	done  chan struct{}
+}
+
+// asyncExport is a queued batch, or a flush request when flushed is not nil.
+type asyncExport struct {
+	spans   []sdktrace.ReadOnlySpan
+	flushed chan error
+}
+
+// newAsyncExporter returns an [asyncExporter] for next holding up to maxSpans spans.
+func newAsyncExporter(next sdktrace.SpanExporter, maxSpans int) *asyncExporter {
+	e := &asyncExporter{
+		next:     next,
+		maxSpans: maxSpans,
+		queue:    make(chan asyncExport, maxSpans),
+		done:     make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// ExportSpans queues spans without waiting for next. It drops them when the queue is full.
+func (e *asyncExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.closed {
+		return errors.New("ttrace: exporter is shut down")
+	}
+
+	if e.queued+len(spans) > e.maxSpans {
+		log.Printf("ttrace: destination queue full, dropping %d spans", len(spans))
+
+		return nil
+	}
+
+	e.queued += len(spans)
+	e.queue <- asyncExport{spans: spans}
+
+	return nil
+}
+
+// ForceFlush waits until the batches queued so far are exported, then flushes next when it
+// supports flushing.
+func (e *asyncExporter) ForceFlush(ctx context.Context) error {
+	flushed := make(chan error, 1)
+
+	e.lock.Lock()
+
+	if e.closed {
+		e.lock.Unlock()
+
+		return nil
+	}
+
+	// run receives without the lock, so sending under it cannot deadlock even when the queue is full.
+	e.queue <- asyncExport{flushed: flushed}
+
+	e.lock.Unlock()
+
+	select {
+	case err := <-flushed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the queued batches and shuts down next.
+func (e *asyncExporter) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+
+	e.lock.Unlock()
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return e.next.Shutdown(ctx)
+}
+
+// run exports queued batches until the queue is closed.
+func (e *asyncExporter) run() {
+	defer close(e.done)
+
+	for export := range e.queue {
+		if export.flushed != nil {
+			export.flushed <- forceFlushExporter(context.Background(), e.next)
+
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+
+		err := e.next.ExportSpans(ctx, export.spans)
+		if err != nil {
+			log.Printf("ttrace: destination export failed: %v", err)
+		}
+
+		cancel()
+
+		e.lock.Lock()
+		e.queued -= len(export.spans)
+		e.lock.Unlock()
+	}
+}
@@ -0,0 +1,287 @@
+package ttrace
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// shutdownRecorder is an exporter that records whether it was shut down.
+type shutdownRecorder struct {
+	shutdown atomic.Bool
+}
+
+func (e *shutdownRecorder) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return nil
+}
+
+func (e *shutdownRecorder) Shutdown(context.Context) error {
+	e.shutdown.Store(true)
+
+	return nil
+}
+
+// spanNames returns the names of the spans in exporter.
+func spanNames(exporter *tracetest.InMemoryExporter) []string {
+	var names []string
+
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+
+	return names
+}
+
+func TestTailSamplingExportsToDestinations(t *testing.T) {
+	primary := tracetest.NewInMemoryExporter()
+	errorsOnly := tracetest.NewInMemoryExporter()
+	everything := tracetest.NewInMemoryExporter()
+
+	handle, err := New(context.Background(),
+		WithExporter(primary),
+		WithSampler(sdktrace.NeverSample()),
+		WithTailSampling(TailSamplingConfig{DecisionWait: time.Hour}),
+		WithDestinations(
+			Destination{Name: "errors", Options: []Option{WithExporter(errorsOnly)}, Filter: ErrorSpans},
+			Destination{Name: "everything", Options: []Option{WithExporter(everything)}},
+		),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	tracer := handle.TracerProvider().Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+	root.End()
+
+	_, dropped := tracer.Start(context.Background(), "dropped")
+	dropped.End()
+
+	// The in-memory exporters forget their spans on shutdown, so flush instead.
+	err = handle.TracerProvider().ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		exporter *tracetest.InMemoryExporter
+		want     int
+	}{
+		{name: "primary", exporter: primary, want: 2},
+		{name: "errors", exporter: errorsOnly, want: 1},
+		{name: "everything", exporter: everything, want: 2},
+	}
+
+	for _, tt := range tests {
+		got := spanNames(tt.exporter)
+		if len(got) != tt.want {
+			t.Errorf("%s destination exported %q, want %d spans of the failed trace", tt.name, got, tt.want)
+		}
+	}
+
+	got := spanNames(errorsOnly)
+	if len(got) == 1 && got[0] != "child" {
+		t.Errorf("errors destination exported %q, want the failed child", got)
+	}
+}
+
+func TestFanoutExporterJoinsErrors(t *testing.T) {
+	healthy := tracetest.NewInMemoryExporter()
+
+	exporter := fanoutExporter{exporters: []sdktrace.SpanExporter{failingExporter{}, healthy}}
+
+	err := exporter.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{tailSpan(newTestTraceID(), "op", false)})
+	if err == nil {
+		t.Error("ExportSpans succeeded with a failing exporter, want its error")
+	}
+
+	if len(healthy.GetSpans()) != 1 {
+		t.Errorf("healthy exporter got %d spans, want 1 despite the failing one", len(healthy.GetSpans()))
+	}
+}
+
+func TestNewShutsDownExportersOnError(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           func(primary, destination *shutdownRecorder, dir string) []Option
+		noDestinations bool
+	}{
+		{
+			name: "invalid destination",
+			opts: func(primary, destination *shutdownRecorder, _ string) []Option {
+				return []Option{
+					WithExporter(primary),
+					WithDestinations(
+						Destination{Name: "valid", Options: []Option{WithExporter(destination)}},
+						Destination{Name: "invalid"},
+					),
+				}
+			},
+		},
+		{
+			name: "invalid destination with tail sampling",
+			opts: func(primary, destination *shutdownRecorder, _ string) []Option {
+				return []Option{
+					WithExporter(primary),
+					WithTailSampling(TailSamplingConfig{}),
+					WithDestinations(
+						Destination{Name: "valid", Options: []Option{WithExporter(destination)}},
+						Destination{Name: "invalid"},
+					),
+				}
+			},
+		},
+		{
+			name: "invalid tail sampling",
+			opts: func(primary, destination *shutdownRecorder, _ string) []Option {
+				return []Option{
+					WithExporter(primary),
+					WithTailSampling(TailSamplingConfig{MaxTraces: -1}),
+					WithDestinations(Destination{Name: "valid", Options: []Option{WithExporter(destination)}}),
+				}
+			},
+		},
+		{
+			name: "invalid persistent queue",
+			opts: func(primary, _ *shutdownRecorder, dir string) []Option {
+				return []Option{
+					WithExporter(primary),
+					WithPersistentQueue(PersistentQueueOptions{Dir: dir, MaxBytes: -1}),
+				}
+			},
+			noDestinations: true,
+		},
+		{
+			name: "persistent queue before invalid destination",
+			opts: func(primary, _ *shutdownRecorder, dir string) []Option {
+				return []Option{
+					WithExporter(primary),
+					WithPersistentQueue(PersistentQueueOptions{Dir: dir, RetryInterval: time.Hour}),
+					WithDestinations(Destination{Name: "invalid"}),
+				}
+			},
+			noDestinations: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &shutdownRecorder{}
+			destination := &shutdownRecorder{}
+
+			_, err := New(context.Background(), tt.opts(primary, destination, t.TempDir())...)
+			if err == nil {
+				t.Fatal("New succeeded, want a configuration error")
+			}
+
+			// The persistent queue shuts down the exporter it wraps.
+			if !primary.shutdown.Load() {
+				t.Error("primary exporter was not shut down")
+			}
+
+			if !tt.noDestinations && !destination.shutdown.Load() {
+				t.Error("destination exporter was not shut down")
+			}
+		})
+	}
+}
+
+// blockingExporter blocks every export until release is closed.
+type blockingExporter struct {
+	release chan struct{}
+}
+
+func (e blockingExporter) ExportSpans(ctx context.Context, _ []sdktrace.ReadOnlySpan) error {
+	select {
+	case <-e.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e blockingExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestTailSamplingDestinationsDoNotWaitForEachOther(t *testing.T) {
+	primary := tracetest.NewInMemoryExporter()
+	blocked := blockingExporter{release: make(chan struct{})}
+
+	handle, err := New(context.Background(),
+		WithExporter(primary),
+		WithTailSampling(TailSamplingConfig{DecisionWait: 10 * time.Millisecond}),
+		WithDestinations(Destination{Name: "blocked", Options: []Option{WithExporter(blocked)}}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	defer func() {
+		close(blocked.release)
+		handle.Shutdown(context.Background())
+	}()
+
+	tracer := handle.TracerProvider().Tracer("test")
+
+	// The second trace is decided after the first export to the blocked destination has started.
+	for i, name := range []string{"first", "second"} {
+		_, span := tracer.Start(context.Background(), name)
+		span.SetStatus(codes.Error, "boom")
+		span.End()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(primary.GetSpans()) <= i {
+			if time.Now().After(deadline) {
+				t.Fatalf("primary exported %q while the other destination was blocked, want %s too", spanNames(primary), name)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
+
+func TestAsyncExporterDropsWhenFull(t *testing.T) {
+	blocked := blockingExporter{release: make(chan struct{})}
+	exporter := newAsyncExporter(blocked, 2)
+
+	spans := []sdktrace.ReadOnlySpan{tailSpan(newTestTraceID(), "op", true), tailSpan(newTestTraceID(), "op", true)}
+
+	for range 3 {
+		err := exporter.ExportSpans(context.Background(), spans)
+		if err != nil {
+			t.Fatalf("ExportSpans: %v", err)
+		}
+	}
+
+	exporter.lock.Lock()
+	queued := exporter.queued
+	exporter.lock.Unlock()
+
+	if queued > 2 {
+		t.Errorf("queued %d spans, want at most 2", queued)
+	}
+
+	close(blocked.release)
+
+	err := exporter.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	err = exporter.ExportSpans(context.Background(), spans)
+	if err == nil {
+		t.Error("ExportSpans after Shutdown succeeded, want error")
+	}
+}
@@ -1,14 +1,19 @@
 // Package ttrace provides OpenTelemetry tracing helpers for Go applications.
 //
-// The package initializes the global TracerProvider and TextMapPropagator, supports stdout and
-// OTLP/HTTP exporters with noop fallback, and exposes helpers for span creation, context
-// propagation, baggage handling, and manual trace-context injection. The instrumentation scope name
-// used by [Start] and [GetTracer] is [TracerName].
+// The package initializes the global TracerProvider and TextMapPropagator, supports stdout,
+// OTLP/HTTP, OTLP/gRPC, and rotating JSON-lines file exporters with noop fallback, and exposes helpers for span creation,
+// context propagation, baggage handling, and manual trace-context injection. The instrumentation
+// scope name used by [Start] and [GetTracer] is [TracerName].
 //
-// During package initialization, configuration is loaded through [github.com/choveylee/tcfg] using
-// keys such as [TracerMode], [OTLPEndpoint], and [AppName]. Call [Shutdown] before process exit
+// Importing the package has no side effects. Call [Init] with functional options such as
+// [WithMode], [WithEndpoint], [WithSampling], and [WithExporter] to install the global providers, or
+// [InitFromEnv] to load the same settings through [github.com/choveylee/tcfg] using keys such as
+// [TracerMode], [OTLPEndpoint], and [AppName]. Both return a [Handle] and the startup error; enable
+// [WithNoopFallback] to log the error and continue with noop tracing instead. [New] builds a
+// provider without installing it globally. Call [Handle.Shutdown] or [Shutdown] before process exit
 // when an SDK-backed provider is active so pending spans are flushed.
 //
 // Applications that use [github.com/gin-gonic/gin] should import subpackage
-// [github.com/choveylee/ttrace/gin]. The core module itself does not depend on Gin.
+// [github.com/choveylee/ttrace/gin], and applications that use [google.golang.org/grpc] should import
+// [github.com/choveylee/ttrace/grpc]. The core module itself depends on neither.
 package ttrace
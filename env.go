@@ -0,0 +1,635 @@
+package ttrace
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choveylee/tcfg"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// Standard OpenTelemetry environment variables consulted by [InitFromEnv] when the corresponding
+// ttrace key is unset.
+const (
+	otelSDKDisabled        = "OTEL_SDK_DISABLED"
+	otelServiceName        = "OTEL_SERVICE_NAME"
+	otelResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+	otelTracesExporter     = "OTEL_TRACES_EXPORTER"
+	otelExporterProtocol   = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	otelExporterEndpoint   = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	otelTracesSampler      = "OTEL_TRACES_SAMPLER"
+	otelTracesSamplerArg   = "OTEL_TRACES_SAMPLER_ARG"
+	otelPropagators        = "OTEL_PROPAGATORS"
+)
+
+// ConfigSource identifies where an effective configuration value reported by [Handle.Settings]
+// came from.
+type ConfigSource string
+
+// Configuration sources in precedence order: ttrace keys win over standard OTEL_* variables, which
+// win over built-in defaults.
+const (
+	SourceTtrace  ConfigSource = "ttrace"
+	SourceOTel    ConfigSource = "otel"
+	SourceDefault ConfigSource = "default"
+)
+
+// Setting describes one effective configuration value resolved by [InitFromEnv].
+type Setting struct {
+	// Key is the ttrace configuration key the value applies to, for example [TracerMode].
+	Key string
+	// Variable is the variable that supplied the value. It equals Key for [SourceTtrace], names the
+	// OTEL_* variable for [SourceOTel], and is empty for [SourceDefault].
+	Variable string
+	// Value is the effective value in ttrace form. Header values are redacted.
+	Value string
+	// Source reports which configuration layer supplied Value.
+	Source ConfigSource
+}
+
+// envResolver resolves configuration values across the ttrace, OTEL_*, and default layers and
+// records the source of every effective value.
+type envResolver struct {
+	settings []Setting
+	err      error
+
+	otelResource map[string]string
+	otelSampler  *otelSamplerValues
+	otelEndpoint *otelEndpointValues
+}
+
+// otelSamplerValues holds the ttrace sampling values equivalent to OTEL_TRACES_SAMPLER.
+// fractionVariable names the variable that supplied samplingFraction; maxTracesPerSecond is always
+// derived from OTEL_TRACES_SAMPLER. The remote values are set for the jaeger_remote samplers and
+// come from OTEL_TRACES_SAMPLER_ARG.
+type otelSamplerValues struct {
+	fractionVariable   string
+	samplingFraction   string
+	maxTracesPerSecond string
+
+	remoteURL      string
+	remoteInterval string
+}
+
+// otelEndpointValues holds the ttrace endpoint values derived from OTEL_EXPORTER_OTLP_ENDPOINT.
+type otelEndpointValues struct {
+	endpoint string
+	tls      string
+	urlPath  string
+}
+
+// otelLookup returns a value derived from OTEL_* variables together with the variable name. The
+// final result reports whether a usable value was found.
+type otelLookup func() (string, string, bool)
+
+// envOptions translates tcfg keys, falling back to standard OTEL_* variables, into [Option] values.
+// Malformed values are reported through [New] so that the noop fallback policy applies to them.
+func envOptions() []Option {
+	r := &envResolver{}
+
+	r.otelResource = r.parseOTelResource()
+	r.otelSampler = otelSampler()
+	r.otelEndpoint = r.parseOTelEndpoint()
+
+	tracerMode := r.resolveInt(TracerMode, otelTracerMode, TracerModeDisable)
+	otlpEndpoint := r.resolve(OTLPEndpoint, r.otelEndpointValue(func(v *otelEndpointValues) string { return v.endpoint }), "")
+
+	tlsOptions := TLSOptions{
+		Enabled:    r.resolveBool(OTLPTLS, r.otelEndpointValue(func(v *otelEndpointValues) string { return v.tls }), false),
+		CAFile:     r.resolve(OTLPCAFile, nil, ""),
+		CertFile:   r.resolve(OTLPCertFile, nil, ""),
+		KeyFile:    r.resolve(OTLPKeyFile, nil, ""),
+		ServerName: r.resolve(OTLPServerName, nil, ""),
+	}
+
+	opts := []Option{
+		WithMode(tracerMode),
+		WithEndpoint(otlpEndpoint),
+		WithTLS(tlsOptions),
+	}
+
+	rawHeaders, ok := ttraceValue(OTLPHeaders)
+	if ok {
+		r.record(OTLPHeaders, OTLPHeaders, "<redacted>", SourceTtrace)
+
+		headers, err := parseHeaders(rawHeaders)
+		if err != nil {
+			r.fail(err)
+		} else {
+			opts = append(opts, WithHeaders(headers))
+		}
+	}
+
+	if tracerMode == TracerModeFile {
+		opts = append(opts, WithFile(FileExporterOptions{
+			Path:       r.resolve(TracerFilePath, nil, ""),
+			Format:     strings.ToLower(r.resolve(TracerFileFormat, nil, FileFormatOTLP)),
+			MaxSize:    int64(r.resolveInt(TracerFileMaxSizeMB, nil, defaultFileMaxSize>>20)) << 20,
+			MaxAge:     r.resolveDuration(TracerFileMaxAge, nil, 0),
+			MaxBackups: r.resolveInt(TracerFileMaxBackups, nil, 0),
+			Compress:   r.resolveBool(TracerFileCompress, nil, false),
+		}))
+	}
+
+	queueDir := r.resolve(TracerQueueDir, nil, "")
+	if queueDir != "" {
+		opts = append(opts, WithPersistentQueue(PersistentQueueOptions{
+			Dir:           queueDir,
+			MaxBytes:      int64(r.resolveInt(TracerQueueMaxSizeMB, nil, defaultQueueMaxBytes>>20)) << 20,
+			RetryInterval: r.resolveDuration(TracerQueueRetryInterval, nil, defaultQueueRetryInterval),
+		}))
+	}
+
+	compression := strings.ToLower(r.resolve(OTLPCompression, nil, ""))
+	urlPath := r.resolve(OTLPURLPath, r.otelEndpointValue(func(v *otelEndpointValues) string { return v.urlPath }), "")
+
+	samplingFraction := r.resolveFloat(TracerSamplingFraction, r.otelSamplerValue(func(v *otelSamplerValues) (string, string) {
+		return v.samplingFraction, v.fractionVariable
+	}), 0.1)
+	maxTracesPerSecond := r.resolveFloat(TracerMaxTracesPerSec, r.otelSamplerValue(func(v *otelSamplerValues) (string, string) {
+		return v.maxTracesPerSecond, otelTracesSampler
+	}), 1.0)
+
+	targetTracesPerSecond := r.resolveFloat(TracerSamplingTargetTracesPerSec, nil, 0)
+	if targetTracesPerSecond != 0 {
+		opts = append(opts, WithAdaptiveSampling(AdaptiveSamplingConfig{
+			TargetTracesPerSecond:     targetTracesPerSecond,
+			LowerBoundTracesPerSecond: r.resolveFloat(TracerSamplingLowerBoundTracesPerSec, nil, 0),
+		}))
+	}
+
+	remoteSamplingURL := r.resolve(TracerSamplingServerURL, r.otelSamplerValue(func(v *otelSamplerValues) (string, string) {
+		return v.remoteURL, otelTracesSamplerArg
+	}), "")
+	if remoteSamplingURL != "" {
+		refreshInterval := r.resolveDuration(TracerSamplingRefreshInterval, r.otelSamplerValue(func(v *otelSamplerValues) (string, string) {
+			return v.remoteInterval, otelTracesSamplerArg
+		}), defaultRemoteSamplingInterval)
+
+		opts = append(opts, WithRemoteSampling(remoteSamplingURL, refreshInterval))
+	}
+
+	samplingRulesFile := r.resolve(TracerSamplingRulesFile, nil, "")
+	if samplingRulesFile != "" {
+		rules, err := LoadSamplingRules(samplingRulesFile)
+		if err != nil {
+			r.fail(err)
+		} else {
+			opts = append(opts, WithSamplingRules(rules))
+		}
+	}
+
+	debugSampling := DebugSamplingOptions{
+		BaggageKey:         r.resolve(TracerDebugBaggageKey, nil, ""),
+		Header:             r.resolve(TracerDebugHeader, nil, ""),
+		TraceStateKey:      r.resolve(TracerDebugTraceStateKey, nil, ""),
+		MaxTracesPerSecond: r.resolveFloat(TracerDebugMaxTracesPerSec, nil, DefaultDebugMaxTracesPerSecond),
+	}
+	if debugSampling.enabled() {
+		opts = append(opts, WithDebugSampling(debugSampling))
+	}
+
+	tailSampling := r.resolveBool(TracerTailSampling, nil, false)
+	if tailSampling {
+		opts = append(opts, WithTailSampling(TailSamplingConfig{
+			DecisionWait:     r.resolveDuration(TracerTailSamplingDecisionWait, nil, defaultTailDecisionWait),
+			LatencyThreshold: r.resolveDuration(TracerTailSamplingLatencyThreshold, nil, 0),
+			MaxTraces:        r.resolveInt(TracerTailSamplingMaxTraces, nil, defaultTailMaxTraces),
+			MaxSpansPerTrace: r.resolveInt(TracerTailSamplingMaxSpansPerTrace, nil, defaultTailMaxSpansPerTrace),
+			MaxPendingSpans:  r.resolveInt(TracerTailSamplingMaxPendingSpans, nil, defaultTailMaxPendingSpans),
+		}))
+	}
+
+	reloadInterval := r.resolveDuration(TracerSamplingReloadInterval, nil, 0)
+	if reloadInterval > 0 {
+		source := TcfgSamplingSource()
+
+		samplingFile := r.resolve(TracerSamplingFile, nil, "")
+		if samplingFile != "" {
+			source = FileSamplingSource(samplingFile)
+		}
+
+		opts = append(opts, WithSamplingWatcher(reloadInterval, source))
+	}
+
+	opts = append(opts,
+		WithCompression(compression),
+		WithURLPath(urlPath),
+		WithSampling(samplingFraction, maxTracesPerSecond),
+		WithResource(r.newResource()),
+		WithPropagator(r.newPropagator()),
+	)
+
+	if r.err != nil {
+		opts = append(opts, withConfigError(r.err))
+	}
+
+	return append(opts, withSettings(r.settings))
+}
+
+// resolve returns the ttrace value for key when set, otherwise the value produced by otel when it
+// is non-nil and finds one, otherwise def. The winning source is recorded.
+func (r *envResolver) resolve(key string, otel otelLookup, def string) string {
+	val, ok := ttraceValue(key)
+	if ok {
+		r.record(key, key, val, SourceTtrace)
+
+		return val
+	}
+
+	if otel != nil {
+		val, variable, ok := otel()
+		if ok {
+			r.record(key, variable, val, SourceOTel)
+
+			return val
+		}
+	}
+
+	r.record(key, "", def, SourceDefault)
+
+	return def
+}
+
+// resolveInt is [envResolver.resolve] for integer values. Malformed values are recorded as
+// configuration errors and def is returned.
+func (r *envResolver) resolveInt(key string, otel otelLookup, def int) int {
+	val := r.resolve(key, otel, strconv.Itoa(def))
+
+	ret, err := strconv.Atoi(val)
+	if err != nil {
+		r.fail(fmt.Errorf("ttrace: invalid %s %q: %w", key, val, err))
+
+		return def
+	}
+
+	return ret
+}
+
+// resolveFloat is [envResolver.resolve] for floating-point values. Malformed values are recorded
+// as configuration errors and def is returned.
+func (r *envResolver) resolveFloat(key string, otel otelLookup, def float64) float64 {
+	val := r.resolve(key, otel, strconv.FormatFloat(def, 'g', -1, 64))
+
+	ret, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		r.fail(fmt.Errorf("ttrace: invalid %s %q: %w", key, val, err))
+
+		return def
+	}
+
+	return ret
+}
+
+// resolveBool is [envResolver.resolve] for boolean values. Malformed values are recorded as
+// configuration errors and def is returned.
+func (r *envResolver) resolveBool(key string, otel otelLookup, def bool) bool {
+	val := r.resolve(key, otel, strconv.FormatBool(def))
+
+	ret, err := strconv.ParseBool(val)
+	if err != nil {
+		r.fail(fmt.Errorf("ttrace: invalid %s %q: %w", key, val, err))
+
+		return def
+	}
+
+	return ret
+}
+
+// resolveDuration is [envResolver.resolve] for [time.ParseDuration] values. Malformed values are
+// recorded as configuration errors and def is returned.
+func (r *envResolver) resolveDuration(key string, otel otelLookup, def time.Duration) time.Duration {
+	val := r.resolve(key, otel, def.String())
+
+	ret, err := time.ParseDuration(val)
+	if err != nil {
+		r.fail(fmt.Errorf("ttrace: invalid %s %q: %w", key, val, err))
+
+		return def
+	}
+
+	return ret
+}
+
+// record appends an effective setting.
+func (r *envResolver) record(key, variable, value string, source ConfigSource) {
+	r.settings = append(r.settings, Setting{
+		Key:      key,
+		Variable: variable,
+		Value:    value,
+		Source:   source,
+	})
+}
+
+// fail records the first configuration error.
+func (r *envResolver) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// otelEndpointValue returns an [otelLookup] that selects a field of the parsed
+// OTEL_EXPORTER_OTLP_ENDPOINT.
+func (r *envResolver) otelEndpointValue(field func(v *otelEndpointValues) string) otelLookup {
+	return func() (string, string, bool) {
+		if r.otelEndpoint == nil {
+			return "", "", false
+		}
+
+		val := field(r.otelEndpoint)
+
+		return val, otelExporterEndpoint, val != ""
+	}
+}
+
+// otelSamplerValue returns an [otelLookup] that selects a value and its variable from the
+// translated OTEL_TRACES_SAMPLER.
+func (r *envResolver) otelSamplerValue(field func(v *otelSamplerValues) (string, string)) otelLookup {
+	return func() (string, string, bool) {
+		if r.otelSampler == nil {
+			return "", "", false
+		}
+
+		val, variable := field(r.otelSampler)
+
+		return val, variable, val != ""
+	}
+}
+
+// parseOTelEndpoint translates the OTEL_EXPORTER_OTLP_ENDPOINT URL into a host:port endpoint, a TLS
+// flag derived from the scheme, and a traces URL path when the URL carries a base path.
+func (r *envResolver) parseOTelEndpoint() *otelEndpointValues {
+	val, ok := otelValue(otelExporterEndpoint)
+	if !ok {
+		return nil
+	}
+
+	if !strings.Contains(val, "://") {
+		return &otelEndpointValues{endpoint: val}
+	}
+
+	u, err := url.Parse(val)
+	if err != nil || u.Host == "" {
+		r.fail(fmt.Errorf("ttrace: invalid %s %q", otelExporterEndpoint, val))
+
+		return nil
+	}
+
+	values := &otelEndpointValues{
+		endpoint: u.Host,
+		tls:      strconv.FormatBool(u.Scheme == "https"),
+	}
+
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if basePath != "" {
+		values.urlPath = basePath + "/v1/traces"
+	}
+
+	return values
+}
+
+// parseOTelResource parses OTEL_RESOURCE_ATTRIBUTES.
+func (r *envResolver) parseOTelResource() map[string]string {
+	val, ok := otelValue(otelResourceAttributes)
+	if !ok {
+		return nil
+	}
+
+	attrs, err := parseKeyValues(otelResourceAttributes, val)
+	if err != nil {
+		r.fail(err)
+
+		return nil
+	}
+
+	return attrs
+}
+
+// newResource builds a [resource.Resource] containing service.name and optional attributes derived
+// from tcfg keys such as [ServiceVersion] and [DeploymentEnvironmentName], falling back to
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES. Remaining OTEL_RESOURCE_ATTRIBUTES entries are
+// added as-is.
+func (r *envResolver) newResource() *resource.Resource {
+	appName := r.resolve(AppName, r.otelResourceValue(otelServiceName, semconv.ServiceNameKey), executableName())
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(appName),
+	}
+
+	optionalAttrs := []struct {
+		key     string
+		attrKey attribute.Key
+	}{
+		{ServiceVersion, semconv.ServiceVersionKey},
+		{ServiceNamespace, semconv.ServiceNamespaceKey},
+		{ServiceInstanceID, semconv.ServiceInstanceIDKey},
+		{DeploymentEnvironmentName, semconv.DeploymentEnvironmentNameKey},
+	}
+
+	known := map[attribute.Key]bool{
+		semconv.ServiceNameKey: true,
+	}
+
+	for _, optionalAttr := range optionalAttrs {
+		known[optionalAttr.attrKey] = true
+
+		val := r.resolve(optionalAttr.key, r.otelResourceValue("", optionalAttr.attrKey), "")
+		if val != "" {
+			attrs = append(attrs, optionalAttr.attrKey.String(val))
+		}
+	}
+
+	for key, val := range r.otelResource {
+		if !known[attribute.Key(key)] {
+			attrs = append(attrs, attribute.String(key, val))
+		}
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
+
+// otelResourceValue returns an [otelLookup] that consults variable (when non-empty) and then the
+// attrKey entry of OTEL_RESOURCE_ATTRIBUTES.
+func (r *envResolver) otelResourceValue(variable string, attrKey attribute.Key) otelLookup {
+	return func() (string, string, bool) {
+		if variable != "" {
+			val, ok := otelValue(variable)
+			if ok {
+				return val, variable, true
+			}
+		}
+
+		val := strings.TrimSpace(r.otelResource[string(attrKey)])
+
+		return val, otelResourceAttributes, val != ""
+	}
+}
+
+// otelTracerMode translates OTEL_SDK_DISABLED, OTEL_TRACES_EXPORTER, and
+// OTEL_EXPORTER_OTLP_PROTOCOL into a [TracerMode] value. Unrecognized values are logged and
+// ignored.
+func otelTracerMode() (string, string, bool) {
+	disabled, ok := otelValue(otelSDKDisabled)
+	if ok && strings.EqualFold(disabled, "true") {
+		return strconv.Itoa(TracerModeDisable), otelSDKDisabled, true
+	}
+
+	exporter, ok := otelValue(otelTracesExporter)
+	if !ok {
+		return "", "", false
+	}
+
+	// Only the first exporter of a comma-separated list is used.
+	exporter, _, _ = strings.Cut(exporter, ",")
+
+	switch strings.ToLower(strings.TrimSpace(exporter)) {
+	case "none":
+		return strconv.Itoa(TracerModeDisable), otelTracesExporter, true
+	case "console":
+		return strconv.Itoa(TracerModeStdout), otelTracesExporter, true
+	case "otlp":
+		protocol, _ := otelValue(otelExporterProtocol)
+
+		switch strings.ToLower(protocol) {
+		case "", "http/protobuf":
+			return strconv.Itoa(TracerModeOTLP), otelTracesExporter, true
+		case "grpc":
+			return strconv.Itoa(TracerModeOTLPGRPC), otelExporterProtocol, true
+		default:
+			log.Printf("ttrace: unsupported %s %q; using http/protobuf", otelExporterProtocol, protocol)
+
+			return strconv.Itoa(TracerModeOTLP), otelTracesExporter, true
+		}
+	default:
+		log.Printf("ttrace: unsupported %s %q; ignoring", otelTracesExporter, exporter)
+
+		return "", "", false
+	}
+}
+
+// otelSampler translates OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG into ttrace sampling
+// values. ttrace samplers always respect the parent decision, so the non-parent-based variants
+// behave like their parentbased_* counterparts. Unrecognized samplers are logged and ignored.
+func otelSampler() *otelSamplerValues {
+	sampler, ok := otelValue(otelTracesSampler)
+	if !ok {
+		return nil
+	}
+
+	switch strings.ToLower(sampler) {
+	case "always_on", "parentbased_always_on":
+		return &otelSamplerValues{fractionVariable: otelTracesSampler, samplingFraction: "-1", maxTracesPerSecond: "-1"}
+	case "always_off", "parentbased_always_off":
+		return &otelSamplerValues{fractionVariable: otelTracesSampler, samplingFraction: "0", maxTracesPerSecond: "-1"}
+	case "traceidratio", "parentbased_traceidratio":
+		arg, ok := otelValue(otelTracesSamplerArg)
+		if !ok {
+			return &otelSamplerValues{fractionVariable: otelTracesSampler, samplingFraction: "1", maxTracesPerSecond: "-1"}
+		}
+
+		return &otelSamplerValues{fractionVariable: otelTracesSamplerArg, samplingFraction: arg, maxTracesPerSecond: "-1"}
+	case "jaeger_remote", "parentbased_jaeger_remote":
+		return otelRemoteSampler()
+	default:
+		log.Printf("ttrace: unsupported %s %q; ignoring", otelTracesSampler, sampler)
+
+		return nil
+	}
+}
+
+// otelRemoteSampler translates the jaeger_remote OTEL_TRACES_SAMPLER_ARG (endpoint,
+// pollingIntervalMs, and initialSamplingRate) into remote sampling values. The initial sampling
+// rate, 0.001 by default, becomes the fallback sampling fraction. A malformed argument is logged
+// and the defaults are used.
+func otelRemoteSampler() *otelSamplerValues {
+	values := &otelSamplerValues{
+		fractionVariable:   otelTracesSampler,
+		samplingFraction:   "0.001",
+		maxTracesPerSecond: "-1",
+		remoteURL:          "http://localhost:5778/sampling",
+	}
+
+	arg, ok := otelValue(otelTracesSamplerArg)
+	if !ok {
+		return values
+	}
+
+	args, err := parseKeyValues(otelTracesSamplerArg, arg)
+	if err != nil {
+		log.Printf("ttrace: %v; using jaeger_remote defaults", err)
+
+		return values
+	}
+
+	if endpoint := args["endpoint"]; endpoint != "" {
+		values.remoteURL = endpoint
+	}
+
+	if pollingInterval := args["pollingIntervalMs"]; pollingInterval != "" {
+		values.remoteInterval = pollingInterval + "ms"
+	}
+
+	if initialSamplingRate := args["initialSamplingRate"]; initialSamplingRate != "" {
+		values.fractionVariable = otelTracesSamplerArg
+		values.samplingFraction = initialSamplingRate
+	}
+
+	return values
+}
+
+// newPropagator builds the propagator named by [TracerPropagators] or OTEL_PROPAGATORS, or the
+// W3C default when neither is set. Unknown names are recorded as configuration errors.
+func (r *envResolver) newPropagator() propagation.TextMapPropagator {
+	val := r.resolve(TracerPropagators, otelEnv(otelPropagators), PropagatorTraceContext+","+PropagatorBaggage)
+
+	propagator, err := NewPropagator(strings.Split(val, ",")...)
+	if err != nil {
+		r.fail(err)
+
+		return defaultPropagator()
+	}
+
+	return propagator
+}
+
+// otelEnv returns an [otelLookup] that reads the OTEL_* variable name as-is.
+func otelEnv(name string) otelLookup {
+	return func() (string, string, bool) {
+		val, ok := otelValue(name)
+
+		return val, name, ok
+	}
+}
+
+// ttraceValue returns the trimmed tcfg value for key. Empty values are treated as unset. All keys
+// except [AppName] are qualified with [tcfg.LocalKey].
+func ttraceValue(key string) (string, bool) {
+	if key != AppName {
+		key = tcfg.LocalKey(key)
+	}
+
+	val, err := tcfg.String(key)
+	if err != nil {
+		return "", false
+	}
+
+	val = strings.TrimSpace(val)
+
+	return val, val != ""
+}
+
+// otelValue returns the trimmed value of the OTEL_* environment variable name. Empty values are
+// treated as unset, as required by the OpenTelemetry specification.
+func otelValue(name string) (string, bool) {
+	val := strings.TrimSpace(os.Getenv(name))
+
+	return val, val != ""
+}
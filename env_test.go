@@ -0,0 +1,461 @@
+package ttrace
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// envTestVariables lists the ttrace keys and OTEL_* variables read by the tests below. They are
+// cleared before every case so that the environment of the test process does not leak in.
+var envTestVariables = []string{
+	AppName, ServiceVersion, TracerMode, OTLPEndpoint, OTLPTLS, OTLPURLPath, TracerPropagators,
+	TracerSamplingFraction, TracerMaxTracesPerSec, TracerSamplingServerURL,
+	TracerSamplingRefreshInterval,
+	otelSDKDisabled, otelServiceName, otelResourceAttributes, otelTracesExporter,
+	otelExporterProtocol, otelExporterEndpoint, otelTracesSampler, otelTracesSamplerArg,
+	otelPropagators,
+}
+
+// setTestEnv clears [envTestVariables] and sets env for the duration of t.
+func setTestEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for _, name := range envTestVariables {
+		// t.Setenv restores the original value; Unsetenv makes the variable absent rather than
+		// empty, which matters for APP_NAME because tcfg qualifies keys whenever it is present.
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	for name, val := range env {
+		t.Setenv(name, val)
+	}
+}
+
+// findSetting returns the setting recorded for key.
+func findSetting(settings []Setting, key string) (Setting, bool) {
+	for _, setting := range settings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+
+	return Setting{}, false
+}
+
+func TestEnvOptionsPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []Setting
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{},
+			want: []Setting{
+				{Key: TracerMode, Value: strconv.Itoa(TracerModeDisable), Source: SourceDefault},
+				{Key: OTLPEndpoint, Value: "", Source: SourceDefault},
+				{Key: TracerSamplingFraction, Value: "0.1", Source: SourceDefault},
+				{Key: TracerMaxTracesPerSec, Value: "1", Source: SourceDefault},
+				{Key: TracerPropagators, Value: "tracecontext,baggage", Source: SourceDefault},
+			},
+		},
+		{
+			name: "sdk disabled wins over exporter",
+			env: map[string]string{
+				otelSDKDisabled:    "true",
+				otelTracesExporter: "otlp",
+			},
+			want: []Setting{
+				{Key: TracerMode, Variable: otelSDKDisabled, Value: strconv.Itoa(TracerModeDisable), Source: SourceOTel},
+			},
+		},
+		{
+			name: "sdk not disabled",
+			env: map[string]string{
+				otelSDKDisabled:    "false",
+				otelTracesExporter: "console",
+			},
+			want: []Setting{
+				{Key: TracerMode, Variable: otelTracesExporter, Value: strconv.Itoa(TracerModeStdout), Source: SourceOTel},
+			},
+		},
+		{
+			name: "none exporter",
+			env: map[string]string{
+				otelTracesExporter: "none",
+			},
+			want: []Setting{
+				{Key: TracerMode, Variable: otelTracesExporter, Value: strconv.Itoa(TracerModeDisable), Source: SourceOTel},
+			},
+		},
+		{
+			name: "otlp exporter",
+			env: map[string]string{
+				otelTracesExporter: "otlp",
+			},
+			want: []Setting{
+				{Key: TracerMode, Variable: otelTracesExporter, Value: strconv.Itoa(TracerModeOTLP), Source: SourceOTel},
+			},
+		},
+		{
+			name: "otlp exporter over grpc",
+			env: map[string]string{
+				otelTracesExporter:   "otlp",
+				otelExporterProtocol: "grpc",
+			},
+			want: []Setting{
+				{Key: TracerMode, Variable: otelExporterProtocol, Value: strconv.Itoa(TracerModeOTLPGRPC), Source: SourceOTel},
+			},
+		},
+		{
+			name: "unsupported exporter falls back to default",
+			env: map[string]string{
+				otelTracesExporter: "zipkin",
+			},
+			want: []Setting{
+				{Key: TracerMode, Value: strconv.Itoa(TracerModeDisable), Source: SourceDefault},
+			},
+		},
+		{
+			name: "ttrace mode wins",
+			env: map[string]string{
+				TracerMode:         strconv.Itoa(TracerModeStdout),
+				otelTracesExporter: "otlp",
+				otelSDKDisabled:    "true",
+			},
+			want: []Setting{
+				{Key: TracerMode, Variable: TracerMode, Value: strconv.Itoa(TracerModeStdout), Source: SourceTtrace},
+			},
+		},
+		{
+			name: "endpoint url",
+			env: map[string]string{
+				otelExporterEndpoint: "https://collector:4318/base/",
+			},
+			want: []Setting{
+				{Key: OTLPEndpoint, Variable: otelExporterEndpoint, Value: "collector:4318", Source: SourceOTel},
+				{Key: OTLPTLS, Variable: otelExporterEndpoint, Value: "true", Source: SourceOTel},
+				{Key: OTLPURLPath, Variable: otelExporterEndpoint, Value: "/base/v1/traces", Source: SourceOTel},
+			},
+		},
+		{
+			name: "endpoint without base path",
+			env: map[string]string{
+				otelExporterEndpoint: "http://collector:4318",
+			},
+			want: []Setting{
+				{Key: OTLPEndpoint, Variable: otelExporterEndpoint, Value: "collector:4318", Source: SourceOTel},
+				{Key: OTLPTLS, Variable: otelExporterEndpoint, Value: "false", Source: SourceOTel},
+				{Key: OTLPURLPath, Value: "", Source: SourceDefault},
+			},
+		},
+		{
+			name: "ttrace endpoint wins",
+			env: map[string]string{
+				OTLPEndpoint:         "local:4318",
+				OTLPTLS:              "false",
+				otelExporterEndpoint: "https://collector:4318",
+			},
+			want: []Setting{
+				{Key: OTLPEndpoint, Variable: OTLPEndpoint, Value: "local:4318", Source: SourceTtrace},
+				{Key: OTLPTLS, Variable: OTLPTLS, Value: "false", Source: SourceTtrace},
+			},
+		},
+		{
+			name: "always_on sampler",
+			env: map[string]string{
+				otelTracesSampler: "always_on",
+			},
+			want: []Setting{
+				{Key: TracerSamplingFraction, Variable: otelTracesSampler, Value: "-1", Source: SourceOTel},
+				{Key: TracerMaxTracesPerSec, Variable: otelTracesSampler, Value: "-1", Source: SourceOTel},
+			},
+		},
+		{
+			name: "parentbased_always_off sampler",
+			env: map[string]string{
+				otelTracesSampler: "parentbased_always_off",
+			},
+			want: []Setting{
+				{Key: TracerSamplingFraction, Variable: otelTracesSampler, Value: "0", Source: SourceOTel},
+				{Key: TracerMaxTracesPerSec, Variable: otelTracesSampler, Value: "-1", Source: SourceOTel},
+			},
+		},
+		{
+			name: "traceidratio sampler with argument",
+			env: map[string]string{
+				otelTracesSampler:    "traceidratio",
+				otelTracesSamplerArg: "0.25",
+			},
+			want: []Setting{
+				{Key: TracerSamplingFraction, Variable: otelTracesSamplerArg, Value: "0.25", Source: SourceOTel},
+				{Key: TracerMaxTracesPerSec, Variable: otelTracesSampler, Value: "-1", Source: SourceOTel},
+			},
+		},
+		{
+			name: "traceidratio sampler without argument",
+			env: map[string]string{
+				otelTracesSampler: "parentbased_traceidratio",
+			},
+			want: []Setting{
+				{Key: TracerSamplingFraction, Variable: otelTracesSampler, Value: "1", Source: SourceOTel},
+			},
+		},
+		{
+			name: "ttrace sampling wins",
+			env: map[string]string{
+				TracerSamplingFraction: "0.5",
+				otelTracesSampler:      "traceidratio",
+				otelTracesSamplerArg:   "0.25",
+			},
+			want: []Setting{
+				{Key: TracerSamplingFraction, Variable: TracerSamplingFraction, Value: "0.5", Source: SourceTtrace},
+				{Key: TracerMaxTracesPerSec, Variable: otelTracesSampler, Value: "-1", Source: SourceOTel},
+			},
+		},
+		{
+			name: "jaeger_remote sampler with argument",
+			env: map[string]string{
+				otelTracesSampler:    "jaeger_remote",
+				otelTracesSamplerArg: "endpoint=http://agent:5778/sampling,pollingIntervalMs=5000,initialSamplingRate=0.2",
+			},
+			want: []Setting{
+				{Key: TracerSamplingServerURL, Variable: otelTracesSamplerArg, Value: "http://agent:5778/sampling", Source: SourceOTel},
+				{Key: TracerSamplingRefreshInterval, Variable: otelTracesSamplerArg, Value: "5000ms", Source: SourceOTel},
+				{Key: TracerSamplingFraction, Variable: otelTracesSamplerArg, Value: "0.2", Source: SourceOTel},
+			},
+		},
+		{
+			name: "jaeger_remote sampler defaults",
+			env: map[string]string{
+				otelTracesSampler: "parentbased_jaeger_remote",
+			},
+			want: []Setting{
+				{Key: TracerSamplingServerURL, Variable: otelTracesSamplerArg, Value: "http://localhost:5778/sampling", Source: SourceOTel},
+				{Key: TracerSamplingRefreshInterval, Value: defaultRemoteSamplingInterval.String(), Source: SourceDefault},
+				{Key: TracerSamplingFraction, Variable: otelTracesSampler, Value: "0.001", Source: SourceOTel},
+			},
+		},
+		{
+			name: "ttrace remote sampling wins",
+			env: map[string]string{
+				TracerSamplingServerURL:       "http://local:5778/sampling",
+				TracerSamplingRefreshInterval: "10s",
+				otelTracesSampler:             "jaeger_remote",
+				otelTracesSamplerArg:          "endpoint=http://agent:5778/sampling,pollingIntervalMs=5000",
+			},
+			want: []Setting{
+				{Key: TracerSamplingServerURL, Variable: TracerSamplingServerURL, Value: "http://local:5778/sampling", Source: SourceTtrace},
+				{Key: TracerSamplingRefreshInterval, Variable: TracerSamplingRefreshInterval, Value: "10s", Source: SourceTtrace},
+			},
+		},
+		{
+			name: "resource attributes",
+			env: map[string]string{
+				otelResourceAttributes: "service.name=cart,service.version=1.2.3",
+			},
+			want: []Setting{
+				{Key: AppName, Variable: otelResourceAttributes, Value: "cart", Source: SourceOTel},
+				{Key: ServiceVersion, Variable: otelResourceAttributes, Value: "1.2.3", Source: SourceOTel},
+			},
+		},
+		{
+			name: "service name wins over resource attributes",
+			env: map[string]string{
+				otelServiceName:        "checkout",
+				otelResourceAttributes: "service.name=cart",
+			},
+			want: []Setting{
+				{Key: AppName, Variable: otelServiceName, Value: "checkout", Source: SourceOTel},
+			},
+		},
+		{
+			name: "ttrace service name wins",
+			env: map[string]string{
+				AppName:                "orders",
+				ServiceVersion:         "2.0.0",
+				otelServiceName:        "checkout",
+				otelResourceAttributes: "service.version=1.2.3",
+			},
+			want: []Setting{
+				{Key: AppName, Variable: AppName, Value: "orders", Source: SourceTtrace},
+				{Key: ServiceVersion, Variable: ServiceVersion, Value: "2.0.0", Source: SourceTtrace},
+			},
+		},
+		{
+			name: "propagators",
+			env: map[string]string{
+				otelPropagators: "b3,baggage",
+			},
+			want: []Setting{
+				{Key: TracerPropagators, Variable: otelPropagators, Value: "b3,baggage", Source: SourceOTel},
+			},
+		},
+		{
+			name: "ttrace propagators win",
+			env: map[string]string{
+				TracerPropagators: "jaeger",
+				otelPropagators:   "b3",
+			},
+			want: []Setting{
+				{Key: TracerPropagators, Variable: TracerPropagators, Value: "jaeger", Source: SourceTtrace},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestEnv(t, tt.env)
+
+			cfg := newConfig(envOptions()...)
+			if cfg.err != nil {
+				t.Fatalf("got error %v, want nil", cfg.err)
+			}
+
+			for _, want := range tt.want {
+				got, ok := findSetting(cfg.settings, want.Key)
+				if !ok {
+					t.Errorf("setting %s not recorded", want.Key)
+
+					continue
+				}
+
+				if got != want {
+					t.Errorf("got setting %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvOptionsAppliesOTelValues(t *testing.T) {
+	setTestEnv(t, map[string]string{
+		otelServiceName:        "checkout",
+		otelResourceAttributes: "service.version=1.2.3,team=payments",
+		otelTracesExporter:     "otlp",
+		otelExporterProtocol:   "grpc",
+		otelExporterEndpoint:   "https://collector:4317/base",
+		otelTracesSampler:      "jaeger_remote",
+		otelTracesSamplerArg:   "endpoint=http://agent:5778/sampling,pollingIntervalMs=5000,initialSamplingRate=0.2",
+		otelPropagators:        "b3multi",
+	})
+
+	cfg := newConfig(envOptions()...)
+	if cfg.err != nil {
+		t.Fatalf("got error %v, want nil", cfg.err)
+	}
+
+	if cfg.mode != TracerModeOTLPGRPC {
+		t.Errorf("got mode %d, want %d", cfg.mode, TracerModeOTLPGRPC)
+	}
+
+	if cfg.endpoint != "collector:4317" || !cfg.tls.Enabled || cfg.urlPath != "/base/v1/traces" {
+		t.Errorf("got endpoint %q, tls %t, url path %q, want collector:4317, true, /base/v1/traces", cfg.endpoint, cfg.tls.Enabled, cfg.urlPath)
+	}
+
+	if cfg.samplingFraction != 0.2 || cfg.maxTracesPerSecond != -1 {
+		t.Errorf("got sampling %v/%v, want 0.2/-1", cfg.samplingFraction, cfg.maxTracesPerSecond)
+	}
+
+	if cfg.remoteSamplingURL != "http://agent:5778/sampling" || cfg.remoteInterval != 5*time.Second {
+		t.Errorf("got remote sampling %q every %v, want http://agent:5778/sampling every 5s", cfg.remoteSamplingURL, cfg.remoteInterval)
+	}
+
+	wantAttrs := map[attribute.Key]string{
+		semconv.ServiceNameKey:    "checkout",
+		semconv.ServiceVersionKey: "1.2.3",
+		"team":                    "payments",
+	}
+	for key, want := range wantAttrs {
+		got, ok := cfg.resource.Set().Value(key)
+		if !ok || got.AsString() != want {
+			t.Errorf("got resource %s %q, want %q", key, got.AsString(), want)
+		}
+	}
+
+	fields := cfg.propagator.Fields()
+	if !slices.Contains(fields, "x-b3-traceid") || slices.Contains(fields, "traceparent") {
+		t.Errorf("got propagator fields %v, want b3 multi-header fields only", fields)
+	}
+}
+
+func TestEnvOptionsReportsMalformedValues(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "ttrace value",
+			env:  map[string]string{TracerSamplingFraction: "abc"},
+			want: TracerSamplingFraction,
+		},
+		{
+			name: "sampler argument",
+			env:  map[string]string{otelTracesSampler: "traceidratio", otelTracesSamplerArg: "abc"},
+			want: TracerSamplingFraction,
+		},
+		{
+			name: "endpoint",
+			env:  map[string]string{otelExporterEndpoint: "https://"},
+			want: otelExporterEndpoint,
+		},
+		{
+			name: "resource attributes",
+			env:  map[string]string{otelResourceAttributes: "service.name"},
+			want: otelResourceAttributes,
+		},
+		{
+			name: "propagators",
+			env:  map[string]string{otelPropagators: "unknown"},
+			want: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestEnv(t, tt.env)
+
+			cfg := newConfig(envOptions()...)
+			if cfg.err == nil || !strings.Contains(cfg.err.Error(), tt.want) {
+				t.Fatalf("got error %v, want one mentioning %s", cfg.err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleSettings(t *testing.T) {
+	setTestEnv(t, map[string]string{
+		otelSDKDisabled: "true",
+	})
+
+	handle, err := New(context.Background(), envOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := findSetting(handle.Settings(), TracerMode)
+	want := Setting{Key: TracerMode, Variable: otelSDKDisabled, Value: strconv.Itoa(TracerModeDisable), Source: SourceOTel}
+	if !ok || got != want {
+		t.Errorf("got setting %+v, want %+v", got, want)
+	}
+
+	handle, err = New(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := handle.Settings()
+	if settings != nil {
+		t.Errorf("got settings %v for explicit options, want nil", settings)
+	}
+}
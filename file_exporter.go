@@ -0,0 +1,522 @@
+package ttrace
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Line formats written by [FileExporter].
+const (
+	// FileFormatOTLP writes each span as an OTLP/JSON TracesData object, the format read by the
+	// OpenTelemetry Collector otlpjsonfile receiver.
+	FileFormatOTLP = "otlp"
+	// FileFormatStdout writes each span in the JSON format of the stdouttrace exporter.
+	FileFormatStdout = "stdout"
+)
+
+// Defaults applied by [NewFileExporter] to zero-valued [FileExporterOptions] fields.
+const (
+	defaultFileMaxSize = 100 << 20
+
+	// fileBackupTimeFormat is the timestamp inserted into rotated file names. It sorts
+	// lexicographically in time order.
+	fileBackupTimeFormat = "20060102T150405.000"
+
+	// fileRotateRetryInterval is how long a file whose rotation failed keeps growing before the
+	// rotation is attempted again.
+	fileRotateRetryInterval = time.Minute
+)
+
+// FileExporterOptions configures a [FileExporter].
+type FileExporterOptions struct {
+	// Path is the file spans are appended to. Its directory is created when missing.
+	Path string
+	// Format is [FileFormatOTLP] (the default) or [FileFormatStdout].
+	Format string
+	// MaxSize is the size in bytes at which the file is rotated. Zero means 100 MiB.
+	MaxSize int64
+	// MaxAge rotates the file once it has been open this long. Zero disables age-based rotation.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files retained; older ones are removed. Zero keeps all
+	// rotated files.
+	MaxBackups int
+	// Compress gzips rotated files in the background.
+	Compress bool
+}
+
+// FileExporter is a [sdktrace.SpanExporter] that appends one JSON span per line to a local file,
+// for shipping with a log agent where no collector is reachable. The file is rotated by size and
+// age: the current file is renamed to name-<UTC timestamp>.ext, with a -<n> suffix when a rotated
+// file with that timestamp exists, optionally gzipped, and rotated files beyond the retention count
+// are removed. When the rename fails, writes continue to the current file and rotation is retried
+// a minute later.
+type FileExporter struct {
+	file   *rotatingFile
+	stdout *stdouttrace.Exporter
+
+	stopped atomic.Bool
+}
+
+// NewFileExporter opens options.Path for appending and returns a [FileExporter] writing to it. It
+// returns an error when the path is empty, a limit is negative, the format is unknown, or the file
+// cannot be opened.
+func NewFileExporter(options FileExporterOptions) (*FileExporter, error) {
+	if strings.TrimSpace(options.Path) == "" {
+		return nil, fmt.Errorf("ttrace: missing %s for file exporter", TracerFilePath)
+	}
+
+	if options.MaxSize < 0 || options.MaxAge < 0 || options.MaxBackups < 0 {
+		return nil, fmt.Errorf("ttrace: invalid file exporter configuration: values must be >= 0")
+	}
+
+	switch options.Format {
+	case "":
+		options.Format = FileFormatOTLP
+	case FileFormatOTLP, FileFormatStdout:
+	default:
+		return nil, fmt.Errorf("ttrace: invalid %s %q: must be %q or %q", TracerFileFormat, options.Format, FileFormatOTLP, FileFormatStdout)
+	}
+
+	if options.MaxSize == 0 {
+		options.MaxSize = defaultFileMaxSize
+	}
+
+	file, err := newRotatingFile(options, time.Now)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter := &FileExporter{
+		file: file,
+	}
+
+	if options.Format == FileFormatStdout {
+		// Without pretty printing, stdouttrace writes each span with a single Write call, so a line
+		// is never split across files.
+		exporter.stdout, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			return nil, errors.Join(err, file.Close())
+		}
+	}
+
+	return exporter, nil
+}
+
+// ExportSpans appends spans to the file, one per line.
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.stopped.Load() {
+		return nil
+	}
+
+	if e.stdout != nil {
+		return e.stdout.ExportSpans(ctx, spans)
+	}
+
+	for _, span := range spans {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		line, err := marshalOTLPJSON(span)
+		if err != nil {
+			return fmt.Errorf("ttrace: encode span %s: %w", span.Name(), err)
+		}
+
+		_, err = e.file.Write(append(line, '\n'))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shutdown closes the file and waits for pending compression and retention work.
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	if e.stopped.Swap(true) {
+		return nil
+	}
+
+	var err error
+
+	if e.stdout != nil {
+		err = e.stdout.Shutdown(ctx)
+	}
+
+	return errors.Join(err, e.file.Close())
+}
+
+// rotatingFile is an [io.Writer] appending to a file that it rotates by size and age. Compression
+// and retention of rotated files run on a background goroutine.
+type rotatingFile struct {
+	options FileExporterOptions
+
+	lock     sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+	// rotateAfter postpones rotation after a failed rename.
+	rotateAfter time.Time
+
+	mill     chan struct{}
+	millDone chan struct{}
+
+	timeNow func() time.Time
+	rename  func(oldPath, newPath string) error
+}
+
+// newRotatingFile opens options.Path and starts the background goroutine, which also processes
+// files rotated before a restart.
+func newRotatingFile(options FileExporterOptions, timeNow func() time.Time) (*rotatingFile, error) {
+	r := &rotatingFile{
+		options:  options,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+		timeNow:  timeNow,
+		rename:   os.Rename,
+	}
+
+	err := os.MkdirAll(filepath.Dir(options.Path), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: create directory for %s: %w", options.Path, err)
+	}
+
+	err = r.open()
+	if err != nil {
+		return nil, err
+	}
+
+	go r.runMill()
+
+	r.mill <- struct{}{}
+
+	return r, nil
+}
+
+// Write appends p, rotating the file first when p would exceed the size limit or the file is
+// older than the age limit. r.file is never rotated while empty, nor while a failed rotation is
+// being backed off.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return 0, fmt.Errorf("ttrace: write to closed file %s", r.options.Path)
+	}
+
+	// A failed rotation leaves no file open; retry opening it.
+	if r.file == nil {
+		err := r.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	now := r.timeNow()
+
+	due := r.size+int64(len(p)) > r.options.MaxSize || r.options.MaxAge > 0 && now.Sub(r.openedAt) >= r.options.MaxAge
+
+	if r.size > 0 && due && !now.Before(r.rotateAfter) {
+		err := r.rotate(now)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	if err != nil {
+		return n, fmt.Errorf("ttrace: write %s: %w", r.options.Path, err)
+	}
+
+	return n, nil
+}
+
+// Close closes the file and waits for the background goroutine to finish.
+func (r *rotatingFile) Close() error {
+	r.lock.Lock()
+
+	if r.closed {
+		r.lock.Unlock()
+
+		return nil
+	}
+
+	r.closed = true
+
+	var err error
+
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+
+	r.lock.Unlock()
+
+	close(r.mill)
+	<-r.millDone
+
+	return err
+}
+
+// open opens r.options.Path for appending. r.lock must be held when r is in use.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.options.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("ttrace: open %s: %w", r.options.Path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Join(fmt.Errorf("ttrace: stat %s: %w", r.options.Path, err), file.Close())
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = r.timeNow()
+
+	return nil
+}
+
+// rotate renames the current file to a timestamped backup, opens a new file, and wakes the
+// background goroutine. When the rename fails, the current file is reopened and rotation is
+// postponed by [fileRotateRetryInterval]. r.lock must be held.
+func (r *rotatingFile) rotate(now time.Time) error {
+	err := r.file.Close()
+	if err != nil {
+		return fmt.Errorf("ttrace: close %s: %w", r.options.Path, err)
+	}
+
+	r.file = nil
+
+	err = r.rename(r.options.Path, r.backupName(now))
+	if err != nil {
+		log.Printf("ttrace: rotate %s failed: %v; retrying in %v", r.options.Path, err, fileRotateRetryInterval)
+
+		r.rotateAfter = now.Add(fileRotateRetryInterval)
+
+		// The reopened file keeps its age so that age-based rotation is retried as well.
+		openedAt := r.openedAt
+
+		err = r.open()
+		if err != nil {
+			return err
+		}
+
+		r.openedAt = openedAt
+
+		return nil
+	}
+
+	err = r.open()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case r.mill <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// backupName returns an unused rotated file name for now, such as spans-20260102T150405.000.jsonl.
+// When a rotated file, compressed or not, already has that timestamp, a sequence suffix is added,
+// as in spans-20260102T150405.000-1.jsonl.
+func (r *rotatingFile) backupName(now time.Time) string {
+	prefix, ext := r.backupPattern()
+	stamp := now.UTC().Format(fileBackupTimeFormat)
+
+	name := prefix + stamp + ext
+
+	for seq := 1; fileExists(name) || fileExists(name+".gz"); seq++ {
+		name = prefix + stamp + "-" + strconv.Itoa(seq) + ext
+	}
+
+	return name
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+
+	return err == nil
+}
+
+// backupPattern returns the path prefix and extension shared by rotated files.
+func (r *rotatingFile) backupPattern() (string, string) {
+	ext := filepath.Ext(r.options.Path)
+
+	return strings.TrimSuffix(r.options.Path, ext) + "-", ext
+}
+
+// runMill compresses and prunes rotated files each time it is woken, until r.mill is closed.
+func (r *rotatingFile) runMill() {
+	defer close(r.millDone)
+
+	for range r.mill {
+		err := r.millOnce()
+		if err != nil {
+			log.Printf("ttrace: process rotated files of %s failed: %v", r.options.Path, err)
+		}
+	}
+}
+
+// millOnce gzips uncompressed rotated files when compression is enabled, then removes the oldest
+// rotated files beyond the retention count.
+func (r *rotatingFile) millOnce() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	if r.options.Compress {
+		for i, backup := range backups {
+			if strings.HasSuffix(backup, ".gz") {
+				continue
+			}
+
+			err = compressFile(backup)
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			backups[i] = backup + ".gz"
+		}
+	}
+
+	if r.options.MaxBackups > 0 && len(backups) > r.options.MaxBackups {
+		for _, backup := range backups[:len(backups)-r.options.MaxBackups] {
+			err = os.Remove(backup)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files of r, oldest first.
+func (r *rotatingFile) backups() ([]string, error) {
+	prefix, ext := r.backupPattern()
+
+	entries, err := os.ReadDir(filepath.Dir(r.options.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	type backup struct {
+		path    string
+		rotated time.Time
+		seq     int
+	}
+
+	var found []backup
+
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(r.options.Path), entry.Name())
+		if entry.IsDir() || !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		stamp, ok := strings.CutSuffix(strings.TrimSuffix(strings.TrimPrefix(path, prefix), ".gz"), ext)
+		if !ok {
+			continue
+		}
+
+		seq := 0
+
+		stamp, suffix, ok := strings.Cut(stamp, "-")
+		if ok {
+			seq, err = strconv.Atoi(suffix)
+			if err != nil || seq < 1 {
+				continue
+			}
+		}
+
+		rotated, err := time.Parse(fileBackupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+
+		found = append(found, backup{path: path, rotated: rotated, seq: seq})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].rotated.Equal(found[j].rotated) {
+			return found[i].rotated.Before(found[j].rotated)
+		}
+
+		return found[i].seq < found[j].seq
+	})
+
+	backups := make([]string, 0, len(found))
+	for _, backup := range found {
+		backups = append(backups, backup.path)
+	}
+
+	return backups, nil
+}
+
+// compressFile writes path to path.gz and removes path. A partial path.gz is removed on failure.
+func compressFile(path string) (err error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	writer := gzip.NewWriter(target)
+
+	_, err = io.Copy(writer, source)
+	if err != nil {
+		return errors.Join(err, writer.Close(), target.Close())
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return errors.Join(err, target.Close())
+	}
+
+	err = target.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
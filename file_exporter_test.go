@@ -0,0 +1,160 @@
+package ttrace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRotatingFile returns a rotatingFile at dir/spans.jsonl using clock. It is closed when the
+// test ends.
+func newTestRotatingFile(t *testing.T, dir string, options FileExporterOptions, clock *fakeClock) *rotatingFile {
+	t.Helper()
+
+	options.Path = filepath.Join(dir, "spans.jsonl")
+
+	file, err := newRotatingFile(options, clock.Now)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { _ = file.Close() })
+
+	return file
+}
+
+// writeLine writes line to file and fails the test on error.
+func writeLine(t *testing.T, file *rotatingFile, line string) {
+	t.Helper()
+
+	_, err := file.Write([]byte(line + "\n"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+// backupNames returns the base names of the rotated files of file, oldest first.
+func backupNames(t *testing.T, file *rotatingFile) []string {
+	t.Helper()
+
+	backups, err := file.backups()
+	if err != nil {
+		t.Fatalf("backups: %v", err)
+	}
+
+	names := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		names = append(names, filepath.Base(backup))
+	}
+
+	return names
+}
+
+func TestRotatingFileKeepsRotationsInTheSameMillisecond(t *testing.T) {
+	clock := newFakeClock()
+	file := newTestRotatingFile(t, t.TempDir(), FileExporterOptions{MaxSize: 8}, clock)
+
+	for _, line := range []string{"first", "second", "third", "fourth"} {
+		writeLine(t, file, line)
+	}
+
+	want := []string{
+		"spans-20260101T000000.000.jsonl",
+		"spans-20260101T000000.000-1.jsonl",
+		"spans-20260101T000000.000-2.jsonl",
+	}
+
+	got := backupNames(t, file)
+	if !slices.Equal(got, want) {
+		t.Fatalf("backups = %q, want %q", got, want)
+	}
+
+	for i, line := range []string{"first", "second", "third"} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(file.options.Path), want[i]))
+		if err != nil {
+			t.Fatalf("read backup: %v", err)
+		}
+
+		if string(data) != line+"\n" {
+			t.Errorf("backup %s = %q, want %q", want[i], data, line+"\n")
+		}
+	}
+}
+
+func TestRotatingFileBacksOffAfterRenameFailure(t *testing.T) {
+	clock := newFakeClock()
+	file := newTestRotatingFile(t, t.TempDir(), FileExporterOptions{MaxSize: 8}, clock)
+
+	renames := 0
+	file.rename = func(string, string) error {
+		renames++
+
+		return errors.New("device busy")
+	}
+
+	for range 10 {
+		writeLine(t, file, "span")
+	}
+
+	if renames != 1 {
+		t.Errorf("attempted %d renames within the retry interval, want 1", renames)
+	}
+
+	data, err := os.ReadFile(file.options.Path)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+
+	if strings.Count(string(data), "span\n") != 10 {
+		t.Errorf("file = %q, want all 10 lines kept", data)
+	}
+
+	file.rename = os.Rename
+	clock.Advance(fileRotateRetryInterval)
+
+	writeLine(t, file, "after")
+
+	got := backupNames(t, file)
+	if len(got) != 1 {
+		t.Fatalf("backups = %q after the retry interval, want 1", got)
+	}
+
+	data, err = os.ReadFile(file.options.Path)
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+
+	if string(data) != "after\n" {
+		t.Errorf("file after rotation = %q, want %q", data, "after\n")
+	}
+}
+
+func TestRotatingFileRetainsNewestBackups(t *testing.T) {
+	clock := newFakeClock()
+	file := newTestRotatingFile(t, t.TempDir(), FileExporterOptions{MaxSize: 8, MaxBackups: 2}, clock)
+
+	for _, line := range []string{"first", "second", "third"} {
+		writeLine(t, file, line)
+	}
+
+	clock.Advance(time.Second)
+	writeLine(t, file, "fourth")
+
+	err := file.millOnce()
+	if err != nil {
+		t.Fatalf("millOnce: %v", err)
+	}
+
+	want := []string{
+		"spans-20260101T000000.000-1.jsonl",
+		"spans-20260101T000001.000.jsonl",
+	}
+
+	got := backupNames(t, file)
+	if !slices.Equal(got, want) {
+		t.Errorf("backups = %q, want %q", got, want)
+	}
+}
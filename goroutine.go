@@ -0,0 +1,182 @@
+package ttrace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// detachedContextKey stores the span context active when [Detach] was called.
+type detachedContextKey struct{}
+
+// Detach returns a context that keeps the values of ctx, including its span context and baggage,
+// but is never canceled and has no deadline, for fire-and-forget work that must outlive the
+// request. Task spans that [Go] and [Group.Go] start directly from the returned context are new
+// roots linked to the originating span, instead of children that would end after their parent;
+// their own descendants are ordinary children. Spans started from it with [Start] or another tracer
+// remain children of the originating span.
+func Detach(ctx context.Context) context.Context {
+	ctx = context.WithoutCancel(ctx)
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ctx
+	}
+
+	return context.WithValue(ctx, detachedContextKey{}, spanContext)
+}
+
+// detachedStartOptions returns the options making a task span started from ctx a new root linked
+// to the originating span, when ctx was returned by [Detach] and no span was started from it since.
+func detachedStartOptions(ctx context.Context) []trace.SpanStartOption {
+	origin, ok := ctx.Value(detachedContextKey{}).(trace.SpanContext)
+	if !ok || !origin.Equal(trace.SpanContextFromContext(ctx)) {
+		return nil
+	}
+
+	return []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.Link{SpanContext: origin}),
+	}
+}
+
+// Go runs fn on a new goroutine with a child span named name of the span in ctx. The span is
+// started before Go returns, so it is parented and timed correctly even when the caller ends its
+// own span right away, and it ends when fn returns. A panic in fn is recorded on the span and
+// re-raised. Combine Go with [Detach] for work that must survive the cancellation of ctx; the span
+// is then a new root linked to the originating span.
+func Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	ctx, span := startTask(ctx, name)
+
+	go func() {
+		defer endTaskSpan(span, nil)
+
+		fn(ctx)
+	}()
+}
+
+// startTask starts the span of a [Go] or [Group.Go] task, which ctx carries to the task. When ctx
+// was returned by [Detach], the span is a new root linked to the originating span.
+func startTask(ctx context.Context, name string) (context.Context, trace.Span) {
+	return Start(ctx, name, detachedStartOptions(ctx)...)
+}
+
+// Group runs tasks on goroutines like errgroup.Group, each in a child span of the span in the
+// context passed to [NewGroup]. The first task error cancels the group context and is recorded on
+// the parent span by [Group.Wait], which callers invoke before ending the parent.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	parent trace.Span
+
+	wg  sync.WaitGroup
+	sem chan struct{}
+
+	errOnce  sync.Once
+	err      error
+	waitOnce sync.Once
+}
+
+// NewGroup returns a [Group] whose tasks start child spans of the span in ctx, together with a
+// derived context that is canceled when a task fails or [Group.Wait] returns. When ctx was returned
+// by [Detach], the task spans are new roots linked to the originating span.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	return &Group{
+		ctx:    ctx,
+		cancel: cancel,
+		parent: trace.SpanFromContext(ctx),
+	}, ctx
+}
+
+// SetLimit limits the number of concurrently running tasks to n; a negative n removes the limit.
+// It must not be called while tasks are running.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+
+		return
+	}
+
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs fn on a new goroutine in a child span named name, blocking while the limit set with
+// [Group.SetLimit] is reached. An error returned by fn is recorded on the task span, and the first
+// one cancels the group context.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	ctx, span := startTask(g.ctx, name)
+
+	g.wg.Add(1)
+
+	go func() {
+		var err error
+
+		defer func() {
+			if g.sem != nil {
+				<-g.sem
+			}
+
+			g.wg.Done()
+		}()
+
+		defer endTaskSpan(span, &err)
+
+		err = fn(ctx)
+		if err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait waits for every task, cancels the group context, and returns the first task error. The
+// first call records that error on the parent span and sets its status to Error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(context.Canceled)
+
+	g.waitOnce.Do(func() {
+		if g.err != nil {
+			g.parent.RecordError(g.err)
+			g.parent.SetStatus(codes.Error, g.err.Error())
+		}
+	})
+
+	return g.err
+}
+
+// endTaskSpan records the error in *errp, or a panic in progress, on span and ends it. A panic is
+// re-raised after the span ends. It must be deferred directly so that recover sees the panic.
+func endTaskSpan(span trace.Span, errp *error) {
+	var err error
+	if errp != nil {
+		err = *errp
+	}
+
+	recovered := recover()
+	if recovered != nil {
+		err = fmt.Errorf("ttrace: panic: %v", recovered)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+
+	if recovered != nil {
+		panic(recovered)
+	}
+}
@@ -0,0 +1,303 @@
+package ttrace
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanNamed returns the ended span named name recorded by exporter.
+func spanNamed(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	t.Fatalf("got spans %v, want %s", spanNames(exporter), name)
+
+	return tracetest.SpanStub{}
+}
+
+func TestGoStartsChildSpan(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+
+	done := make(chan struct{})
+	release := make(chan struct{})
+
+	Go(ctx, "task", func(ctx context.Context) {
+		defer close(done)
+
+		<-release
+	})
+
+	// The parent may end before the task runs; the task span is already started.
+	parent.End()
+	close(release)
+	<-done
+
+	// The task span ends after fn returns, on the task goroutine.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(exporter.GetSpans()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	task := spanNamed(t, exporter, "task")
+	if task.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("got task parent %s, want %s", task.Parent.SpanID(), parent.SpanContext().SpanID())
+	}
+
+	if !task.StartTime.Before(spanNamed(t, exporter, "parent").EndTime) {
+		t.Error("got a task span started after its parent ended, want it started by Go")
+	}
+}
+
+func TestGoDetachedSurvivesCancellation(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, err := ContextWithBaggage(ctx, "tenant=acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := Start(ctx, "request")
+
+	done := make(chan error, 1)
+	release := make(chan struct{})
+
+	Go(Detach(ctx), "background", func(ctx context.Context) {
+		<-release
+
+		done <- ctx.Err()
+	})
+
+	parent.End()
+	cancel()
+	close(release)
+
+	err = <-done
+	if err != nil {
+		t.Errorf("got context error %v after the parent was canceled, want nil", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(exporter.GetSpans()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	background := spanNamed(t, exporter, "background")
+	if background.Parent.IsValid() || background.SpanContext.TraceID() == parent.SpanContext().TraceID() {
+		t.Errorf("got background parent %v in trace %s, want a new root", background.Parent, background.SpanContext.TraceID())
+	}
+
+	if len(background.Links) != 1 || !background.Links[0].SpanContext.Equal(parent.SpanContext()) {
+		t.Errorf("got links %v, want the request span", background.Links)
+	}
+}
+
+func TestStartFromDetachedContextIsChild(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	ctx, parent := Start(context.Background(), "request")
+	detached := Detach(ctx)
+
+	ctx, span := Start(detached, "child")
+	span.End()
+	parent.End()
+
+	child := spanNamed(t, exporter, "child")
+	if child.Parent.SpanID() != parent.SpanContext().SpanID() || len(child.Links) != 0 {
+		t.Errorf("got parent %s and links %v, want an unlinked child of %s", child.Parent.SpanID(), child.Links, parent.SpanContext().SpanID())
+	}
+
+	if ctx.Done() != nil {
+		t.Error("got a cancelable context from Detach, want none")
+	}
+}
+
+func TestDetachWithoutSpan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	detached := Detach(ctx)
+	if detached.Err() != nil {
+		t.Errorf("got context error %v, want nil", detached.Err())
+	}
+
+	if detachedStartOptions(detached) != nil {
+		t.Error("got detached start options without an originating span, want none")
+	}
+}
+
+func TestGroupRecordsFirstError(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+
+	g, gctx := NewGroup(ctx)
+
+	errFirst := errors.New("first")
+	failed := make(chan struct{})
+
+	g.Go("fail", func(ctx context.Context) error {
+		defer close(failed)
+
+		return errFirst
+	})
+
+	g.Go("canceled", func(ctx context.Context) error {
+		<-failed
+		<-ctx.Done()
+
+		return errors.New("second")
+	})
+
+	g.Go("ok", func(ctx context.Context) error {
+		return nil
+	})
+
+	err := g.Wait()
+	if !errors.Is(err, errFirst) {
+		t.Errorf("got error %v, want %v", err, errFirst)
+	}
+
+	if !errors.Is(context.Cause(gctx), errFirst) {
+		t.Errorf("got cause %v, want %v", context.Cause(gctx), errFirst)
+	}
+
+	parent.End()
+
+	got := spanNamed(t, exporter, "parent")
+	if got.Status.Code != codes.Error || got.Status.Description != "first" || len(got.Events) != 1 {
+		t.Errorf("got parent status %+v with %d events, want the first error recorded once", got.Status, len(got.Events))
+	}
+
+	for name, want := range map[string]codes.Code{"fail": codes.Error, "canceled": codes.Error, "ok": codes.Unset} {
+		task := spanNamed(t, exporter, name)
+		if task.Status.Code != want || task.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("got task %s status %v with parent %s, want %v under the parent", name, task.Status.Code, task.Parent.SpanID(), want)
+		}
+	}
+
+	err = g.Wait()
+	if !errors.Is(err, errFirst) || len(spanNamed(t, exporter, "parent").Events) != 1 {
+		t.Errorf("got error %v from a second Wait, want the first error without another record", err)
+	}
+}
+
+func TestGroupSetLimit(t *testing.T) {
+	installTestTracing(t)
+
+	g, _ := NewGroup(context.Background())
+	g.SetLimit(2)
+
+	var (
+		running    atomic.Int64
+		maxRunning atomic.Int64
+		completed  atomic.Int64
+	)
+
+	for range 8 {
+		g.Go("task", func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				current := maxRunning.Load()
+				if n <= current || maxRunning.CompareAndSwap(current, n) {
+					break
+				}
+			}
+
+			time.Sleep(2 * time.Millisecond)
+			completed.Add(1)
+
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if completed.Load() != 8 {
+		t.Errorf("got %d completed tasks, want 8", completed.Load())
+	}
+
+	if maxRunning.Load() > 2 {
+		t.Errorf("got %d concurrent tasks, want at most 2", maxRunning.Load())
+	}
+}
+
+func TestDetachedGroupLinksTasks(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	ctx, parent := Start(context.Background(), "request")
+	parent.End()
+
+	g, _ := NewGroup(Detach(ctx))
+	g.Go("task", func(ctx context.Context) error { return nil })
+
+	err := g.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := spanNamed(t, exporter, "task")
+	if task.Parent.IsValid() || len(task.Links) != 1 || !task.Links[0].SpanContext.Equal(parent.SpanContext()) {
+		t.Errorf("got parent %v and links %v, want a new root linked to the request span", task.Parent, task.Links)
+	}
+}
+
+func TestEndTaskSpanRecordsPanic(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	_, span := Start(context.Background(), "task")
+
+	func() {
+		defer func() {
+			recovered := recover()
+			if recovered != "boom" {
+				t.Errorf("got panic %v, want boom re-raised", recovered)
+			}
+		}()
+
+		defer endTaskSpan(span, nil)
+
+		panic("boom")
+	}()
+
+	task := spanNamed(t, exporter, "task")
+	if task.Status.Code != codes.Error || task.Status.Description != "ttrace: panic: boom" {
+		t.Errorf("got status %+v, want the panic recorded", task.Status)
+	}
+
+	if len(task.Events) != 1 || task.Events[0].Name != "exception" {
+		t.Errorf("got events %v, want one exception", task.Events)
+	}
+}
+
+func TestEndTaskSpanRecordsError(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	_, span := Start(context.Background(), "task")
+
+	err := errors.New("failed")
+	endTaskSpan(span, &err)
+
+	task := spanNamed(t, exporter, "task")
+	if task.Status.Code != codes.Error || task.Status.Description != "failed" {
+		t.Errorf("got status %+v, want the error recorded", task.Status)
+	}
+}
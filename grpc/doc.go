@@ -0,0 +1,13 @@
+// Package grpc provides OpenTelemetry instrumentation for gRPC servers and clients: stats handlers
+// that wrap otelgrpc from [go.opentelemetry.io/contrib], and unary and stream interceptors for
+// servers and clients that install instrumentation through interceptor chains.
+//
+// Spans are named after the full method, such as "helloworld.Greeter/SayHello", carry the
+// rpc.system.name, rpc.method, and rpc.response.status_code attributes, and have error status for
+// failed client calls and for server errors. Trace context is extracted from and injected into
+// gRPC metadata with the global TextMapPropagator, which [github.com/choveylee/ttrace.Init]
+// installs, unless [WithPropagator] supplies another one.
+//
+// Import this package only in applications that already depend on [google.golang.org/grpc]. The
+// core [github.com/choveylee/ttrace] module does not require otelgrpc.
+package grpc
@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// ScopeName is the instrumentation scope name of spans created by the interceptors.
+const (
+	ScopeName = "github.com/choveylee/ttrace/grpc"
+)
+
+// config collects the settings applied by [Option] values.
+type config struct {
+	propagator     propagation.TextMapPropagator
+	tracerProvider trace.TracerProvider
+}
+
+// newConfig returns the configuration with opts applied in order. Unset values resolve to the
+// global providers when they are used, so instrumentation created before
+// [github.com/choveylee/ttrace.Init] follows the providers installed later.
+func newConfig(opts []Option) *config {
+	cfg := &config{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	return cfg
+}
+
+// textMapPropagator returns the configured propagator or the global one.
+func (cfg *config) textMapPropagator() propagation.TextMapPropagator {
+	if cfg.propagator != nil {
+		return cfg.propagator
+	}
+
+	return otel.GetTextMapPropagator()
+}
+
+// tracer returns a tracer from the configured provider or the global one.
+func (cfg *config) tracer() trace.Tracer {
+	tracerProvider := cfg.tracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	return tracerProvider.Tracer(ScopeName)
+}
+
+// otelgrpcOptions translates cfg into otelgrpc options.
+func (cfg *config) otelgrpcOptions() []otelgrpc.Option {
+	var opts []otelgrpc.Option
+
+	if cfg.propagator != nil {
+		opts = append(opts, otelgrpc.WithPropagators(cfg.propagator))
+	}
+
+	if cfg.tracerProvider != nil {
+		opts = append(opts, otelgrpc.WithTracerProvider(cfg.tracerProvider))
+	}
+
+	return opts
+}
+
+// Option configures the stats handlers and interceptors of this package.
+type Option func(cfg *config)
+
+// WithPropagator extracts and injects trace context with propagator, such as the one returned by
+// [github.com/choveylee/ttrace.Handle.Propagator], instead of the global TextMapPropagator.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(cfg *config) {
+		cfg.propagator = propagator
+	}
+}
+
+// WithTracerProvider creates spans with tracerProvider instead of the global TracerProvider.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(cfg *config) {
+		cfg.tracerProvider = tracerProvider
+	}
+}
+
+// NewServerHandler returns a server [stats.Handler] built by otelgrpc. It records a server span
+// for every RPC, including streams, and the otelgrpc RPC metrics.
+func NewServerHandler(opts ...Option) stats.Handler {
+	return otelgrpc.NewServerHandler(newConfig(opts).otelgrpcOptions()...)
+}
+
+// NewClientHandler returns a client [stats.Handler] built by otelgrpc. It records a client span
+// for every RPC, including streams, and the otelgrpc RPC metrics.
+func NewClientHandler(opts ...Option) stats.Handler {
+	return otelgrpc.NewClientHandler(newConfig(opts).otelgrpcOptions()...)
+}
+
+// ServerOption returns a [grpc.ServerOption] installing [NewServerHandler].
+func ServerOption(opts ...Option) grpc.ServerOption {
+	return grpc.StatsHandler(NewServerHandler(opts...))
+}
+
+// DialOption returns a [grpc.DialOption] installing [NewClientHandler].
+func DialOption(opts ...Option) grpc.DialOption {
+	return grpc.WithStatsHandler(NewClientHandler(opts...))
+}
@@ -0,0 +1,337 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a [grpc.UnaryServerInterceptor] that extracts the trace context
+// from the incoming metadata and records a server span for each call. Use it instead of
+// [NewServerHandler], not in addition to it.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	cfg := newConfig(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startServerSpan(ctx, cfg, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+		endSpan(span, err, serverStatus)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a [grpc.StreamServerInterceptor] that extracts the trace context
+// from the incoming metadata and records a server span covering each stream. Use it instead of
+// [NewServerHandler], not in addition to it.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	cfg := newConfig(opts)
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startServerSpan(stream.Context(), cfg, info.FullMethod)
+
+		err := handler(srv, serverStream{ServerStream: stream, ctx: ctx})
+		endSpan(span, err, serverStatus)
+
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a [grpc.UnaryClientInterceptor] that records a client span for each
+// call and injects its context into the outgoing metadata. Use it instead of [NewClientHandler],
+// not in addition to it.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	cfg := newConfig(opts)
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		ctx, span := startClientSpan(ctx, cfg, method)
+
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		endSpan(span, err, clientStatus)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a [grpc.StreamClientInterceptor] that records a client span for
+// each stream and injects its context into the outgoing metadata. The span ends when the stream
+// reports its final status: a receive error, including io.EOF, the response of a stream without
+// server streaming, a failed send or header, or a failed stream creation. It also ends when the call
+// context is canceled or its deadline passes, so a stream abandoned without reading its final status
+// is still recorded. Use it instead of [NewClientHandler], not in addition to it.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	cfg := newConfig(opts)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startClientSpan(ctx, cfg, method)
+
+		stream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			endSpan(span, err, clientStatus)
+
+			return nil, err
+		}
+
+		wrapped := &clientStream{
+			ClientStream:  stream,
+			span:          span,
+			serverStreams: desc.ServerStreams,
+			done:          make(chan struct{}),
+		}
+
+		go wrapped.watch(ctx)
+
+		return wrapped, nil
+	}
+}
+
+// startServerSpan extracts the remote span context from the incoming metadata of ctx and starts a
+// server span for fullMethod.
+func startServerSpan(ctx context.Context, cfg *config, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = cfg.textMapPropagator().Extract(ctx, metadataCarrier(md.Copy()))
+
+	name, attributes := parseFullMethod(fullMethod)
+
+	return cfg.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// startClientSpan starts a client span for method and injects its context into the outgoing
+// metadata of the returned context.
+func startClientSpan(ctx context.Context, cfg *config, method string) (context.Context, trace.Span) {
+	name, attributes := parseFullMethod(method)
+
+	ctx, span := cfg.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	cfg.textMapPropagator().Inject(ctx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// endSpan records the gRPC status of err on span, mapping it to a span status with recordStatus,
+// and ends span.
+func endSpan(span trace.Span, err error, recordStatus func(*status.Status) (codes.Code, string)) {
+	grpcStatus := status.New(grpccodes.OK, "")
+	if err != nil {
+		grpcStatus, _ = status.FromError(err)
+
+		spanCode, description := recordStatus(grpcStatus)
+		span.SetStatus(spanCode, description)
+	}
+
+	span.SetAttributes(semconv.RPCResponseStatusCode(canonicalCode(grpcStatus.Code())))
+	span.End()
+}
+
+// serverStatus marks server spans as failed only for codes that indicate a server-side error,
+// following the OpenTelemetry gRPC semantic conventions.
+func serverStatus(grpcStatus *status.Status) (codes.Code, string) {
+	switch grpcStatus.Code() {
+	case grpccodes.Unknown,
+		grpccodes.DeadlineExceeded,
+		grpccodes.Unimplemented,
+		grpccodes.Internal,
+		grpccodes.Unavailable,
+		grpccodes.DataLoss:
+		return codes.Error, grpcStatus.Message()
+	default:
+		return codes.Unset, ""
+	}
+}
+
+// clientStatus marks client spans as failed for every non-OK code.
+func clientStatus(grpcStatus *status.Status) (codes.Code, string) {
+	return codes.Error, grpcStatus.Message()
+}
+
+// parseFullMethod returns the span name, the full method without its leading slash, and the RPC
+// attributes of fullMethod.
+func parseFullMethod(fullMethod string) (string, []attribute.KeyValue) {
+	name := strings.TrimPrefix(fullMethod, "/")
+
+	return name, []attribute.KeyValue{
+		semconv.RPCSystemNameGRPC,
+		semconv.RPCMethod(name),
+	}
+}
+
+// canonicalCode returns the upper-case canonical name of code, such as "DEADLINE_EXCEEDED".
+func canonicalCode(code grpccodes.Code) string {
+	switch code {
+	case grpccodes.OK:
+		return "OK"
+	case grpccodes.Canceled:
+		return "CANCELLED"
+	case grpccodes.Unknown:
+		return "UNKNOWN"
+	case grpccodes.InvalidArgument:
+		return "INVALID_ARGUMENT"
+	case grpccodes.DeadlineExceeded:
+		return "DEADLINE_EXCEEDED"
+	case grpccodes.NotFound:
+		return "NOT_FOUND"
+	case grpccodes.AlreadyExists:
+		return "ALREADY_EXISTS"
+	case grpccodes.PermissionDenied:
+		return "PERMISSION_DENIED"
+	case grpccodes.ResourceExhausted:
+		return "RESOURCE_EXHAUSTED"
+	case grpccodes.FailedPrecondition:
+		return "FAILED_PRECONDITION"
+	case grpccodes.Aborted:
+		return "ABORTED"
+	case grpccodes.OutOfRange:
+		return "OUT_OF_RANGE"
+	case grpccodes.Unimplemented:
+		return "UNIMPLEMENTED"
+	case grpccodes.Internal:
+		return "INTERNAL"
+	case grpccodes.Unavailable:
+		return "UNAVAILABLE"
+	case grpccodes.DataLoss:
+		return "DATA_LOSS"
+	case grpccodes.Unauthenticated:
+		return "UNAUTHENTICATED"
+	default:
+		return code.String()
+	}
+}
+
+// metadataCarrier adapts [metadata.MD] to [propagation.TextMapCarrier].
+type metadataCarrier metadata.MD
+
+// Get returns the first value of key.
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set replaces the values of key.
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns the metadata keys.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// serverStream overrides the context of a [grpc.ServerStream] with one carrying the server span.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+// Context returns the context carrying the server span.
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream ends its client span when the stream reports its final status or its call context
+// is done.
+type clientStream struct {
+	grpc.ClientStream
+
+	span trace.Span
+
+	// serverStreams is false when the server sends a single response, which then finishes the
+	// stream.
+	serverStreams bool
+
+	once sync.Once
+	done chan struct{}
+}
+
+// watch ends the span with the context error when ctx, the call context, is done before the stream
+// reports its final status. gRPC also cancels the stream context when the stream finishes; the
+// wrapped call that observed the finish then reports the status, so watch returns without ending the
+// span while ctx is still live.
+func (s *clientStream) watch(ctx context.Context) {
+	select {
+	case <-s.done:
+	case <-s.Context().Done():
+		err := ctx.Err()
+		if err != nil {
+			s.finish(status.FromContextError(err).Err())
+		}
+	}
+}
+
+// RecvMsg receives a message and ends the span when the stream finishes. gRPC finishes a stream
+// without server streaming once its response is received, typically after CloseSend, so the
+// caller need not read io.EOF.
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.finish(err)
+	}
+
+	return err
+}
+
+// SendMsg sends a message and ends the span when the stream failed.
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.finish(err)
+	}
+
+	return err
+}
+
+// Header returns the header metadata and ends the span when the stream failed.
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+
+	return md, err
+}
+
+// finish ends the span once, treating io.EOF as a successful end of stream.
+func (s *clientStream) finish(err error) {
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+
+	s.once.Do(func() {
+		endSpan(s.span, err, clientStatus)
+		close(s.done)
+	})
+}
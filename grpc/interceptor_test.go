@@ -0,0 +1,610 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Full methods of echoService.
+const (
+	unaryMethod   = "/ttrace.test.Echo/Unary"
+	streamMethod  = "/ttrace.test.Echo/Stream"
+	collectMethod = "/ttrace.test.Echo/Collect"
+)
+
+// echoService describes a unary, a bidirectional streaming and a client streaming method exchanging
+// [wrapperspb.StringValue] messages. A message holding a gRPC code number other than 0 makes the
+// server fail with that code.
+var echoService = grpc.ServiceDesc{
+	ServiceName: "ttrace.test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Unary", Handler: echoUnaryHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Stream", Handler: echoStreamHandler, ServerStreams: true, ClientStreams: true},
+		{StreamName: "Collect", Handler: echoCollectHandler, ClientStreams: true},
+	},
+}
+
+// echoServer implements echoService and records what the handlers observed.
+type echoServer struct {
+	lock        sync.Mutex
+	metadata    metadata.MD
+	spanContext trace.SpanContext
+}
+
+// observe records the incoming metadata and span context of ctx.
+func (s *echoServer) observe(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.metadata = md
+	s.spanContext = trace.SpanContextFromContext(ctx)
+}
+
+// observed returns what the last handler recorded.
+func (s *echoServer) observed() (metadata.MD, trace.SpanContext) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.metadata, s.spanContext
+}
+
+// reply echoes req, or returns the error encoded in it.
+func (s *echoServer) reply(req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	code, err := strconv.Atoi(req.GetValue())
+	if err == nil && code != 0 {
+		return nil, status.Error(grpccodes.Code(code), "echo failed")
+	}
+
+	return req, nil
+}
+
+func echoUnaryHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := new(wrapperspb.StringValue)
+
+	err := dec(req)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		srv.(*echoServer).observe(ctx)
+
+		return srv.(*echoServer).reply(req.(*wrapperspb.StringValue))
+	}
+
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: unaryMethod}, handler)
+}
+
+func echoStreamHandler(srv any, stream grpc.ServerStream) error {
+	server := srv.(*echoServer)
+	server.observe(stream.Context())
+
+	for {
+		req := new(wrapperspb.StringValue)
+
+		err := stream.RecvMsg(req)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		resp, err := server.reply(req)
+		if err != nil {
+			return err
+		}
+
+		err = stream.SendMsg(resp)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// echoCollectHandler replies with the last message of the client stream.
+func echoCollectHandler(srv any, stream grpc.ServerStream) error {
+	server := srv.(*echoServer)
+	server.observe(stream.Context())
+
+	last := new(wrapperspb.StringValue)
+
+	for {
+		req := new(wrapperspb.StringValue)
+
+		err := stream.RecvMsg(req)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		last, err = server.reply(req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return stream.SendMsg(last)
+}
+
+// newTestTracerProvider returns a TracerProvider exporting ended spans synchronously to the
+// returned exporter.
+func newTestTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tracerProvider.Shutdown(context.Background()) })
+
+	return tracerProvider, exporter
+}
+
+// newTestConn serves echoService over an in-memory listener with the interceptors of this package
+// and returns a client connection using them. Both sides record spans to the returned exporter.
+func newTestConn(t *testing.T) (*grpc.ClientConn, *echoServer, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	tracerProvider, exporter := newTestTracerProvider(t)
+
+	opts := []Option{
+		WithTracerProvider(tracerProvider),
+		WithPropagator(propagation.TraceContext{}),
+	}
+
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(opts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(opts...)),
+	)
+
+	echo := &echoServer{}
+	server.RegisterService(&echoService, echo)
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(opts...)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(opts...)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn, echo, exporter
+}
+
+// spanOfKind returns the single ended span of kind, failing the test when there is not exactly one.
+func spanOfKind(t *testing.T, exporter *tracetest.InMemoryExporter, kind trace.SpanKind) tracetest.SpanStub {
+	t.Helper()
+
+	var found []tracetest.SpanStub
+
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind == kind {
+			found = append(found, span)
+		}
+	}
+
+	if len(found) != 1 {
+		t.Fatalf("got %d %s spans, want 1", len(found), kind)
+	}
+
+	return found[0]
+}
+
+// waitForSpanOfKind waits until a span of kind has ended and returns it.
+func waitForSpanOfKind(t *testing.T, exporter *tracetest.InMemoryExporter, kind trace.SpanKind) tracetest.SpanStub {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		for _, span := range exporter.GetSpans() {
+			if span.SpanKind == kind {
+				return span
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("no %s span ended", kind)
+
+	return tracetest.SpanStub{}
+}
+
+// responseStatusCode returns the rpc.response.status_code attribute of span.
+func responseStatusCode(span tracetest.SpanStub) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == semconv.RPCResponseStatusCodeKey {
+			return kv.Value.AsString()
+		}
+	}
+
+	return ""
+}
+
+// checkSpan compares the name, status, and response status code attribute of span.
+func checkSpan(t *testing.T, span tracetest.SpanStub, name string, code codes.Code, responseCode string) {
+	t.Helper()
+
+	if span.Name != name {
+		t.Errorf("%s span name = %q, want %q", span.SpanKind, span.Name, name)
+	}
+
+	if span.Status.Code != code {
+		t.Errorf("%s span status = %v, want %v", span.SpanKind, span.Status.Code, code)
+	}
+
+	got := responseStatusCode(span)
+	if got != responseCode {
+		t.Errorf("%s span rpc.response.status_code = %q, want %q", span.SpanKind, got, responseCode)
+	}
+}
+
+// checkPropagation verifies that the server span is a child of the client span and that the
+// handler saw the server span and the caller metadata.
+func checkPropagation(t *testing.T, echo *echoServer, client, server tracetest.SpanStub) {
+	t.Helper()
+
+	if server.Parent.SpanID() != client.SpanContext.SpanID() || !server.Parent.IsRemote() {
+		t.Errorf("server span parent = %v, want the remote client span %v", server.Parent, client.SpanContext)
+	}
+
+	md, spanContext := echo.observed()
+
+	if spanContext.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("handler span context = %v, want the server span %v", spanContext, server.SpanContext)
+	}
+
+	tenant := md.Get("x-tenant")
+	if len(tenant) != 1 || tenant[0] != "acme" {
+		t.Errorf("handler metadata x-tenant = %q, want the caller value kept", tenant)
+	}
+
+	if len(md.Get("traceparent")) != 1 {
+		t.Errorf("handler metadata = %v, want one traceparent", md)
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       grpccodes.Code
+		clientCode codes.Code
+		serverCode codes.Code
+	}{
+		{name: "ok", code: grpccodes.OK, clientCode: codes.Unset, serverCode: codes.Unset},
+		{name: "client error", code: grpccodes.NotFound, clientCode: codes.Error, serverCode: codes.Unset},
+		{name: "server error", code: grpccodes.Internal, clientCode: codes.Error, serverCode: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, echo, exporter := newTestConn(t)
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "acme")
+
+			err := conn.Invoke(ctx, unaryMethod, wrapperspb.String(strconv.Itoa(int(tt.code))), new(wrapperspb.StringValue))
+			if status.Code(err) != tt.code {
+				t.Fatalf("Invoke error = %v, want code %v", err, tt.code)
+			}
+
+			client := spanOfKind(t, exporter, trace.SpanKindClient)
+			server := spanOfKind(t, exporter, trace.SpanKindServer)
+
+			checkSpan(t, client, "ttrace.test.Echo/Unary", tt.clientCode, canonicalCode(tt.code))
+			checkSpan(t, server, "ttrace.test.Echo/Unary", tt.serverCode, canonicalCode(tt.code))
+			checkPropagation(t, echo, client, server)
+		})
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       grpccodes.Code
+		clientCode codes.Code
+		serverCode codes.Code
+	}{
+		{name: "ok", code: grpccodes.OK, clientCode: codes.Unset, serverCode: codes.Unset},
+		{name: "client error", code: grpccodes.InvalidArgument, clientCode: codes.Error, serverCode: codes.Unset},
+		{name: "server error", code: grpccodes.Unavailable, clientCode: codes.Error, serverCode: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, echo, exporter := newTestConn(t)
+
+			ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "acme"))
+			defer cancel()
+
+			stream, err := conn.NewStream(ctx, &echoService.Streams[0], streamMethod)
+			if err != nil {
+				t.Fatalf("NewStream: %v", err)
+			}
+
+			err = stream.SendMsg(wrapperspb.String("hello"))
+			if err != nil {
+				t.Fatalf("SendMsg: %v", err)
+			}
+
+			resp := new(wrapperspb.StringValue)
+
+			err = stream.RecvMsg(resp)
+			if err != nil || resp.GetValue() != "hello" {
+				t.Fatalf("RecvMsg = %q, %v; want the echo", resp.GetValue(), err)
+			}
+
+			// A failing message ends the stream; otherwise the client closes it.
+			if tt.code != grpccodes.OK {
+				err = stream.SendMsg(wrapperspb.String(strconv.Itoa(int(tt.code))))
+			} else {
+				err = stream.CloseSend()
+			}
+
+			if err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			err = stream.RecvMsg(resp)
+			if tt.code == grpccodes.OK && !errors.Is(err, io.EOF) || tt.code != grpccodes.OK && status.Code(err) != tt.code {
+				t.Fatalf("final RecvMsg error = %v, want code %v", err, tt.code)
+			}
+
+			client := spanOfKind(t, exporter, trace.SpanKindClient)
+			server := waitForSpanOfKind(t, exporter, trace.SpanKindServer)
+
+			checkSpan(t, client, "ttrace.test.Echo/Stream", tt.clientCode, canonicalCode(tt.code))
+			checkSpan(t, server, "ttrace.test.Echo/Stream", tt.serverCode, canonicalCode(tt.code))
+			checkPropagation(t, echo, client, server)
+		})
+	}
+}
+
+func TestStreamClientInterceptorEndsAbandonedStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		code    grpccodes.Code
+	}{
+		{name: "canceled", code: grpccodes.Canceled},
+		{name: "deadline exceeded", timeout: 50 * time.Millisecond, code: grpccodes.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _, exporter := newTestConn(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if tt.timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			stream, err := conn.NewStream(ctx, &echoService.Streams[0], streamMethod)
+			if err != nil {
+				t.Fatalf("NewStream: %v", err)
+			}
+
+			err = stream.SendMsg(wrapperspb.String("hello"))
+			if err != nil {
+				t.Fatalf("SendMsg: %v", err)
+			}
+
+			// The caller gives up without ever reading the final status.
+			if tt.timeout == 0 {
+				cancel()
+			}
+
+			span := waitForSpanOfKind(t, exporter, trace.SpanKindClient)
+			checkSpan(t, span, "ttrace.test.Echo/Stream", codes.Error, canonicalCode(tt.code))
+		})
+	}
+}
+
+func TestStreamClientInterceptorEndsStreamsOnResponse(t *testing.T) {
+	conn, _, exporter := newTestConn(t)
+
+	stream, err := conn.NewStream(context.Background(), &echoService.Streams[1], collectMethod)
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+
+	for _, value := range []string{"first", "last"} {
+		err = stream.SendMsg(wrapperspb.String(value))
+		if err != nil {
+			t.Fatalf("SendMsg: %v", err)
+		}
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+
+	resp := new(wrapperspb.StringValue)
+
+	err = stream.RecvMsg(resp)
+	if err != nil {
+		t.Fatalf("RecvMsg: %v", err)
+	}
+
+	if resp.GetValue() != "last" {
+		t.Errorf("got response %q, want last", resp.GetValue())
+	}
+
+	// The caller abandons the stream without reading io.EOF or canceling its context.
+	span := waitForSpanOfKind(t, exporter, trace.SpanKindClient)
+	checkSpan(t, span, "ttrace.test.Echo/Collect", codes.Unset, "OK")
+}
+
+// fakeClientStream is a [grpc.ClientStream] returning fixed errors.
+type fakeClientStream struct {
+	grpc.ClientStream
+
+	recvErr   error
+	sendErr   error
+	headerErr error
+}
+
+func (s fakeClientStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s fakeClientStream) RecvMsg(any) error {
+	return s.recvErr
+}
+
+func (s fakeClientStream) SendMsg(any) error {
+	return s.sendErr
+}
+
+func (s fakeClientStream) Header() (metadata.MD, error) {
+	return nil, s.headerErr
+}
+
+func TestClientStreamFinish(t *testing.T) {
+	tests := []struct {
+		name           string
+		stream         fakeClientStream
+		call           func(stream grpc.ClientStream) error
+		singleResponse bool
+		wantEnd        bool
+		wantCode       string
+	}{
+		{
+			name:     "receive EOF",
+			stream:   fakeClientStream{recvErr: io.EOF},
+			call:     func(stream grpc.ClientStream) error { return stream.RecvMsg(nil) },
+			wantEnd:  true,
+			wantCode: "OK",
+		},
+		{
+			name:     "receive error",
+			stream:   fakeClientStream{recvErr: status.Error(grpccodes.NotFound, "missing")},
+			call:     func(stream grpc.ClientStream) error { return stream.RecvMsg(nil) },
+			wantEnd:  true,
+			wantCode: "NOT_FOUND",
+		},
+		{
+			name:   "receive message",
+			stream: fakeClientStream{},
+			call:   func(stream grpc.ClientStream) error { return stream.RecvMsg(nil) },
+		},
+		{
+			name:           "receive response",
+			stream:         fakeClientStream{},
+			call:           func(stream grpc.ClientStream) error { return stream.RecvMsg(nil) },
+			singleResponse: true,
+			wantEnd:        true,
+			wantCode:       "OK",
+		},
+		{
+			// The status of a stream that ended is reported by the next RecvMsg.
+			name:   "send EOF",
+			stream: fakeClientStream{sendErr: io.EOF},
+			call:   func(stream grpc.ClientStream) error { return stream.SendMsg(nil) },
+		},
+		{
+			name:     "send error",
+			stream:   fakeClientStream{sendErr: status.Error(grpccodes.Internal, "marshal")},
+			call:     func(stream grpc.ClientStream) error { return stream.SendMsg(nil) },
+			wantEnd:  true,
+			wantCode: "INTERNAL",
+		},
+		{
+			name:     "header error",
+			stream:   fakeClientStream{headerErr: status.Error(grpccodes.Unavailable, "gone")},
+			call:     func(stream grpc.ClientStream) error { _, err := stream.Header(); return err },
+			wantEnd:  true,
+			wantCode: "UNAVAILABLE",
+		},
+		{
+			name:   "header",
+			stream: fakeClientStream{},
+			call:   func(stream grpc.ClientStream) error { _, err := stream.Header(); return err },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracerProvider, exporter := newTestTracerProvider(t)
+
+			_, span := tracerProvider.Tracer("test").Start(context.Background(), "stream")
+
+			stream := &clientStream{ClientStream: tt.stream, span: span, serverStreams: !tt.singleResponse, done: make(chan struct{})}
+
+			_ = tt.call(stream)
+
+			// A second final status does not end the span again.
+			_ = tt.call(stream)
+
+			spans := exporter.GetSpans()
+			if !tt.wantEnd {
+				if len(spans) != 0 {
+					t.Errorf("span ended, want it still running")
+				}
+
+				return
+			}
+
+			if len(spans) != 1 {
+				t.Fatalf("got %d ended spans, want 1", len(spans))
+			}
+
+			got := responseStatusCode(spans[0])
+			if got != tt.wantCode {
+				t.Errorf("rpc.response.status_code = %q, want %q", got, tt.wantCode)
+			}
+
+			select {
+			case <-stream.done:
+			default:
+				t.Error("done is still open after the span ended")
+			}
+		})
+	}
+}
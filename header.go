@@ -0,0 +1,127 @@
+package ttrace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HeaderProvider returns headers to attach to an individual OTLP export request. It is called once
+// per request, which makes it suitable for short-lived credentials such as bearer tokens that are
+// refreshed in the background. Returned headers override static headers with the same name.
+type HeaderProvider func(ctx context.Context) (map[string]string, error)
+
+// Compression values accepted by [WithCompression] and the [OTLPCompression] configuration key.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// parseHeaders parses value in the OpenTelemetry OTEL_EXPORTER_OTLP_HEADERS format.
+func parseHeaders(value string) (map[string]string, error) {
+	return parseKeyValues(OTLPHeaders, value)
+}
+
+// parseKeyValues parses value as a comma-separated list of key=value pairs whose keys and values
+// may be percent-encoded, as used by OTEL_EXPORTER_OTLP_HEADERS and OTEL_RESOURCE_ATTRIBUTES. Blank
+// entries are ignored. name identifies the setting in error messages.
+func parseKeyValues(name, value string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		rawKey, rawValue, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("ttrace: invalid %s entry %q: want key=value", name, item)
+		}
+
+		key, err := url.PathUnescape(strings.TrimSpace(rawKey))
+		if err != nil || key == "" {
+			return nil, fmt.Errorf("ttrace: invalid %s key %q", name, rawKey)
+		}
+
+		val, err := url.PathUnescape(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: invalid %s value for key %q: %w", name, key, err)
+		}
+
+		values[key] = val
+	}
+
+	return values, nil
+}
+
+// validateCompression reports an error when compression is not one of the supported values. The
+// empty string is accepted as [CompressionNone].
+func validateCompression(compression string) error {
+	switch compression {
+	case "", CompressionNone, CompressionGzip:
+		return nil
+	default:
+		return fmt.Errorf("ttrace: invalid %s %q: must be %q or %q", OTLPCompression, compression, CompressionNone, CompressionGzip)
+	}
+}
+
+// headerRoundTripper adds headers from a [HeaderProvider] to each outgoing request.
+type headerRoundTripper struct {
+	base     http.RoundTripper
+	provider HeaderProvider
+}
+
+// RoundTrip clones req, applies the provider headers, and delegates to the base transport.
+func (t *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers, err := t.provider(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		return nil, fmt.Errorf("ttrace: header provider: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// headerCredentials adapts a [HeaderProvider] to gRPC per-RPC credentials. It also carries the
+// static headers, so that a provider header replaces a static one with the same name instead of
+// being sent next to it.
+type headerCredentials struct {
+	headers  map[string]string
+	provider HeaderProvider
+}
+
+// GetRequestMetadata returns the static headers merged with the provider headers as gRPC metadata.
+// gRPC metadata keys are lowercase, so header names are folded before merging.
+func (c headerCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	headers, err := c.provider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: header provider: %w", err)
+	}
+
+	md := make(map[string]string, len(c.headers)+len(headers))
+	for key, val := range c.headers {
+		md[strings.ToLower(key)] = val
+	}
+
+	for key, val := range headers {
+		md[strings.ToLower(key)] = val
+	}
+
+	return md, nil
+}
+
+// RequireTransportSecurity returns false so that providers also work with plaintext collectors.
+func (c headerCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,165 @@
+package ttrace
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span events recorded by [WrapTransport] when [WithClientEvents] is enabled.
+const (
+	// HTTPRedirectEvent is added to the client span of a request that follows a redirect.
+	HTTPRedirectEvent = "http.redirect"
+	// HTTPRetryEvent is added to the client span of a request marked with [ContextWithRetryAttempt].
+	HTTPRetryEvent = "http.retry"
+)
+
+// clientConfig collects the settings applied by [ClientOption] values.
+type clientConfig struct {
+	propagator        propagation.TextMapPropagator
+	tracerProvider    trace.TracerProvider
+	spanNameFormatter func(req *http.Request) string
+	events            bool
+}
+
+// ClientOption configures [WrapTransport] and [WrapClient].
+type ClientOption func(cfg *clientConfig)
+
+// WithClientPropagator injects the trace context with propagator, such as [Handle.Propagator],
+// instead of the global TextMapPropagator installed by [Init].
+func WithClientPropagator(propagator propagation.TextMapPropagator) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.propagator = propagator
+	}
+}
+
+// WithClientTracerProvider creates client spans with tracerProvider, such as
+// [Handle.TracerProvider], instead of the global TracerProvider.
+func WithClientTracerProvider(tracerProvider trace.TracerProvider) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.tracerProvider = tracerProvider
+	}
+}
+
+// WithClientSpanNameFormatter names client spans with formatter instead of the HTTP method. The
+// name should remain low-cardinality, for example a method and route template.
+func WithClientSpanNameFormatter(formatter func(req *http.Request) string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.spanNameFormatter = formatter
+	}
+}
+
+// WithClientEvents adds [HTTPRedirectEvent] and [HTTPRetryEvent] events to client spans of
+// redirected and retried requests.
+func WithClientEvents(enabled bool) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.events = enabled
+	}
+}
+
+// WrapTransport returns an [http.RoundTripper] instrumented with
+// [go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp]: each request gets a client span
+// with semantic-convention HTTP attributes, the span context is injected into the request headers,
+// and the response status code or transport error is recorded on the span. Requests that follow a
+// redirect or carry [ContextWithRetryAttempt] also get http.request.resend_count. A nil base means
+// [http.DefaultTransport].
+func WrapTransport(base http.RoundTripper, opts ...ClientOption) http.RoundTripper {
+	cfg := &clientConfig{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var otelOpts []otelhttp.Option
+
+	if cfg.propagator != nil {
+		otelOpts = append(otelOpts, otelhttp.WithPropagators(cfg.propagator))
+	}
+
+	if cfg.tracerProvider != nil {
+		otelOpts = append(otelOpts, otelhttp.WithTracerProvider(cfg.tracerProvider))
+	}
+
+	if cfg.spanNameFormatter != nil {
+		formatter := cfg.spanNameFormatter
+
+		otelOpts = append(otelOpts, otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return formatter(req)
+		}))
+	}
+
+	// The resend transport runs inside the otelhttp span, so it annotates the client span itself.
+	return otelhttp.NewTransport(resendTransport{base: base, events: cfg.events}, otelOpts...)
+}
+
+// WrapClient returns a copy of client whose transport is wrapped with [WrapTransport]. client is
+// not modified; a nil client means [http.DefaultClient].
+func WrapClient(client *http.Client, opts ...ClientOption) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	wrapped := *client
+	wrapped.Transport = WrapTransport(client.Transport, opts...)
+
+	return &wrapped
+}
+
+// retryAttemptContextKey stores the retry attempt set by [ContextWithRetryAttempt].
+type retryAttemptContextKey struct{}
+
+// ContextWithRetryAttempt marks requests sent with the returned context as retry number attempt,
+// starting at 1 for the first retry, so that [WrapTransport] records it. Retry loops call it before
+// re-sending a request.
+func ContextWithRetryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, retryAttemptContextKey{}, attempt)
+}
+
+// resendTransport records redirects and retries on the client span in the request context.
+type resendTransport struct {
+	base   http.RoundTripper
+	events bool
+}
+
+// RoundTrip annotates the client span and delegates to the base transport.
+func (t resendTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span := trace.SpanFromContext(req.Context())
+
+	// http.Client links each redirected request to the response that caused it.
+	redirects := 0
+	for response := req.Response; response != nil && response.Request != nil; response = response.Request.Response {
+		redirects++
+	}
+
+	attempt, _ := req.Context().Value(retryAttemptContextKey{}).(int)
+
+	if redirects > 0 || attempt > 0 {
+		span.SetAttributes(semconv.HTTPRequestResendCount(redirects + attempt))
+	}
+
+	if t.events && redirects > 0 {
+		span.AddEvent(HTTPRedirectEvent, trace.WithAttributes(
+			semconv.HTTPResponseStatusCode(req.Response.StatusCode),
+			attribute.Int("http.redirect.count", redirects),
+		))
+	}
+
+	if t.events && attempt > 0 {
+		span.AddEvent(HTTPRetryEvent, trace.WithAttributes(
+			attribute.Int("http.retry.attempt", attempt),
+		))
+	}
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,259 @@
+package ttrace
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestClient returns a client wrapped with [WrapClient] that records its spans in the returned
+// exporter and injects W3C Trace Context headers.
+func newTestClient(t *testing.T, opts ...ClientOption) (*http.Client, *tracetest.InMemoryExporter, trace.Tracer) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	t.Cleanup(func() {
+		_ = tracerProvider.Shutdown(context.Background())
+	})
+
+	opts = append([]ClientOption{
+		WithClientTracerProvider(tracerProvider),
+		WithClientPropagator(propagation.TraceContext{}),
+	}, opts...)
+
+	return WrapClient(nil, opts...), exporter, tracerProvider.Tracer("test")
+}
+
+// get sends a GET request for url with ctx and drains the response, which ends the client span.
+func get(t *testing.T, client *http.Client, ctx context.Context, url string) error {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.Body.Close()
+}
+
+// clientSpans returns the ended client spans recorded by exporter.
+func clientSpans(exporter *tracetest.InMemoryExporter) tracetest.SpanStubs {
+	var spans tracetest.SpanStubs
+
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind == trace.SpanKindClient {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// intAttribute returns the integer value of key on span, or -1 when it is absent.
+func intAttribute(span tracetest.SpanStub, key string) int64 {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value.AsInt64()
+		}
+	}
+
+	return -1
+}
+
+func TestWrapTransportInjectsClientSpan(t *testing.T) {
+	var traceparent string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	client, exporter, tracer := newTestClient(t)
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+
+	err := get(t, client, ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent.End()
+
+	spans := clientSpans(exporter)
+	if len(spans) != 1 {
+		t.Fatalf("got %d client spans, want 1", len(spans))
+	}
+
+	span := spans[0]
+
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("got client span parent %s, want %s", span.Parent.SpanID(), parent.SpanContext().SpanID())
+	}
+
+	want := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("got traceparent %q, want %q", traceparent, want)
+	}
+
+	if intAttribute(span, string(semconv.HTTPResponseStatusCodeKey)) != http.StatusOK {
+		t.Errorf("got attributes %v, want %s 200", span.Attributes, semconv.HTTPResponseStatusCodeKey)
+	}
+
+	if intAttribute(span, string(semconv.HTTPRequestResendCountKey)) != -1 {
+		t.Errorf("got attributes %v, want no %s", span.Attributes, semconv.HTTPRequestResendCountKey)
+	}
+}
+
+func TestWrapTransportRecordsStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		want       codes.Code
+	}{
+		{"ok", http.StatusOK, codes.Unset},
+		{"redirect", http.StatusNotModified, codes.Unset},
+		{"client error", http.StatusNotFound, codes.Error},
+		{"server error", http.StatusServiceUnavailable, codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client, exporter, _ := newTestClient(t)
+
+			err := get(t, client, context.Background(), server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			spans := clientSpans(exporter)
+			if len(spans) != 1 {
+				t.Fatalf("got %d client spans, want 1", len(spans))
+			}
+
+			if spans[0].Status.Code != tt.want {
+				t.Errorf("got status %v, want %v", spans[0].Status.Code, tt.want)
+			}
+
+			if intAttribute(spans[0], string(semconv.HTTPResponseStatusCodeKey)) != int64(tt.statusCode) {
+				t.Errorf("got attributes %v, want %s %d", spans[0].Attributes, semconv.HTTPResponseStatusCodeKey, tt.statusCode)
+			}
+		})
+	}
+}
+
+func TestWrapTransportRecordsTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client, exporter, _ := newTestClient(t)
+
+	err := get(t, client, context.Background(), url)
+	if err == nil {
+		t.Fatal("got nil error from a closed server, want error")
+	}
+
+	spans := clientSpans(exporter)
+	if len(spans) != 1 {
+		t.Fatalf("got %d client spans, want 1", len(spans))
+	}
+
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description == "" {
+		t.Errorf("got status %+v, want an error with a description", spans[0].Status)
+	}
+}
+
+func TestWrapTransportRecordsRedirectsAndRetries(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/new", http.StatusFound))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, exporter, _ := newTestClient(t,
+		WithClientEvents(true),
+		WithClientSpanNameFormatter(func(req *http.Request) string {
+			return req.Method + " " + req.URL.Path
+		}),
+	)
+
+	err := get(t, client, context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = get(t, client, ContextWithRetryAttempt(context.Background(), 2), server.URL+"/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		resendCount int64
+		event       string
+	}{
+		{"GET /old", -1, ""},
+		{"GET /new", 1, HTTPRedirectEvent},
+		{"GET /new", 2, HTTPRetryEvent},
+	}
+
+	spans := clientSpans(exporter)
+	if len(spans) != len(tests) {
+		t.Fatalf("got %d client spans, want %d", len(spans), len(tests))
+	}
+
+	for i, tt := range tests {
+		span := spans[i]
+
+		if span.Name != tt.name {
+			t.Errorf("got span %d name %q, want %q", i, span.Name, tt.name)
+		}
+
+		got := intAttribute(span, string(semconv.HTTPRequestResendCountKey))
+		if got != tt.resendCount {
+			t.Errorf("got span %d resend count %d, want %d", i, got, tt.resendCount)
+		}
+
+		var events []string
+		for _, event := range span.Events {
+			events = append(events, event.Name)
+		}
+
+		if tt.event == "" && len(events) != 0 || tt.event != "" && (len(events) != 1 || events[0] != tt.event) {
+			t.Errorf("got span %d events %v, want %q", i, events, tt.event)
+		}
+	}
+}
+
+func TestWrapClientCopiesClient(t *testing.T) {
+	client := &http.Client{}
+
+	wrapped := WrapClient(client)
+	if wrapped == client || client.Transport != nil || wrapped.Transport == nil {
+		t.Errorf("got original transport %v and wrapped transport %v, want only the copy wrapped", client.Transport, wrapped.Transport)
+	}
+}
@@ -0,0 +1,196 @@
+package ttrace
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrJobOverlap is returned by [Job.Run] when [JobOptions].SkipOverlapping is set and a previous
+// run is still in progress.
+var ErrJobOverlap = errors.New("ttrace: job run skipped: previous run still in progress")
+
+// Span attributes recorded on the root span of each [Job] run.
+const (
+	// JobNameKey holds the job name.
+	JobNameKey = attribute.Key("job.name")
+	// JobRunIDKey holds a random identifier unique to the run.
+	JobRunIDKey = attribute.Key("job.run_id")
+	// JobMissedRunsKey holds the number of scheduled runs missed since the previous run, when
+	// [JobOptions].Interval is set and runs were missed.
+	JobMissedRunsKey = attribute.Key("job.missed_runs")
+	// JobOverlappingRunsKey holds the number of previous runs still in progress when the run
+	// started, when there are any.
+	JobOverlappingRunsKey = attribute.Key("job.overlapping_runs")
+	// JobSkippedKey is set to true on runs skipped because of [JobOptions].SkipOverlapping.
+	JobSkippedKey = attribute.Key("job.skipped")
+)
+
+// JobOptions configures a [Job].
+type JobOptions struct {
+	// Interval is the expected time between runs. When set, a run that starts about n intervals
+	// after the previous one records n-1 missed runs. Zero disables missed-run detection.
+	Interval time.Duration
+	// SkipOverlapping skips a run, returning [ErrJobOverlap], while a previous run is in progress.
+	// By default overlapping runs proceed and are only recorded.
+	SkipOverlapping bool
+}
+
+// JobStats holds cumulative [Job] counters.
+type JobStats struct {
+	// Runs counts calls of [Job.Run], including skipped runs.
+	Runs uint64
+	// Failures counts runs whose function returned an error or panicked.
+	Failures uint64
+	// MissedRuns counts scheduled runs detected as missed.
+	MissedRuns uint64
+	// OverlappingRuns counts runs that started while a previous run was in progress.
+	OverlappingRuns uint64
+	// SkippedRuns counts overlapping runs skipped because of SkipOverlapping.
+	SkippedRuns uint64
+}
+
+// Job wraps a scheduled function, such as a cron entry, so that every run starts a fresh root
+// trace with [JobNameKey] and [JobRunIDKey] attributes, and missed or overlapping runs are recorded
+// on the run span and in [Job.Stats]. A Job is safe for concurrent use.
+type Job struct {
+	name    string
+	options JobOptions
+	fn      func(ctx context.Context) error
+
+	lock      sync.Mutex
+	running   int
+	lastStart time.Time
+
+	runs            atomic.Uint64
+	failures        atomic.Uint64
+	missedRuns      atomic.Uint64
+	overlappingRuns atomic.Uint64
+	skippedRuns     atomic.Uint64
+
+	timeNow func() time.Time
+}
+
+// NewJob returns a [Job] named name running fn.
+func NewJob(name string, options JobOptions, fn func(ctx context.Context) error) *Job {
+	return newJob(name, options, fn, time.Now)
+}
+
+// newJob is [NewJob] with the clock used to detect missed runs.
+func newJob(name string, options JobOptions, fn func(ctx context.Context) error, timeNow func() time.Time) *Job {
+	return &Job{
+		name:    name,
+		options: options,
+		fn:      fn,
+		timeNow: timeNow,
+	}
+}
+
+// Run runs the job function in a new root span named after the job and returns its error. The
+// span is not a child of the span in ctx, but other values of ctx, such as its cancellation, are
+// kept. A panic in the function is recorded on the span and re-raised.
+func (j *Job) Run(ctx context.Context) (err error) {
+	seq := j.runs.Add(1)
+	now := j.timeNow()
+
+	j.lock.Lock()
+
+	overlapping := j.running
+	missed := j.missedSince(now)
+	skip := overlapping > 0 && j.options.SkipOverlapping
+
+	// A skipped run does not start, so the next run still measures missed runs from the last
+	// run that did.
+	if !skip {
+		j.lastStart = now
+		j.running++
+	}
+
+	j.lock.Unlock()
+
+	runID, err := NewSpanId()
+	if err != nil {
+		runID = strconv.FormatUint(seq, 10)
+	}
+
+	attributes := []attribute.KeyValue{
+		JobNameKey.String(j.name),
+		JobRunIDKey.String(runID),
+	}
+
+	if missed > 0 {
+		j.missedRuns.Add(uint64(missed))
+		attributes = append(attributes, JobMissedRunsKey.Int(missed))
+	}
+
+	if overlapping > 0 {
+		j.overlappingRuns.Add(1)
+		attributes = append(attributes, JobOverlappingRunsKey.Int(overlapping))
+	}
+
+	if skip {
+		j.skippedRuns.Add(1)
+		attributes = append(attributes, JobSkippedKey.Bool(true))
+	}
+
+	ctx, span := Start(ctx, j.name, trace.WithNewRoot(), trace.WithAttributes(attributes...))
+
+	if skip {
+		span.End()
+
+		return ErrJobOverlap
+	}
+
+	// returned stays false when the function panics.
+	returned := false
+
+	defer func() {
+		j.lock.Lock()
+		j.running--
+		j.lock.Unlock()
+
+		if err != nil || !returned {
+			j.failures.Add(1)
+		}
+	}()
+
+	defer endTaskSpan(span, &err)
+
+	err = j.fn(ctx)
+	returned = true
+
+	return err
+}
+
+// Stats returns the cumulative counters.
+func (j *Job) Stats() JobStats {
+	return JobStats{
+		Runs:            j.runs.Load(),
+		Failures:        j.failures.Load(),
+		MissedRuns:      j.missedRuns.Load(),
+		OverlappingRuns: j.overlappingRuns.Load(),
+		SkippedRuns:     j.skippedRuns.Load(),
+	}
+}
+
+// missedSince returns the number of runs missed between the previous run and now. j.lock must be
+// held.
+func (j *Job) missedSince(now time.Time) int {
+	if j.options.Interval <= 0 || j.lastStart.IsZero() {
+		return 0
+	}
+
+	intervals := math.Round(float64(now.Sub(j.lastStart)) / float64(j.options.Interval))
+	if intervals < 2 {
+		return 0
+	}
+
+	return int(intervals) - 1
+}
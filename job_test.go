@@ -0,0 +1,235 @@
+package ttrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestJobRunStartsRootSpan(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	job := NewJob("cleanup", JobOptions{}, func(ctx context.Context) error { return nil })
+
+	ctx, request := Start(context.Background(), "request")
+
+	err := job.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.End()
+
+	run := spanNamed(t, exporter, "cleanup")
+	if run.Parent.IsValid() {
+		t.Errorf("got parent %v, want a new root", run.Parent)
+	}
+
+	attrs := attributeMap(run.Attributes)
+	if attrs[JobNameKey].AsString() != "cleanup" || attrs[JobRunIDKey].AsString() == "" {
+		t.Errorf("got attributes %v, want the job name and a run ID", run.Attributes)
+	}
+}
+
+func TestJobRunRecordsMissedRuns(t *testing.T) {
+	exporter := installTestTracing(t)
+	clock := newFakeClock()
+
+	job := newJob("cleanup", JobOptions{Interval: time.Minute}, func(ctx context.Context) error { return nil }, clock.Now)
+
+	for _, advance := range []time.Duration{0, time.Minute, 3 * time.Minute} {
+		clock.Advance(advance)
+
+		err := job.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+
+	for i, want := range []int64{0, 0, 2} {
+		got := attributeMap(spans[i].Attributes)[JobMissedRunsKey].AsInt64()
+		if got != want {
+			t.Errorf("got run %d %s %d, want %d", i, JobMissedRunsKey, got, want)
+		}
+	}
+
+	stats := job.Stats()
+	if stats.Runs != 3 || stats.MissedRuns != 2 {
+		t.Errorf("got stats %+v, want 3 runs and 2 missed runs", stats)
+	}
+}
+
+// startBlockedRun starts job.Run on a goroutine and waits until the job function signals started.
+// The error of the run is sent on the returned channel.
+func startBlockedRun(job *Job, started <-chan struct{}) <-chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- job.Run(context.Background())
+	}()
+
+	<-started
+
+	return done
+}
+
+func TestJobRunSkipsOverlappingRuns(t *testing.T) {
+	exporter := installTestTracing(t)
+	clock := newFakeClock()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	job := newJob("cleanup", JobOptions{Interval: time.Minute, SkipOverlapping: true}, func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+
+		return nil
+	}, clock.Now)
+
+	done := startBlockedRun(job, started)
+
+	clock.Advance(time.Minute)
+
+	err := job.Run(context.Background())
+	if !errors.Is(err, ErrJobOverlap) {
+		t.Errorf("got error %v, want ErrJobOverlap", err)
+	}
+
+	close(release)
+
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The skipped run did not start, so this run measures from the first one.
+	clock.Advance(time.Minute)
+
+	err = job.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := job.Stats()
+	want := JobStats{Runs: 3, OverlappingRuns: 1, SkippedRuns: 1, MissedRuns: 1}
+	if stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+
+	var skipped int
+
+	for _, span := range exporter.GetSpans() {
+		attrs := attributeMap(span.Attributes)
+		if attrs[JobSkippedKey].AsBool() {
+			skipped++
+
+			if attrs[JobOverlappingRunsKey].AsInt64() != 1 {
+				t.Errorf("got skipped run attributes %v, want %s 1", span.Attributes, JobOverlappingRunsKey)
+			}
+		}
+	}
+
+	if skipped != 1 {
+		t.Errorf("got %d skipped run spans, want 1", skipped)
+	}
+}
+
+func TestJobRunRecordsOverlappingRuns(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+
+	job := NewJob("cleanup", JobOptions{}, func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+
+		return nil
+	})
+
+	first := startBlockedRun(job, started)
+	second := startBlockedRun(job, started)
+
+	close(release)
+
+	for _, done := range []<-chan error{first, second} {
+		err := <-done
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := job.Stats()
+	if stats.Runs != 2 || stats.OverlappingRuns != 1 || stats.SkippedRuns != 0 {
+		t.Errorf("got stats %+v, want 2 runs with 1 overlapping and none skipped", stats)
+	}
+
+	var overlapping int
+
+	for _, span := range exporter.GetSpans() {
+		if attributeMap(span.Attributes)[JobOverlappingRunsKey].AsInt64() == 1 {
+			overlapping++
+		}
+	}
+
+	if overlapping != 1 {
+		t.Errorf("got %d overlapping run spans, want 1", overlapping)
+	}
+}
+
+func TestJobRunRecordsFailures(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	errFailed := errors.New("failed")
+
+	job := NewJob("cleanup", JobOptions{}, func(ctx context.Context) error { return errFailed })
+
+	err := job.Run(context.Background())
+	if !errors.Is(err, errFailed) {
+		t.Errorf("got error %v, want %v", err, errFailed)
+	}
+
+	panicking := NewJob("panicking", JobOptions{}, func(ctx context.Context) error { panic("boom") })
+
+	func() {
+		defer func() {
+			recovered := recover()
+			if recovered != "boom" {
+				t.Errorf("got panic %v, want boom re-raised", recovered)
+			}
+		}()
+
+		_ = panicking.Run(context.Background())
+	}()
+
+	if job.Stats().Failures != 1 || panicking.Stats().Failures != 1 {
+		t.Errorf("got failures %d and %d, want 1 each", job.Stats().Failures, panicking.Stats().Failures)
+	}
+
+	// The panicking run is no longer counted as running.
+	func() {
+		defer func() { _ = recover() }()
+
+		_ = panicking.Run(context.Background())
+	}()
+
+	if panicking.Stats().OverlappingRuns != 0 {
+		t.Errorf("got %d overlapping runs after a panic, want 0", panicking.Stats().OverlappingRuns)
+	}
+
+	for name, want := range map[string]string{"cleanup": "failed", "panicking": "ttrace: panic: boom"} {
+		span := spanNamed(t, exporter, name)
+		if span.Status.Code != codes.Error || span.Status.Description != want {
+			t.Errorf("got %s status %+v, want error %q", name, span.Status, want)
+		}
+	}
+}
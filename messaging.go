@@ -0,0 +1,147 @@
+package ttrace
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Message describes a message sent with [StartProducer] or processed with [StartConsumer].
+type Message struct {
+	// System is the messaging.system value, such as "kafka", "rabbitmq", or "nats".
+	System string
+	// Destination is the topic, queue, exchange, or subject name (messaging.destination.name).
+	Destination string
+	// ID is the message identifier (messaging.message.id). Empty omits the attribute.
+	ID string
+	// BodySize is the payload size in bytes (messaging.message.body.size). Zero omits the attribute.
+	BodySize int
+	// Attributes are added to the span, for example semconv.MessagingKafkaOffset.
+	Attributes []attribute.KeyValue
+	// Carrier holds the message headers, such as a [KafkaHeadersCarrier], [AMQPTableCarrier],
+	// [NATSHeaderCarrier], or [BytesMapCarrier]. [StartProducer] injects into it and
+	// [StartConsumer] extracts from it. Nil skips propagation.
+	Carrier propagation.TextMapCarrier
+}
+
+// StartProducer starts a producer span named "send <destination>" as a child of the span in ctx
+// and injects the new span context and the baggage of ctx into message.Carrier with the global
+// TextMapPropagator. The caller sends the message and ends the span.
+func StartProducer(ctx context.Context, message Message, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	attributes := append(messageAttributes(message), semconv.MessagingOperationTypeSend, semconv.MessagingOperationName("send"))
+
+	opts = append([]trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attributes...),
+	}, opts...)
+
+	ctx, span := Start(ctx, messagingSpanName("send", message.Destination), opts...)
+
+	if message.Carrier != nil {
+		Inject(ctx, message.Carrier)
+	}
+
+	return ctx, span
+}
+
+// StartConsumer starts a consumer span named "process <destination>" for one message or a batch.
+// The span is a child of the span in ctx, such as a poll loop span, or a new root, and links to
+// the producer span context extracted from each message carrier. Linking instead of parenting keeps
+// a batch from being attributed to the trace of whichever message arrived first. When a single
+// message is processed, the returned context also carries the baggage of the message.
+//
+// Attributes of the first message name the system and destination; ID, BodySize, and Attributes
+// are recorded only for a single message, and batches record messaging.batch.message_count.
+func StartConsumer(ctx context.Context, messages []Message, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	var (
+		first Message
+		links []trace.Link
+		bag   baggage.Baggage
+	)
+
+	if len(messages) > 0 {
+		first = messages[0]
+	}
+
+	for _, msg := range messages {
+		if msg.Carrier == nil {
+			continue
+		}
+
+		extracted := Extract(context.Background(), msg.Carrier)
+
+		spanContext := trace.SpanContextFromContext(extracted)
+		if spanContext.IsValid() {
+			var linkAttributes []attribute.KeyValue
+			if msg.ID != "" {
+				linkAttributes = append(linkAttributes, semconv.MessagingMessageID(msg.ID))
+			}
+
+			links = append(links, trace.Link{SpanContext: spanContext, Attributes: linkAttributes})
+		}
+
+		if len(messages) == 1 {
+			bag = baggage.FromContext(extracted)
+		}
+	}
+
+	var attributes []attribute.KeyValue
+
+	if len(messages) == 1 {
+		attributes = messageAttributes(first)
+	} else {
+		attributes = messageAttributes(Message{System: first.System, Destination: first.Destination})
+		attributes = append(attributes, semconv.MessagingBatchMessageCount(len(messages)))
+	}
+
+	attributes = append(attributes, semconv.MessagingOperationTypeProcess, semconv.MessagingOperationName("process"))
+
+	opts = append([]trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attributes...),
+		trace.WithLinks(links...),
+	}, opts...)
+
+	if bag.Len() > 0 {
+		ctx = baggage.ContextWithBaggage(ctx, bag)
+	}
+
+	return Start(ctx, messagingSpanName("process", first.Destination), opts...)
+}
+
+// messageAttributes returns the messaging attributes describing message.
+func messageAttributes(message Message) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+
+	if message.System != "" {
+		attributes = append(attributes, semconv.MessagingSystemKey.String(message.System))
+	}
+
+	if message.Destination != "" {
+		attributes = append(attributes, semconv.MessagingDestinationName(message.Destination))
+	}
+
+	if message.ID != "" {
+		attributes = append(attributes, semconv.MessagingMessageID(message.ID))
+	}
+
+	if message.BodySize > 0 {
+		attributes = append(attributes, semconv.MessagingMessageBodySize(message.BodySize))
+	}
+
+	return append(attributes, message.Attributes...)
+}
+
+// messagingSpanName returns "<operation> <destination>", or operation alone when destination is
+// empty.
+func messagingSpanName(operation, destination string) string {
+	if destination == "" {
+		return operation
+	}
+
+	return operation + " " + destination
+}
@@ -0,0 +1,232 @@
+package ttrace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// installTestTracing installs a TracerProvider recording into the returned exporter and the W3C
+// propagators as the global providers for the duration of t.
+func installTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	restoreGlobals(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	t.Cleanup(func() {
+		_ = tracerProvider.Shutdown(context.Background())
+	})
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(defaultPropagator())
+
+	return exporter
+}
+
+// attributeMap returns attrs keyed by attribute key.
+func attributeMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value
+	}
+
+	return m
+}
+
+// produce sends one message with [StartProducer] under baggage tenant=acme and returns its
+// headers and the producer span context.
+func produce(t *testing.T, id string) (*[]testHeader, trace.SpanContext) {
+	t.Helper()
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := &[]testHeader{}
+
+	_, span := StartProducer(baggage.ContextWithBaggage(context.Background(), bag), Message{
+		System:      "kafka",
+		Destination: "orders",
+		ID:          id,
+		Carrier:     newTestKafkaCarrier(headers),
+	})
+	span.End()
+
+	return headers, span.SpanContext()
+}
+
+func TestStartProducer(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	ctx, parent := Start(context.Background(), "handler")
+
+	headers := &[]testHeader{}
+
+	_, span := StartProducer(ctx, Message{
+		System:      "kafka",
+		Destination: "orders",
+		ID:          "m1",
+		BodySize:    42,
+		Attributes:  []attribute.KeyValue{semconv.MessagingKafkaOffset(7)},
+		Carrier:     newTestKafkaCarrier(headers),
+	})
+	span.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	producer := spans[0]
+	if producer.Name != "send orders" || producer.SpanKind != trace.SpanKindProducer {
+		t.Errorf("got span %q of kind %v, want send orders of kind producer", producer.Name, producer.SpanKind)
+	}
+
+	if producer.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("got parent %s, want %s", producer.Parent.SpanID(), parent.SpanContext().SpanID())
+	}
+
+	attrs := attributeMap(producer.Attributes)
+	want := map[attribute.Key]attribute.Value{
+		semconv.MessagingSystemKey:          attribute.StringValue("kafka"),
+		semconv.MessagingDestinationNameKey: attribute.StringValue("orders"),
+		semconv.MessagingMessageIDKey:       attribute.StringValue("m1"),
+		semconv.MessagingMessageBodySizeKey: attribute.IntValue(42),
+		semconv.MessagingKafkaOffsetKey:     attribute.IntValue(7),
+		semconv.MessagingOperationTypeKey:   attribute.StringValue("send"),
+		semconv.MessagingOperationNameKey:   attribute.StringValue("send"),
+	}
+	for key, val := range want {
+		if attrs[key] != val {
+			t.Errorf("got %s = %v, want %v", key, attrs[key].Emit(), val.Emit())
+		}
+	}
+
+	extracted := trace.SpanContextFromContext(defaultPropagator().Extract(context.Background(), newTestKafkaCarrier(headers)))
+	if extracted.SpanID() != producer.SpanContext.SpanID() {
+		t.Errorf("got injected span %s, want the producer span %s", extracted.SpanID(), producer.SpanContext.SpanID())
+	}
+}
+
+func TestStartConsumerSingleMessage(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	headers, producer := produce(t, "m1")
+	exporter.Reset()
+
+	ctx, span := StartConsumer(context.Background(), []Message{{
+		System:      "kafka",
+		Destination: "orders",
+		ID:          "m1",
+		Carrier:     newTestKafkaCarrier(headers),
+	}})
+	span.End()
+
+	tenant := baggage.FromContext(ctx).Member("tenant").Value()
+	if tenant != "acme" {
+		t.Errorf("got baggage tenant %q, want acme", tenant)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+
+	consumer := spans[0]
+	if consumer.Name != "process orders" || consumer.SpanKind != trace.SpanKindConsumer {
+		t.Errorf("got span %q of kind %v, want process orders of kind consumer", consumer.Name, consumer.SpanKind)
+	}
+
+	if consumer.Parent.IsValid() || consumer.SpanContext.TraceID() == producer.TraceID() {
+		t.Error("got a consumer span in the producer trace, want a new root linked to it")
+	}
+
+	if len(consumer.Links) != 1 || !consumer.Links[0].SpanContext.Equal(producer.WithRemote(true)) {
+		t.Fatalf("got links %v, want the producer span", consumer.Links)
+	}
+
+	linkAttrs := attributeMap(consumer.Links[0].Attributes)
+	if linkAttrs[semconv.MessagingMessageIDKey].AsString() != "m1" {
+		t.Errorf("got link attributes %v, want %s m1", consumer.Links[0].Attributes, semconv.MessagingMessageIDKey)
+	}
+
+	attrs := attributeMap(consumer.Attributes)
+	if attrs[semconv.MessagingMessageIDKey].AsString() != "m1" || attrs[semconv.MessagingOperationTypeKey].AsString() != "process" {
+		t.Errorf("got attributes %v, want the message ID and the process operation", consumer.Attributes)
+	}
+}
+
+func TestStartConsumerBatch(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	firstHeaders, first := produce(t, "m1")
+	secondHeaders, second := produce(t, "m2")
+	exporter.Reset()
+
+	ctx, poll := Start(context.Background(), "poll")
+
+	ctx, span := StartConsumer(ctx, []Message{
+		{System: "kafka", Destination: "orders", ID: "m1", Carrier: newTestKafkaCarrier(firstHeaders)},
+		{System: "kafka", Destination: "orders", ID: "m2", Carrier: newTestKafkaCarrier(secondHeaders)},
+		{System: "kafka", Destination: "orders", ID: "m3", Carrier: propagation.MapCarrier{}},
+		{System: "kafka", Destination: "orders", ID: "m4"},
+	})
+	span.End()
+	poll.End()
+
+	if baggage.FromContext(ctx).Len() != 0 {
+		t.Errorf("got baggage %v for a batch, want none", baggage.FromContext(ctx))
+	}
+
+	consumer := exporter.GetSpans()[0]
+	if consumer.Parent.SpanID() != poll.SpanContext().SpanID() {
+		t.Errorf("got parent %s, want the poll span %s", consumer.Parent.SpanID(), poll.SpanContext().SpanID())
+	}
+
+	if len(consumer.Links) != 2 || consumer.Links[0].SpanContext.SpanID() != first.SpanID() || consumer.Links[1].SpanContext.SpanID() != second.SpanID() {
+		t.Errorf("got links %v, want the two producer spans", consumer.Links)
+	}
+
+	attrs := attributeMap(consumer.Attributes)
+	if attrs[semconv.MessagingBatchMessageCountKey].AsInt64() != 4 {
+		t.Errorf("got attributes %v, want %s 4", consumer.Attributes, semconv.MessagingBatchMessageCountKey)
+	}
+
+	_, ok := attrs[semconv.MessagingMessageIDKey]
+	if ok || attrs[semconv.MessagingDestinationNameKey].AsString() != "orders" {
+		t.Errorf("got attributes %v, want the destination without a message ID", consumer.Attributes)
+	}
+}
+
+func TestMessagingSpanNameWithoutDestination(t *testing.T) {
+	exporter := installTestTracing(t)
+
+	_, span := StartProducer(context.Background(), Message{System: "nats"})
+	span.End()
+
+	_, span = StartConsumer(context.Background(), nil)
+	span.End()
+
+	got := spanNames(exporter)
+	if len(got) != 2 || got[0] != "send" || got[1] != "process" {
+		t.Errorf("got spans %v, want [send process]", got)
+	}
+}
@@ -0,0 +1,318 @@
+package ttrace
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// config collects the settings applied by [Option] values before a TracerProvider is built.
+type config struct {
+	mode     int
+	endpoint string
+	tls      TLSOptions
+
+	headers        map[string]string
+	headerProvider HeaderProvider
+	compression    string
+	urlPath        string
+
+	file            FileExporterOptions
+	persistentQueue *PersistentQueueOptions
+	destinations    []Destination
+
+	samplingFraction   float64
+	maxTracesPerSecond float64
+	sampler            sdktrace.Sampler
+	watchInterval      time.Duration
+	watchSource        SamplingSource
+	remoteSamplingURL  string
+	remoteInterval     time.Duration
+	samplingRules      *SamplingRules
+	adaptiveSampling   *AdaptiveSamplingConfig
+	consistentSampling *float64
+	debugSampling      DebugSamplingOptions
+
+	resource   *resource.Resource
+	exporter   sdktrace.SpanExporter
+	propagator propagation.TextMapPropagator
+
+	tailSampling *TailSamplingConfig
+
+	noopFallback bool
+
+	// settings lists the effective configuration values and their sources when options were
+	// loaded by [InitFromEnv].
+	settings []Setting
+
+	// err records a configuration error detected while options were being assembled, for example
+	// while parsing tcfg values. It is reported by [New].
+	err error
+}
+
+// newConfig returns the default configuration with opts applied in order. Tracing is disabled by
+// default, and sampling defaults match the tcfg defaults used by [InitFromEnv].
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		mode: TracerModeDisable,
+
+		samplingFraction:   0.1,
+		maxTracesPerSecond: 1.0,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	return cfg
+}
+
+// Option configures [New], [Init], and [InitFromEnv].
+type Option func(cfg *config)
+
+// WithMode selects the exporter mode. Valid values are [TracerModeDisable], [TracerModeStdout],
+// [TracerModeOTLP], [TracerModeOTLPGRPC], and [TracerModeFile]. Other values cause [New] to fail.
+func WithMode(mode int) Option {
+	return func(cfg *config) {
+		cfg.mode = mode
+	}
+}
+
+// WithEndpoint sets the OTLP trace endpoint (host:port) used when the mode is [TracerModeOTLP] or
+// [TracerModeOTLPGRPC].
+func WithEndpoint(endpoint string) Option {
+	return func(cfg *config) {
+		cfg.endpoint = endpoint
+	}
+}
+
+// WithTLS configures transport security for the OTLP exporters. Without it, the exporters connect
+// in plaintext.
+func WithTLS(tlsOptions TLSOptions) Option {
+	return func(cfg *config) {
+		cfg.tls = tlsOptions
+	}
+}
+
+// WithHeaders sets static headers, such as Authorization or X-Scope-OrgID, sent with every OTLP
+// export request. For gRPC, the header names are used as metadata keys.
+func WithHeaders(headers map[string]string) Option {
+	return func(cfg *config) {
+		cfg.headers = headers
+	}
+}
+
+// WithHeaderProvider installs provider to compute headers for every OTLP export request. Headers it
+// returns replace [WithHeaders] values with the same name, compared case-insensitively for gRPC, so
+// each header is sent once. An error from provider fails that export attempt.
+func WithHeaderProvider(provider HeaderProvider) Option {
+	return func(cfg *config) {
+		cfg.headerProvider = provider
+	}
+}
+
+// WithCompression selects the OTLP payload compression: [CompressionGzip] or [CompressionNone]
+// (the default). Other values cause [New] to fail.
+func WithCompression(compression string) Option {
+	return func(cfg *config) {
+		cfg.compression = compression
+	}
+}
+
+// WithURLPath overrides the OTLP/HTTP request path (default "/v1/traces"). It has no effect in
+// [TracerModeOTLPGRPC].
+func WithURLPath(urlPath string) Option {
+	return func(cfg *config) {
+		cfg.urlPath = urlPath
+	}
+}
+
+// WithFile configures the [FileExporter] used when the mode is [TracerModeFile]. A missing path or
+// invalid values cause [New] to fail in that mode.
+func WithFile(options FileExporterOptions) Option {
+	return func(cfg *config) {
+		cfg.file = options
+	}
+}
+
+// WithPersistentQueue spools batches that the exporter fails to send to options.Dir and replays
+// them when it recovers, within a disk budget. See [PersistentQueueExporter]. Invalid values cause
+// [New] to fail.
+func WithPersistentQueue(options PersistentQueueOptions) Option {
+	return func(cfg *config) {
+		cfg.persistentQueue = &options
+	}
+}
+
+// WithDestinations exports spans to each destination in addition to the primary exporter selected
+// by [WithMode] or [WithExporter], which may be left disabled. Each destination has its own batch
+// span processor, or is fed by the [TailSamplingProcessor] with [WithTailSampling], and an optional
+// filter. Invalid destinations cause [New] to fail. Repeated calls add destinations.
+func WithDestinations(destinations ...Destination) Option {
+	return func(cfg *config) {
+		cfg.destinations = append(cfg.destinations, destinations...)
+	}
+}
+
+// WithSampling sets the ratio and per-second throughput stages used to build the default sampler.
+// Either value may be -1 to disable that stage; values below -1 cause [New] to fail. It has no
+// effect when [WithSampler] is also supplied.
+func WithSampling(samplingFraction, maxTracesPerSecond float64) Option {
+	return func(cfg *config) {
+		cfg.samplingFraction = samplingFraction
+		cfg.maxTracesPerSecond = maxTracesPerSecond
+	}
+}
+
+// WithAdaptiveSampling replaces the fixed stages of [WithSampling] with an [AdaptiveSampler] that
+// adjusts its probability to reach adaptiveConfig.TargetTracesPerSecond. [WithRemoteSampling] and
+// [WithSamplingRules] use it as their fallback. [Handle.Sampler] returns nil; the sampler and its
+// decision counts are available through [Handle.AdaptiveSampler] and [Handle.SamplingStats].
+// Invalid values cause [New] to fail. It has no effect when [WithSampler] is also supplied.
+func WithAdaptiveSampling(adaptiveConfig AdaptiveSamplingConfig) Option {
+	return func(cfg *config) {
+		cfg.adaptiveSampling = &adaptiveConfig
+	}
+}
+
+// WithConsistentSampling samples root traces with probability fraction using a [ConsistentSampler],
+// which propagates the probability in the W3C tracestate. It replaces the sampler that
+// [WithSampling], [WithAdaptiveSampling], [WithRemoteSampling], and [WithSamplingRules] would
+// otherwise build, and [Handle.Sampler] returns nil. A fraction outside [0, 1] causes [New] to fail.
+// It has no effect when [WithSampler] is also supplied.
+func WithConsistentSampling(fraction float64) Option {
+	return func(cfg *config) {
+		cfg.consistentSampling = &fraction
+	}
+}
+
+// WithSamplingWatcher re-reads the sampling configuration from source every interval and applies
+// it to the [SamplerController] built from [WithSampling]. The watcher stops when the [Handle] is
+// shut down. It has no effect when [WithSampler] is also supplied or interval is not positive.
+func WithSamplingWatcher(interval time.Duration, source SamplingSource) Option {
+	return func(cfg *config) {
+		cfg.watchInterval = interval
+		cfg.watchSource = source
+	}
+}
+
+// WithRemoteSampling makes root sampling follow the Jaeger-compatible strategy served at url,
+// polled every refreshInterval (one minute when not positive). The [SamplerController] built from
+// [WithSampling] is used until the first strategy arrives; when a later poll fails, the last
+// strategy stays active.
+// The poller stops when the [Handle] is shut down. It has no effect when [WithSampler] is also
+// supplied or url is empty.
+func WithRemoteSampling(url string, refreshInterval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.remoteSamplingURL = url
+		cfg.remoteInterval = refreshInterval
+	}
+}
+
+// WithSamplingRules evaluates rules before the sampler built from [WithSampling] and
+// [WithRemoteSampling], which decides for root spans that match no rule unless rules has a default.
+// Invalid rules cause [New] to fail. It has no effect when [WithSampler] is also supplied.
+func WithSamplingRules(rules SamplingRules) Option {
+	return func(cfg *config) {
+		cfg.samplingRules = &rules
+	}
+}
+
+// WithDebugSampling forces sampling of requests carrying one of the debug signals in options,
+// bypassing the configured sampler within a per-second limit. It also applies to a sampler
+// supplied with [WithSampler]. When options.Header is set, [DebugHeaderPropagator] is added to the
+// installed propagator.
+func WithDebugSampling(options DebugSamplingOptions) Option {
+	return func(cfg *config) {
+		cfg.debugSampling = options
+	}
+}
+
+// WithSampler installs sampler as the TracerProvider sampler, replacing the sampler that
+// [WithSampling] would otherwise build.
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return func(cfg *config) {
+		cfg.sampler = sampler
+	}
+}
+
+// WithResource sets the resource attached to every span. When omitted, a resource containing
+// service.name derived from the executable base name is used.
+func WithResource(res *resource.Resource) Option {
+	return func(cfg *config) {
+		cfg.resource = res
+	}
+}
+
+// WithExporter installs exporter instead of the exporter implied by the mode. Supplying an exporter
+// enables SDK-backed tracing even when the mode is [TracerModeDisable].
+func WithExporter(exporter sdktrace.SpanExporter) Option {
+	return func(cfg *config) {
+		cfg.exporter = exporter
+	}
+}
+
+// WithTailSampling buffers spans per trace with a [TailSamplingProcessor] and exports only traces
+// that the head sampler sampled or that contain an error, a slow span, or a span matching the
+// configured predicate. Spans the head sampler rejects are recorded instead of dropped so that the
+// processor can inspect them. Invalid values cause [New] to fail.
+func WithTailSampling(tailConfig TailSamplingConfig) Option {
+	return func(cfg *config) {
+		cfg.tailSampling = &tailConfig
+	}
+}
+
+// WithPropagator sets the TextMapPropagator installed globally by [Init]. When omitted, W3C Trace
+// Context and W3C Baggage propagation is used.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(cfg *config) {
+		cfg.propagator = propagator
+	}
+}
+
+// WithPropagators sets the global TextMapPropagator to the composition of the named propagators
+// built by [NewPropagator]. Unknown names cause [New] to fail.
+func WithPropagators(names ...string) Option {
+	return func(cfg *config) {
+		propagator, err := NewPropagator(names...)
+		if err != nil {
+			if cfg.err == nil {
+				cfg.err = err
+			}
+
+			return
+		}
+
+		cfg.propagator = propagator
+	}
+}
+
+// WithNoopFallback controls how [Init] and [InitFromEnv] react to configuration errors. When
+// enabled, the error is logged, noop tracing is installed, and initialization reports success.
+// When disabled (the default), the error is returned and the global providers are left untouched.
+func WithNoopFallback(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.noopFallback = enabled
+	}
+}
+
+// withConfigError records err so that [New] reports it. It lets configuration loaders such as
+// [InitFromEnv] surface parse failures through the regular error and fallback handling.
+func withConfigError(err error) Option {
+	return func(cfg *config) {
+		if cfg.err == nil {
+			cfg.err = err
+		}
+	}
+}
+
+// withSettings records the effective configuration reported by [Handle.Settings].
+func withSettings(settings []Setting) Option {
+	return func(cfg *config) {
+		cfg.settings = settings
+	}
+}
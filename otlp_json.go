@@ -0,0 +1,445 @@
+package ttrace
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// otlpIDPattern matches the JSON fields holding trace and span IDs with their string value. The
+// protobuf JSON mapping encodes the IDs as base64, while OTLP/JSON requires hex strings. Field names
+// are never escaped, and a quote inside a string value always is, so only ID fields match.
+var otlpIDPattern = regexp.MustCompile(`"(?:traceId|spanId|parentSpanId)"\s*:\s*"([^"]*)"`)
+
+// marshalOTLPJSON encodes span, with its resource and instrumentation scope, as a single-line
+// OTLP/JSON TracesData object. It uses the protobuf JSON mapping with the OTLP exceptions: trace and
+// span IDs are hex strings and enums are integers.
+func marshalOTLPJSON(span sdktrace.ReadOnlySpan) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(otlpTracesData(span))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertOTLPIDs(data, func(value string) (string, error) {
+		id, err := base64.StdEncoding.DecodeString(value)
+
+		return hex.EncodeToString(id), err
+	})
+}
+
+// unmarshalOTLPJSON decodes the single span of an OTLP/JSON TracesData object written by
+// [marshalOTLPJSON].
+func unmarshalOTLPJSON(line []byte) (tracetest.SpanStub, error) {
+	data, err := convertOTLPIDs(line, func(value string) (string, error) {
+		id, err := hex.DecodeString(value)
+
+		return base64.StdEncoding.EncodeToString(id), err
+	})
+	if err != nil {
+		return tracetest.SpanStub{}, err
+	}
+
+	var traces tracepb.TracesData
+
+	err = protojson.Unmarshal(data, &traces)
+	if err != nil {
+		return tracetest.SpanStub{}, err
+	}
+
+	return spanStubFromOTLP(&traces)
+}
+
+// convertOTLPIDs returns data with convert applied to the value of every ID field.
+func convertOTLPIDs(data []byte, convert func(string) (string, error)) ([]byte, error) {
+	var errs []error
+
+	converted := otlpIDPattern.ReplaceAllFunc(data, func(field []byte) []byte {
+		match := otlpIDPattern.FindSubmatchIndex(field)
+		value := field[match[2]:match[3]]
+
+		id, err := convert(string(value))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ttrace: convert OTLP ID %q: %w", value, err))
+
+			return field
+		}
+
+		return append(append(append([]byte(nil), field[:match[2]]...), id...), field[match[3]:]...)
+	})
+
+	return converted, errors.Join(errs...)
+}
+
+// otlpTracesData converts span, with its resource and instrumentation scope, to OTLP TracesData the
+// way the OTLP exporters do.
+func otlpTracesData(span sdktrace.ReadOnlySpan) *tracepb.TracesData {
+	traceID := span.SpanContext().TraceID()
+	spanID := span.SpanContext().SpanID()
+
+	otlp := &tracepb.Span{
+		TraceId:                traceID[:],
+		SpanId:                 spanID[:],
+		TraceState:             span.SpanContext().TraceState().String(),
+		Flags:                  otlpFlags(span.SpanContext(), span.Parent()),
+		Name:                   span.Name(),
+		Kind:                   tracepb.Span_SpanKind(span.SpanKind()),
+		StartTimeUnixNano:      otlpTime(span.StartTime()),
+		EndTimeUnixNano:        otlpTime(span.EndTime()),
+		Attributes:             otlpKeyValues(span.Attributes()),
+		DroppedAttributesCount: otlpCount(span.DroppedAttributes()),
+		DroppedEventsCount:     otlpCount(span.DroppedEvents()),
+		DroppedLinksCount:      otlpCount(span.DroppedLinks()),
+		Status:                 otlpStatus(span.Status()),
+	}
+
+	parentSpanID := span.Parent().SpanID()
+	if parentSpanID.IsValid() {
+		otlp.ParentSpanId = parentSpanID[:]
+	}
+
+	for _, event := range span.Events() {
+		otlp.Events = append(otlp.Events, &tracepb.Span_Event{
+			TimeUnixNano:           otlpTime(event.Time),
+			Name:                   event.Name,
+			Attributes:             otlpKeyValues(event.Attributes),
+			DroppedAttributesCount: otlpCount(event.DroppedAttributeCount),
+		})
+	}
+
+	for _, link := range span.Links() {
+		linkTraceID := link.SpanContext.TraceID()
+		linkSpanID := link.SpanContext.SpanID()
+
+		otlp.Links = append(otlp.Links, &tracepb.Span_Link{
+			TraceId:                linkTraceID[:],
+			SpanId:                 linkSpanID[:],
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             otlpKeyValues(link.Attributes),
+			DroppedAttributesCount: otlpCount(link.DroppedAttributeCount),
+			Flags:                  otlpFlags(link.SpanContext, link.SpanContext),
+		})
+	}
+
+	resourceSpans := &tracepb.ResourceSpans{
+		Resource: &resourcepb.Resource{},
+	}
+
+	res := span.Resource()
+	if res != nil {
+		resourceSpans.Resource.Attributes = otlpKeyValues(res.Attributes())
+		resourceSpans.SchemaUrl = res.SchemaURL()
+	}
+
+	scope := span.InstrumentationScope()
+
+	resourceSpans.ScopeSpans = []*tracepb.ScopeSpans{
+		{
+			Scope: &commonpb.InstrumentationScope{
+				Name:       scope.Name,
+				Version:    scope.Version,
+				Attributes: otlpKeyValues(scope.Attributes.ToSlice()),
+			},
+			Spans:     []*tracepb.Span{otlp},
+			SchemaUrl: scope.SchemaURL,
+		},
+	}
+
+	return &tracepb.TracesData{
+		ResourceSpans: []*tracepb.ResourceSpans{resourceSpans},
+	}
+}
+
+// otlpFlags returns the W3C trace flags of spanContext with the remote bits of remote.
+func otlpFlags(spanContext, remote trace.SpanContext) uint32 {
+	flags := uint32(spanContext.TraceFlags()) | uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_HAS_IS_REMOTE_MASK)
+	if remote.IsRemote() {
+		flags |= uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK)
+	}
+
+	return flags
+}
+
+// otlpStatus maps status to the OTLP status, whose OK and ERROR codes are swapped relative to
+// [codes.Code].
+func otlpStatus(status sdktrace.Status) *tracepb.Status {
+	switch status.Code {
+	case codes.Ok:
+		return &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK}
+	case codes.Error:
+		return &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: status.Description}
+	default:
+		return &tracepb.Status{}
+	}
+}
+
+// otlpTime returns t as Unix nanoseconds, or 0 for the zero time.
+func otlpTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return uint64(max(0, t.UnixNano()))
+}
+
+// otlpCount clamps a dropped count to the uint32 range of OTLP.
+func otlpCount(count int) uint32 {
+	return uint32(min(max(0, int64(count)), math.MaxUint32))
+}
+
+// otlpKeyValues converts attributes to OTLP key-value pairs.
+func otlpKeyValues(attributes []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	result := make([]*commonpb.KeyValue, 0, len(attributes))
+	for _, kv := range attributes {
+		result = append(result, &commonpb.KeyValue{
+			Key:   string(kv.Key),
+			Value: otlpValue(kv.Value),
+		})
+	}
+
+	return result
+}
+
+// otlpValue converts value to an OTLP AnyValue. Slices become array values.
+func otlpValue(value attribute.Value) *commonpb.AnyValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: value.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: value.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		return otlpArray(value.AsBoolSlice(), func(b bool) attribute.Value { return attribute.BoolValue(b) })
+	case attribute.INT64SLICE:
+		return otlpArray(value.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return otlpArray(value.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return otlpArray(value.AsStringSlice(), attribute.StringValue)
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value.Emit()}}
+	}
+}
+
+// otlpArray converts the elements of a slice attribute to an OTLP array value.
+func otlpArray[T any](elements []T, valueOf func(T) attribute.Value) *commonpb.AnyValue {
+	values := make([]*commonpb.AnyValue, 0, len(elements))
+	for _, element := range elements {
+		values = append(values, otlpValue(valueOf(element)))
+	}
+
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+}
+
+// spanStubFromOTLP converts the single span of data, as produced by [otlpTracesData], to a
+// [tracetest.SpanStub].
+func spanStubFromOTLP(data *tracepb.TracesData) (tracetest.SpanStub, error) {
+	if len(data.ResourceSpans) != 1 || len(data.ResourceSpans[0].ScopeSpans) != 1 || len(data.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		return tracetest.SpanStub{}, fmt.Errorf("ttrace: decode OTLP span: want exactly one span")
+	}
+
+	resourceSpans := data.ResourceSpans[0]
+	scopeSpans := resourceSpans.ScopeSpans[0]
+	otlp := scopeSpans.Spans[0]
+
+	spanContext, err := otlpSpanContext(otlp.TraceId, otlp.SpanId, otlp.TraceState, otlp.Flags, false)
+	if err != nil {
+		return tracetest.SpanStub{}, err
+	}
+
+	stub := tracetest.SpanStub{
+		Name:              otlp.Name,
+		SpanContext:       spanContext,
+		SpanKind:          trace.SpanKind(otlp.Kind),
+		StartTime:         timeFromOTLP(otlp.StartTimeUnixNano),
+		EndTime:           timeFromOTLP(otlp.EndTimeUnixNano),
+		Attributes:        attributesFromOTLP(otlp.Attributes),
+		DroppedAttributes: int(otlp.DroppedAttributesCount),
+		DroppedEvents:     int(otlp.DroppedEventsCount),
+		DroppedLinks:      int(otlp.DroppedLinksCount),
+		Status:            sdkStatusFromOTLP(otlp.Status),
+		Resource:          resource.NewWithAttributes(resourceSpans.SchemaUrl, attributesFromOTLP(resourceSpans.GetResource().GetAttributes())...),
+		InstrumentationScope: instrumentation.Scope{
+			Name:       scopeSpans.GetScope().GetName(),
+			Version:    scopeSpans.GetScope().GetVersion(),
+			SchemaURL:  scopeSpans.SchemaUrl,
+			Attributes: attribute.NewSet(attributesFromOTLP(scopeSpans.GetScope().GetAttributes())...),
+		},
+	}
+
+	if len(otlp.ParentSpanId) != 0 {
+		stub.Parent, err = otlpSpanContext(otlp.TraceId, otlp.ParentSpanId, "", 0, otlp.Flags&uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK) != 0)
+		if err != nil {
+			return tracetest.SpanStub{}, err
+		}
+	}
+
+	for _, event := range otlp.Events {
+		stub.Events = append(stub.Events, sdktrace.Event{
+			Name:                  event.Name,
+			Attributes:            attributesFromOTLP(event.Attributes),
+			DroppedAttributeCount: int(event.DroppedAttributesCount),
+			Time:                  timeFromOTLP(event.TimeUnixNano),
+		})
+	}
+
+	for _, link := range otlp.Links {
+		linkContext, err := otlpSpanContext(link.TraceId, link.SpanId, link.TraceState, link.Flags, link.Flags&uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK) != 0)
+		if err != nil {
+			return tracetest.SpanStub{}, err
+		}
+
+		stub.Links = append(stub.Links, sdktrace.Link{
+			SpanContext:           linkContext,
+			Attributes:            attributesFromOTLP(link.Attributes),
+			DroppedAttributeCount: int(link.DroppedAttributesCount),
+		})
+	}
+
+	return stub, nil
+}
+
+// otlpSpanContext builds a span context from OTLP IDs, tracestate, and flags.
+func otlpSpanContext(traceIDBytes, spanIDBytes []byte, traceStateValue string, flags uint32, remote bool) (trace.SpanContext, error) {
+	var (
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	)
+
+	if len(traceIDBytes) != len(traceID) {
+		return trace.SpanContext{}, fmt.Errorf("ttrace: decode OTLP trace ID %x: want %d bytes", traceIDBytes, len(traceID))
+	}
+
+	if len(spanIDBytes) != len(spanID) {
+		return trace.SpanContext{}, fmt.Errorf("ttrace: decode OTLP span ID %x: want %d bytes", spanIDBytes, len(spanID))
+	}
+
+	copy(traceID[:], traceIDBytes)
+	copy(spanID[:], spanIDBytes)
+
+	traceState, err := trace.ParseTraceState(traceStateValue)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("ttrace: decode OTLP trace state %q: %w", traceStateValue, err)
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags & uint32(tracepb.SpanFlags_SPAN_FLAGS_TRACE_FLAGS_MASK)),
+		TraceState: traceState,
+		Remote:     remote,
+	}), nil
+}
+
+// sdkStatusFromOTLP reverses [otlpStatus].
+func sdkStatusFromOTLP(status *tracepb.Status) sdktrace.Status {
+	switch status.GetCode() {
+	case tracepb.Status_STATUS_CODE_OK:
+		return sdktrace.Status{Code: codes.Ok}
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return sdktrace.Status{Code: codes.Error, Description: status.GetMessage()}
+	default:
+		return sdktrace.Status{Code: codes.Unset}
+	}
+}
+
+// timeFromOTLP reverses [otlpTime].
+func timeFromOTLP(nanos uint64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, int64(min(nanos, math.MaxInt64)))
+}
+
+// attributesFromOTLP converts OTLP key-value pairs back to attributes. Values that cannot be
+// represented, such as maps and bytes, are dropped.
+func attributesFromOTLP(keyValues []*commonpb.KeyValue) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+
+	for _, kv := range keyValues {
+		value, ok := attributeValueFromOTLP(kv.GetValue())
+		if ok {
+			attributes = append(attributes, attribute.KeyValue{Key: attribute.Key(kv.GetKey()), Value: value})
+		}
+	}
+
+	return attributes
+}
+
+// attributeValueFromOTLP reverses [otlpValue].
+func attributeValueFromOTLP(value *commonpb.AnyValue) (attribute.Value, bool) {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return attribute.StringValue(v.StringValue), true
+	case *commonpb.AnyValue_BoolValue:
+		return attribute.BoolValue(v.BoolValue), true
+	case *commonpb.AnyValue_IntValue:
+		return attribute.Int64Value(v.IntValue), true
+	case *commonpb.AnyValue_DoubleValue:
+		return attribute.Float64Value(v.DoubleValue), true
+	case *commonpb.AnyValue_ArrayValue:
+		return arrayValueFromOTLP(v.ArrayValue.GetValues())
+	default:
+		return attribute.Value{}, false
+	}
+}
+
+// arrayValueFromOTLP converts a homogeneous OTLP array to a slice attribute value. The array is
+// typed by its first element.
+func arrayValueFromOTLP(values []*commonpb.AnyValue) (attribute.Value, bool) {
+	if len(values) == 0 {
+		return attribute.StringSliceValue(nil), true
+	}
+
+	switch values[0].GetValue().(type) {
+	case *commonpb.AnyValue_BoolValue:
+		return arrayFromOTLP(values, (*commonpb.AnyValue).GetBoolValue, attribute.BoolSliceValue)
+	case *commonpb.AnyValue_IntValue:
+		return arrayFromOTLP(values, (*commonpb.AnyValue).GetIntValue, attribute.Int64SliceValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return arrayFromOTLP(values, (*commonpb.AnyValue).GetDoubleValue, attribute.Float64SliceValue)
+	case *commonpb.AnyValue_StringValue:
+		return arrayFromOTLP(values, (*commonpb.AnyValue).GetStringValue, attribute.StringSliceValue)
+	default:
+		return attribute.Value{}, false
+	}
+}
+
+// arrayFromOTLP collects values with get, failing when an element has a different type than the
+// first one.
+func arrayFromOTLP[T any](values []*commonpb.AnyValue, get func(*commonpb.AnyValue) T, sliceValue func([]T) attribute.Value) (attribute.Value, bool) {
+	first := reflect.TypeOf(values[0].GetValue())
+	result := make([]T, 0, len(values))
+
+	for _, value := range values {
+		if reflect.TypeOf(value.GetValue()) != first {
+			return attribute.Value{}, false
+		}
+
+		result = append(result, get(value))
+	}
+
+	return sliceValue(result), true
+}
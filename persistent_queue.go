@@ -0,0 +1,569 @@
+package ttrace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Metrics recorded by [PersistentQueueExporter] through the global MeterProvider under
+// instrumentation scope [TracerName].
+const (
+	// PersistentQueueBytesMetric is a gauge of the bytes spooled on disk.
+	PersistentQueueBytesMetric = "ttrace.export.queue.size"
+	// PersistentQueueSpansMetric is a gauge of the spans spooled on disk.
+	PersistentQueueSpansMetric = "ttrace.export.queue.spans"
+	// PersistentQueueEvictedMetric counts spooled spans removed to stay within the disk budget.
+	PersistentQueueEvictedMetric = "ttrace.export.queue.evicted"
+)
+
+// Defaults applied by [NewPersistentQueueExporter] to zero-valued [PersistentQueueOptions] fields.
+const (
+	defaultQueueMaxBytes      = 512 << 20
+	defaultQueueRetryInterval = 5 * time.Second
+
+	// queueSegmentExt is the extension of complete segment files; files being written use
+	// queueSegmentTempExt until they are renamed.
+	queueSegmentExt     = ".seg"
+	queueSegmentTempExt = ".tmp"
+)
+
+// PersistentQueueOptions configures a [PersistentQueueExporter].
+type PersistentQueueOptions struct {
+	// Dir is the directory segment files are written to. It is created when missing and must not be
+	// shared with another process.
+	Dir string
+	// MaxBytes is the disk budget for spooled segments. The oldest segments are evicted when a new
+	// segment would exceed it. Zero means 512 MiB.
+	MaxBytes int64
+	// RetryInterval is how often replay of spooled segments is attempted. Zero means five seconds.
+	RetryInterval time.Duration
+}
+
+// PersistentQueueStats holds the backlog and cumulative counters of a [PersistentQueueExporter].
+type PersistentQueueStats struct {
+	// BacklogBytes, BacklogSegments, and BacklogSpans describe the segments currently on disk.
+	BacklogBytes    int64
+	BacklogSegments int
+	BacklogSpans    int
+	// SpansSpooled counts spans written to disk because the exporter failed or a backlog existed.
+	SpansSpooled uint64
+	// SpansReplayed counts spooled spans exported successfully.
+	SpansReplayed uint64
+	// SpansEvicted counts spooled spans removed to stay within MaxBytes, or because their segment
+	// could not be read.
+	SpansEvicted uint64
+}
+
+// PersistentQueueExporter is a [sdktrace.SpanExporter] that passes batches to another exporter and,
+// when that fails, spools them to disk as OTLP/JSON segment files and replays them once the
+// exporter recovers. While a backlog exists, new batches are spooled behind it so that the batch
+// span processor never blocks on an unreachable collector and spans are replayed in order.
+//
+// Each batch is written to a temporary file, synced, and renamed into place, so a crash leaves
+// either a complete segment or none. Segments left by a previous process are replayed after a
+// restart.
+type PersistentQueueExporter struct {
+	exporter sdktrace.SpanExporter
+	options  PersistentQueueOptions
+
+	lock     sync.Mutex
+	segments []queueSegment
+	bytes    int64
+	spans    int
+	nextSeq  uint64
+
+	exportLock sync.Mutex
+
+	stop    chan struct{}
+	stopped chan struct{}
+	closed  atomic.Bool
+
+	spansSpooled  atomic.Uint64
+	spansReplayed atomic.Uint64
+	spansEvicted  atomic.Uint64
+
+	evictedCounter metric.Int64Counter
+	registration   metric.Registration
+}
+
+// queueSegment describes one segment file. The span count is part of the file name so that the
+// backlog is known after a restart without reading the segments.
+type queueSegment struct {
+	seq   uint64
+	spans int
+	bytes int64
+}
+
+// NewPersistentQueueExporter returns a [PersistentQueueExporter] spooling to options.Dir in front
+// of exporter, loads segments left by a previous process, and starts the replay loop. It returns
+// an error when the directory is empty or cannot be created, or a limit is negative.
+func NewPersistentQueueExporter(exporter sdktrace.SpanExporter, options PersistentQueueOptions) (*PersistentQueueExporter, error) {
+	if exporter == nil {
+		return nil, fmt.Errorf("ttrace: missing exporter for persistent queue")
+	}
+
+	if strings.TrimSpace(options.Dir) == "" {
+		return nil, fmt.Errorf("ttrace: missing %s for persistent queue", TracerQueueDir)
+	}
+
+	if options.MaxBytes < 0 || options.RetryInterval < 0 {
+		return nil, fmt.Errorf("ttrace: invalid persistent queue configuration: values must be >= 0")
+	}
+
+	if options.MaxBytes == 0 {
+		options.MaxBytes = defaultQueueMaxBytes
+	}
+
+	if options.RetryInterval == 0 {
+		options.RetryInterval = defaultQueueRetryInterval
+	}
+
+	err := os.MkdirAll(options.Dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: create persistent queue directory %s: %w", options.Dir, err)
+	}
+
+	q := &PersistentQueueExporter{
+		exporter: exporter,
+		options:  options,
+		nextSeq:  1,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	err = q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	q.registerMetrics()
+
+	go q.run()
+
+	return q, nil
+}
+
+// ExportSpans passes spans to the wrapped exporter when no backlog exists, and spools them to disk
+// when a backlog exists or the export fails. It returns an error only when spooling fails.
+func (q *PersistentQueueExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if q.closed.Load() || len(spans) == 0 {
+		return nil
+	}
+
+	// The lock is held from the backlog check to the write, so that the batch is queued behind the
+	// backlog that was seen and a concurrent replay or eviction cannot change it in between.
+	q.lock.Lock()
+
+	if len(q.segments) > 0 {
+		defer q.lock.Unlock()
+
+		return q.spool(spans)
+	}
+
+	q.lock.Unlock()
+
+	q.exportLock.Lock()
+	err := q.exporter.ExportSpans(ctx, spans)
+	q.exportLock.Unlock()
+
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("ttrace: export failed, spooling %d spans to %s: %v", len(spans), q.options.Dir, err)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.spool(spans)
+}
+
+// Shutdown stops the replay loop and shuts down the wrapped exporter. Spooled segments stay on disk
+// and are replayed by the next exporter using the same directory.
+func (q *PersistentQueueExporter) Shutdown(ctx context.Context) error {
+	if q.closed.Swap(true) {
+		return nil
+	}
+
+	close(q.stop)
+	<-q.stopped
+
+	var err error
+
+	if q.registration != nil {
+		err = q.registration.Unregister()
+	}
+
+	return errors.Join(err, q.exporter.Shutdown(ctx))
+}
+
+// Stats returns the backlog and cumulative counters.
+func (q *PersistentQueueExporter) Stats() PersistentQueueStats {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return PersistentQueueStats{
+		BacklogBytes:    q.bytes,
+		BacklogSegments: len(q.segments),
+		BacklogSpans:    q.spans,
+		SpansSpooled:    q.spansSpooled.Load(),
+		SpansReplayed:   q.spansReplayed.Load(),
+		SpansEvicted:    q.spansEvicted.Load(),
+	}
+}
+
+// spool writes spans to a new segment and evicts the oldest segments beyond the disk budget. q.lock
+// must be held.
+func (q *PersistentQueueExporter) spool(spans []sdktrace.ReadOnlySpan) error {
+	var buf bytes.Buffer
+
+	for _, span := range spans {
+		line, err := marshalOTLPJSON(span)
+		if err != nil {
+			return fmt.Errorf("ttrace: encode span %s: %w", span.Name(), err)
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	segment := queueSegment{
+		seq:   q.nextSeq,
+		spans: len(spans),
+		bytes: int64(buf.Len()),
+	}
+
+	if segment.bytes > q.options.MaxBytes {
+		q.evicted(len(spans))
+
+		return fmt.Errorf("ttrace: batch of %d bytes exceeds persistent queue budget of %d bytes", segment.bytes, q.options.MaxBytes)
+	}
+
+	err := q.writeSegment(segment, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	q.nextSeq++
+	q.spansSpooled.Add(uint64(len(spans)))
+
+	q.segments = append(q.segments, segment)
+	q.bytes += segment.bytes
+	q.spans += segment.spans
+
+	for q.bytes > q.options.MaxBytes && len(q.segments) > 1 {
+		oldest := q.segments[0]
+
+		err = os.Remove(q.segmentPath(oldest))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("ttrace: evict persistent queue segment failed: %v", err)
+		}
+
+		q.removeSegment(0)
+		q.evicted(oldest.spans)
+	}
+
+	return nil
+}
+
+// writeSegment writes data to a temporary file, syncs it, and renames it to the segment path, so
+// that the segment appears completely or not at all.
+func (q *PersistentQueueExporter) writeSegment(segment queueSegment, data []byte) error {
+	path := q.segmentPath(segment)
+	tempPath := strings.TrimSuffix(path, queueSegmentExt) + queueSegmentTempExt
+
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("ttrace: create persistent queue segment: %w", err)
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	err = errors.Join(err, file.Close())
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tempPath)
+
+		return fmt.Errorf("ttrace: write persistent queue segment: %w", err)
+	}
+
+	syncDir(q.options.Dir)
+
+	return nil
+}
+
+// run replays the backlog every retry interval until the exporter is shut down.
+func (q *PersistentQueueExporter) run() {
+	defer close(q.stopped)
+
+	ticker := time.NewTicker(q.options.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+
+		q.replay()
+	}
+}
+
+// replay exports segments oldest first and stops at the first failure.
+func (q *PersistentQueueExporter) replay() {
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		q.lock.Lock()
+		if len(q.segments) == 0 {
+			q.lock.Unlock()
+
+			return
+		}
+
+		segment := q.segments[0]
+		q.lock.Unlock()
+
+		spans, err := q.readSegment(segment)
+		if err != nil {
+			log.Printf("ttrace: discard unreadable persistent queue segment: %v", err)
+			q.finish(segment, false)
+
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+
+		q.exportLock.Lock()
+		err = q.exporter.ExportSpans(ctx, spans)
+		q.exportLock.Unlock()
+
+		cancel()
+
+		if err != nil {
+			return
+		}
+
+		q.finish(segment, true)
+	}
+}
+
+// finish removes segment after it was replayed or found unreadable, unless it was already evicted
+// in the meantime.
+func (q *PersistentQueueExporter) finish(segment queueSegment, replayed bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.segments) == 0 || q.segments[0].seq != segment.seq {
+		return
+	}
+
+	err := os.Remove(q.segmentPath(segment))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("ttrace: remove persistent queue segment failed: %v", err)
+	}
+
+	q.removeSegment(0)
+
+	if replayed {
+		q.spansReplayed.Add(uint64(segment.spans))
+	} else {
+		q.evicted(segment.spans)
+	}
+}
+
+// readSegment decodes the spans of segment.
+func (q *PersistentQueueExporter) readSegment(segment queueSegment) ([]sdktrace.ReadOnlySpan, error) {
+	data, err := os.ReadFile(q.segmentPath(segment))
+	if err != nil {
+		return nil, err
+	}
+
+	var spans []sdktrace.ReadOnlySpan
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	for scanner.Scan() {
+		stub, err := unmarshalOTLPJSON(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: decode persistent queue segment %d: %w", segment.seq, err)
+		}
+
+		spans = append(spans, stub.Snapshot())
+	}
+
+	return spans, scanner.Err()
+}
+
+// load removes incomplete temporary files and indexes the segments found in the directory.
+func (q *PersistentQueueExporter) load() error {
+	entries, err := os.ReadDir(q.options.Dir)
+	if err != nil {
+		return fmt.Errorf("ttrace: read persistent queue directory %s: %w", q.options.Dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+
+		if strings.HasSuffix(name, queueSegmentTempExt) {
+			_ = os.Remove(filepath.Join(q.options.Dir, name))
+
+			continue
+		}
+
+		segment, ok := parseSegmentName(name)
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		segment.bytes = info.Size()
+
+		q.segments = append(q.segments, segment)
+		q.bytes += segment.bytes
+		q.spans += segment.spans
+		q.nextSeq = max(q.nextSeq, segment.seq+1)
+	}
+
+	sort.Slice(q.segments, func(i, j int) bool {
+		return q.segments[i].seq < q.segments[j].seq
+	})
+
+	return nil
+}
+
+// removeSegment drops the segment at index i from the index. q.lock must be held.
+func (q *PersistentQueueExporter) removeSegment(i int) {
+	q.bytes -= q.segments[i].bytes
+	q.spans -= q.segments[i].spans
+	q.segments = append(q.segments[:i], q.segments[i+1:]...)
+}
+
+// evicted counts spans removed from the queue without being exported.
+func (q *PersistentQueueExporter) evicted(spans int) {
+	q.spansEvicted.Add(uint64(spans))
+
+	if q.evictedCounter != nil {
+		q.evictedCounter.Add(context.Background(), int64(spans))
+	}
+}
+
+// segmentPath returns the file path of segment.
+func (q *PersistentQueueExporter) segmentPath(segment queueSegment) string {
+	return filepath.Join(q.options.Dir, fmt.Sprintf("%020d-%d%s", segment.seq, segment.spans, queueSegmentExt))
+}
+
+// parseSegmentName parses a segment file name written by segmentPath.
+func parseSegmentName(name string) (queueSegment, bool) {
+	base, ok := strings.CutSuffix(name, queueSegmentExt)
+	if !ok {
+		return queueSegment{}, false
+	}
+
+	seqValue, spansValue, ok := strings.Cut(base, "-")
+	if !ok {
+		return queueSegment{}, false
+	}
+
+	seq, err := strconv.ParseUint(seqValue, 10, 64)
+	if err != nil {
+		return queueSegment{}, false
+	}
+
+	spans, err := strconv.Atoi(spansValue)
+	if err != nil {
+		return queueSegment{}, false
+	}
+
+	return queueSegment{seq: seq, spans: spans}, true
+}
+
+// registerMetrics creates the backlog gauges and the eviction counter. Failures leave the metrics
+// unrecorded.
+func (q *PersistentQueueExporter) registerMetrics() {
+	meter := otel.Meter(TracerName)
+
+	evictedCounter, err := meter.Int64Counter(PersistentQueueEvictedMetric,
+		metric.WithDescription("Spooled spans evicted from the persistent export queue."),
+		metric.WithUnit("{span}"),
+	)
+	if err == nil {
+		q.evictedCounter = evictedCounter
+	}
+
+	bytesGauge, err := meter.Int64ObservableGauge(PersistentQueueBytesMetric,
+		metric.WithDescription("Bytes spooled in the persistent export queue."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return
+	}
+
+	spansGauge, err := meter.Int64ObservableGauge(PersistentQueueSpansMetric,
+		metric.WithDescription("Spans spooled in the persistent export queue."),
+		metric.WithUnit("{span}"),
+	)
+	if err != nil {
+		return
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		stats := q.Stats()
+
+		observer.ObserveInt64(bytesGauge, stats.BacklogBytes)
+		observer.ObserveInt64(spansGauge, int64(stats.BacklogSpans))
+
+		return nil
+	}, bytesGauge, spansGauge)
+	if err == nil {
+		q.registration = registration
+	}
+}
+
+// syncDir flushes directory metadata so that a renamed segment survives a crash. Errors are
+// ignored because some platforms do not support syncing directories.
+func syncDir(dir string) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	_ = file.Sync()
+	_ = file.Close()
+}
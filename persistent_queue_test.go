@@ -0,0 +1,330 @@
+package ttrace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// failingExporter rejects every batch, like an exporter whose collector is unreachable.
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return errors.New("collector unreachable")
+}
+
+func (failingExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// richSpan returns a span using every field and attribute type that the OTLP/JSON encoding carries.
+func richSpan(t *testing.T) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	traceState, err := trace.ParseTraceState("ot=th:8;rv:c0000000000000,vendor=value")
+	if err != nil {
+		t.Fatalf("ParseTraceState: %v", err)
+	}
+
+	traceID := newTestTraceID()
+	start := time.Unix(1767225600, 123456789)
+
+	attributes := []attribute.KeyValue{
+		attribute.String("string", "value"),
+		attribute.String("json", `{"traceId":"AQID","spanId": "AQID"}`),
+		attribute.Bool("bool", true),
+		attribute.Int64("int", math.MaxInt64),
+		attribute.Float64("float", 0.1),
+		attribute.StringSlice("strings", []string{"a", "b"}),
+		attribute.BoolSlice("bools", []bool{true, false}),
+		attribute.Int64Slice("ints", []int64{-1, 0, 1}),
+		attribute.Float64Slice("floats", []float64{1.5, math.Inf(1), math.NaN()}),
+	}
+
+	stub := tracetest.SpanStub{
+		Name: "GET /users",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: traceState,
+		}),
+		Parent: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID,
+			SpanID:  trace.SpanID{8, 7, 6, 5, 4, 3, 2, 1},
+			Remote:  true,
+		}),
+		SpanKind:   trace.SpanKindServer,
+		StartTime:  start,
+		EndTime:    start.Add(1500 * time.Millisecond),
+		Attributes: attributes,
+		Events: []sdktrace.Event{
+			{
+				Name:                  "exception",
+				Attributes:            []attribute.KeyValue{attribute.String("exception.message", "boom")},
+				DroppedAttributeCount: 1,
+				Time:                  start.Add(time.Second),
+			},
+		},
+		Links: []sdktrace.Link{
+			{
+				SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    newTestTraceID(),
+					SpanID:     trace.SpanID{9},
+					TraceFlags: trace.FlagsSampled,
+					TraceState: traceState,
+					Remote:     true,
+				}),
+				Attributes:            []attribute.KeyValue{attribute.Int("link.index", 0)},
+				DroppedAttributeCount: 2,
+			},
+		},
+		Status:            sdktrace.Status{Code: codes.Error, Description: "internal error"},
+		DroppedAttributes: 3,
+		DroppedEvents:     4,
+		DroppedLinks:      5,
+		Resource:          resource.NewWithAttributes("https://opentelemetry.io/schemas/1.26.0", attribute.String("service.name", "users")),
+		InstrumentationScope: instrumentation.Scope{
+			Name:       "github.com/choveylee/ttrace",
+			Version:    "v1.0.0",
+			SchemaURL:  "https://opentelemetry.io/schemas/1.26.0",
+			Attributes: attribute.NewSet(attribute.String("scope.attribute", "value")),
+		},
+	}
+
+	return stub.Snapshot()
+}
+
+// mustMarshalOTLPJSON encodes span and fails the test on error.
+func mustMarshalOTLPJSON(t *testing.T, span sdktrace.ReadOnlySpan) []byte {
+	t.Helper()
+
+	line, err := marshalOTLPJSON(span)
+	if err != nil {
+		t.Fatalf("marshalOTLPJSON: %v", err)
+	}
+
+	return line
+}
+
+func TestOTLPJSONRoundTrip(t *testing.T) {
+	span := richSpan(t)
+	line := mustMarshalOTLPJSON(t, span)
+
+	// OTLP/JSON differs from the plain protobuf JSON mapping in its hex IDs and integer enums.
+	for _, want := range []string{
+		`"traceId":"` + span.SpanContext().TraceID().String() + `"`,
+		`"spanId":"0102030405060708"`,
+		`"parentSpanId":"0807060504030201"`,
+		`"kind":2`,
+		`"code":2`,
+	} {
+		if !bytes.Contains(line, []byte(want)) {
+			t.Errorf("encoded span %s does not contain %s", line, want)
+		}
+	}
+
+	stub, err := unmarshalOTLPJSON(line)
+	if err != nil {
+		t.Fatalf("unmarshalOTLPJSON: %v", err)
+	}
+
+	decoded := stub.Snapshot()
+
+	got := mustMarshalOTLPJSON(t, decoded)
+	if !bytes.Equal(got, line) {
+		t.Errorf("re-encoded span differs:\ngot  %s\nwant %s", got, line)
+	}
+
+	if decoded.SpanContext().TraceState().String() != span.SpanContext().TraceState().String() {
+		t.Errorf("tracestate = %q, want %q", decoded.SpanContext().TraceState(), span.SpanContext().TraceState())
+	}
+
+	if !decoded.Parent().IsRemote() || decoded.Parent().SpanID() != span.Parent().SpanID() {
+		t.Errorf("parent = %v, want the remote parent %v", decoded.Parent(), span.Parent())
+	}
+
+	if !decoded.StartTime().Equal(span.StartTime()) || !decoded.EndTime().Equal(span.EndTime()) {
+		t.Errorf("times = %v..%v, want %v..%v", decoded.StartTime(), decoded.EndTime(), span.StartTime(), span.EndTime())
+	}
+
+	if decoded.Status() != span.Status() {
+		t.Errorf("status = %+v, want %+v", decoded.Status(), span.Status())
+	}
+
+	link := decoded.Links()[0].SpanContext
+	if !link.IsRemote() || !link.IsSampled() || link.TraceState().String() != span.SpanContext().TraceState().String() {
+		t.Errorf("link = %v, want a remote sampled link with tracestate", link)
+	}
+
+	if decoded.Attributes()[1] != span.Attributes()[1] {
+		t.Errorf("attribute = %v, want the JSON string %v unchanged", decoded.Attributes()[1], span.Attributes()[1])
+	}
+
+	floats := decoded.Attributes()[len(decoded.Attributes())-1].Value.AsFloat64Slice()
+	if len(floats) != 3 || floats[0] != 1.5 || !math.IsInf(floats[1], 1) || !math.IsNaN(floats[2]) {
+		t.Errorf("floats = %v, want [1.5 +Inf NaN]", floats)
+	}
+
+	gotScope := decoded.InstrumentationScope()
+	wantScope := span.InstrumentationScope()
+
+	if !gotScope.Attributes.Equals(&wantScope.Attributes) {
+		t.Errorf("scope attributes = %v, want %v", gotScope.Attributes.ToSlice(), wantScope.Attributes.ToSlice())
+	}
+
+	if !decoded.Resource().Equal(span.Resource()) {
+		t.Errorf("resource = %v, want %v", decoded.Resource(), span.Resource())
+	}
+}
+
+func TestUnmarshalOTLPJSONReadsCollectorFormat(t *testing.T) {
+	line := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"users"}}]},` +
+		`"scopeSpans":[{"scope":{"name":"test"},"spans":[{"traceId":"0af7651916cd43dd8448eb211c80319c",` +
+		`"spanId":"b7ad6b7169203331","name":"op","kind":3,"startTimeUnixNano":"1767225600000000000",` +
+		`"endTimeUnixNano":"1767225601000000000","attributes":[{"key":"retries","value":{"intValue":"3"}}],` +
+		`"status":{"code":1}}]}]}]}`
+
+	stub, err := unmarshalOTLPJSON([]byte(line))
+	if err != nil {
+		t.Fatalf("unmarshalOTLPJSON: %v", err)
+	}
+
+	if stub.SpanContext.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" || stub.SpanContext.SpanID().String() != "b7ad6b7169203331" {
+		t.Errorf("span context = %v, want the hex IDs of the line", stub.SpanContext)
+	}
+
+	if stub.SpanKind != trace.SpanKindClient || stub.Status.Code != codes.Ok {
+		t.Errorf("kind = %v, status = %v; want client and ok", stub.SpanKind, stub.Status.Code)
+	}
+
+	if len(stub.Attributes) != 1 || stub.Attributes[0] != attribute.Int64("retries", 3) {
+		t.Errorf("attributes = %v, want retries=3", stub.Attributes)
+	}
+
+	if stub.EndTime.Sub(stub.StartTime) != time.Second {
+		t.Errorf("duration = %v, want 1s", stub.EndTime.Sub(stub.StartTime))
+	}
+}
+
+func TestPersistentQueueReplaysSpooledSpansAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	span := richSpan(t)
+
+	spooling, err := NewPersistentQueueExporter(failingExporter{}, PersistentQueueOptions{Dir: dir, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewPersistentQueueExporter: %v", err)
+	}
+
+	err = spooling.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{span})
+	if err != nil {
+		t.Fatalf("ExportSpans: %v", err)
+	}
+
+	stats := spooling.Stats()
+	if stats.BacklogSpans != 1 || stats.SpansSpooled != 1 {
+		t.Fatalf("Stats() = %+v, want one spooled span", stats)
+	}
+
+	err = spooling.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	replaying, err := NewPersistentQueueExporter(exporter, PersistentQueueOptions{Dir: dir, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewPersistentQueueExporter: %v", err)
+	}
+	defer replaying.Shutdown(context.Background())
+
+	replaying.replay()
+
+	spans := exporter.GetSpans().Snapshots()
+	if len(spans) != 1 {
+		t.Fatalf("replayed %d spans, want 1", len(spans))
+	}
+
+	got := mustMarshalOTLPJSON(t, spans[0])
+	want := mustMarshalOTLPJSON(t, span)
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("replayed span differs:\ngot  %s\nwant %s", got, want)
+	}
+
+	stats = replaying.Stats()
+	if stats.BacklogSegments != 0 || stats.SpansReplayed != 1 {
+		t.Errorf("Stats() = %+v, want an empty backlog and one replayed span", stats)
+	}
+}
+
+func TestPersistentQueueDiscardsUnreadableSegments(t *testing.T) {
+	dir := t.TempDir()
+
+	spooling, err := NewPersistentQueueExporter(failingExporter{}, PersistentQueueOptions{Dir: dir, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewPersistentQueueExporter: %v", err)
+	}
+	defer spooling.Shutdown(context.Background())
+
+	err = spooling.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{richSpan(t)})
+	if err != nil {
+		t.Fatalf("ExportSpans: %v", err)
+	}
+
+	segment := spooling.segments[0]
+
+	err = os.WriteFile(spooling.segmentPath(segment), []byte(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"not-hex"}]}]}]}`), 0o644)
+	if err != nil {
+		t.Fatalf("overwrite segment: %v", err)
+	}
+
+	spooling.replay()
+
+	stats := spooling.Stats()
+	if stats.BacklogSegments != 0 || stats.SpansEvicted != 1 {
+		t.Errorf("Stats() = %+v, want the unreadable segment evicted", stats)
+	}
+}
+
+func TestPersistentQueueSpoolsConcurrentBatches(t *testing.T) {
+	queue, err := NewPersistentQueueExporter(failingExporter{}, PersistentQueueOptions{Dir: t.TempDir(), RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewPersistentQueueExporter: %v", err)
+	}
+	defer queue.Shutdown(context.Background())
+
+	span := richSpan(t)
+
+	var wg sync.WaitGroup
+
+	for range 20 {
+		wg.Go(func() {
+			err := queue.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{span})
+			if err != nil {
+				t.Errorf("ExportSpans: %v", err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	stats := queue.Stats()
+	if stats.BacklogSpans != 20 || stats.BacklogSegments != 20 || stats.SpansSpooled != 20 {
+		t.Errorf("Stats() = %+v, want every batch spooled in its own segment", stats)
+	}
+}
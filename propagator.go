@@ -0,0 +1,136 @@
+package ttrace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/contrib/propagators/ot"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Propagator names accepted by [WithPropagators], [TracerPropagators], and OTEL_PROPAGATORS.
+const (
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+	PropagatorB3           = "b3"
+	PropagatorB3Multi      = "b3multi"
+	PropagatorJaeger       = "jaeger"
+	PropagatorXRay         = "xray"
+	PropagatorOTTrace      = "ottrace"
+	PropagatorNone         = "none"
+)
+
+// defaultPropagator returns the W3C Trace Context and W3C Baggage composite installed when no
+// propagator is configured.
+func defaultPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// NewPropagator composes the propagators named in names, in order. Injection writes every format.
+// Extraction falls through the list: the first propagator that yields a valid span context
+// determines the parent, while later propagators may still contribute non-trace state such as
+// baggage. [PropagatorNone] must appear alone and yields a propagator that neither injects nor
+// extracts anything. Unknown names are reported as errors.
+func NewPropagator(names ...string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+
+	none := false
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		switch name {
+		case "":
+			continue
+		case PropagatorNone:
+			none = true
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		case PropagatorXRay:
+			propagators = append(propagators, xray.Propagator{})
+		case PropagatorOTTrace:
+			propagators = append(propagators, ot.OT{})
+		default:
+			return nil, fmt.Errorf("ttrace: unsupported propagator %q", name)
+		}
+	}
+
+	if none {
+		if len(propagators) > 0 {
+			return nil, fmt.Errorf("ttrace: propagator %q cannot be combined with other propagators", PropagatorNone)
+		}
+
+		return propagation.NewCompositeTextMapPropagator(), nil
+	}
+
+	return fallthroughPropagator(propagators), nil
+}
+
+// fallthroughPropagator injects with every member and extracts with the members in order, keeping
+// the span context from the first member that finds one.
+type fallthroughPropagator []propagation.TextMapPropagator
+
+// Inject writes the cross-cutting concerns from ctx with every member propagator.
+func (p fallthroughPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	for _, propagator := range p {
+		propagator.Inject(ctx, carrier)
+	}
+}
+
+// Extract applies the member propagators in order. Once a member has extracted a remote span
+// context, later members cannot replace it but may still add other values to the context.
+func (p fallthroughPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	initial := trace.SpanContextFromContext(ctx)
+
+	var extracted trace.SpanContext
+
+	for _, propagator := range p {
+		ctx = propagator.Extract(ctx, carrier)
+
+		if extracted.IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, extracted)
+
+			continue
+		}
+
+		spanContext := trace.SpanContextFromContext(ctx)
+		if spanContext.IsValid() && spanContext.IsRemote() && !spanContext.Equal(initial) {
+			extracted = spanContext
+		}
+	}
+
+	return ctx
+}
+
+// Fields returns the union of the member propagator fields.
+func (p fallthroughPropagator) Fields() []string {
+	unique := make(map[string]struct{})
+
+	var fields []string
+
+	for _, propagator := range p {
+		for _, field := range propagator.Fields() {
+			if _, ok := unique[field]; ok {
+				continue
+			}
+
+			unique[field] = struct{}{}
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,220 @@
+package ttrace
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestSpanContext returns a sampled span context with fixed identifiers.
+func newTestSpanContext() trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func TestNewPropagatorRoundTrip(t *testing.T) {
+	// OT trace IDs carry only the low 64 bits of the trace ID.
+	tests := []struct {
+		name      string
+		header    string
+		truncated bool
+	}{
+		{PropagatorTraceContext, "traceparent", false},
+		{PropagatorB3, "b3", false},
+		{PropagatorB3Multi, "x-b3-traceid", false},
+		{PropagatorJaeger, "uber-trace-id", false},
+		{PropagatorXRay, "X-Amzn-Trace-Id", false},
+		{PropagatorOTTrace, "ot-tracer-traceid", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			propagator, err := NewPropagator(" " + strings.ToUpper(tt.name) + " ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := newTestSpanContext()
+			wantTraceID := want.TraceID()
+			if tt.truncated {
+				clear(wantTraceID[:8])
+			}
+
+			carrier := propagation.MapCarrier{}
+			propagator.Inject(trace.ContextWithSpanContext(context.Background(), want), carrier)
+
+			if carrier.Get(tt.header) == "" {
+				t.Fatalf("got headers %v, want %s", carrier, tt.header)
+			}
+
+			got := trace.SpanContextFromContext(propagator.Extract(context.Background(), carrier))
+			if got.TraceID() != wantTraceID || got.SpanID() != want.SpanID() || !got.IsSampled() || !got.IsRemote() {
+				t.Errorf("got span context %v/%v sampled %t remote %t, want %v/%v sampled and remote",
+					got.TraceID(), got.SpanID(), got.IsSampled(), got.IsRemote(), wantTraceID, want.SpanID())
+			}
+		})
+	}
+}
+
+func TestNewPropagatorBaggage(t *testing.T) {
+	propagator, err := NewPropagator(PropagatorBaggage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(baggage.ContextWithBaggage(context.Background(), bag), carrier)
+
+	ctx := propagator.Extract(context.Background(), carrier)
+
+	got := baggage.FromContext(ctx).Member("tenant").Value()
+	if got != "acme" {
+		t.Errorf("got baggage tenant %q, want acme", got)
+	}
+}
+
+func TestNewPropagatorNone(t *testing.T) {
+	propagator, err := NewPropagator(PropagatorNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), newTestSpanContext()), carrier)
+
+	if len(carrier) != 0 {
+		t.Errorf("got headers %v, want none", carrier)
+	}
+
+	carrier = propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+
+	got := trace.SpanContextFromContext(propagator.Extract(context.Background(), carrier))
+	if got.IsValid() {
+		t.Errorf("got span context %v, want none", got)
+	}
+}
+
+func TestNewPropagatorFallsThrough(t *testing.T) {
+	const (
+		traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		b3Header    = "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"
+	)
+
+	propagator, err := NewPropagator(PropagatorB3, PropagatorTraceContext, PropagatorBaggage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		carrier propagation.MapCarrier
+		want    string
+	}{
+		{
+			name:    "first format",
+			carrier: propagation.MapCarrier{"b3": b3Header},
+			want:    "80f198ee56343ba864fe8b2a57d3eff7",
+		},
+		{
+			name:    "later format",
+			carrier: propagation.MapCarrier{"traceparent": traceparent},
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "first format wins",
+			carrier: propagation.MapCarrier{"b3": b3Header, "traceparent": traceparent},
+			want:    "80f198ee56343ba864fe8b2a57d3eff7",
+		},
+		{
+			name:    "invalid first format",
+			carrier: propagation.MapCarrier{"b3": "invalid", "traceparent": traceparent},
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.carrier["baggage"] = "tenant=acme"
+
+			ctx := propagator.Extract(context.Background(), tt.carrier)
+
+			got := trace.SpanContextFromContext(ctx).TraceID().String()
+			if got != tt.want {
+				t.Errorf("got trace ID %s, want %s", got, tt.want)
+			}
+
+			tenant := baggage.FromContext(ctx).Member("tenant").Value()
+			if tenant != "acme" {
+				t.Errorf("got baggage tenant %q, want acme", tenant)
+			}
+		})
+	}
+}
+
+func TestNewPropagatorInjectsEveryFormat(t *testing.T) {
+	propagator, err := NewPropagator(PropagatorTraceContext, PropagatorB3Multi, PropagatorJaeger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), newTestSpanContext()), carrier)
+
+	for _, header := range []string{"traceparent", "x-b3-traceid", "uber-trace-id"} {
+		if carrier.Get(header) == "" {
+			t.Errorf("got headers %v, want %s", carrier, header)
+		}
+	}
+
+	fields := propagator.Fields()
+	for _, field := range []string{"traceparent", "x-b3-traceid", "uber-trace-id"} {
+		if !slices.Contains(fields, field) {
+			t.Errorf("got fields %v, want %s", fields, field)
+		}
+	}
+}
+
+func TestNewPropagatorErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  string
+	}{
+		{"unknown name", []string{PropagatorTraceContext, "zipkin"}, `unsupported propagator "zipkin"`},
+		{"none combined", []string{PropagatorNone, PropagatorB3}, "cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPropagator(tt.names...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPropagatorsReportsUnknownName(t *testing.T) {
+	_, err := New(context.Background(), WithPropagators("zipkin"))
+	if err == nil {
+		t.Error("got nil error, want unsupported propagator")
+	}
+}
@@ -1,6 +1,7 @@
 package ttrace
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sync"
@@ -18,9 +19,11 @@ type RateLimiter interface {
 }
 
 // ReconfigurableRateLimiter implements a leaky-bucket rate limiter expressed in abstract credits.
-// On each [ReconfigurableRateLimiter.CheckCredit] call, the balance refills in proportion to
-// elapsed time at a rate of creditsPerSecond, up to maxBalance. CheckCredit deducts itemCost when
-// sufficient balance is available and reports whether the deduction succeeded.
+// The balance refills in proportion to elapsed time at a rate of creditsPerSecond, up to
+// maxBalance, which is the burst size and is independent of the rate. [ReconfigurableRateLimiter.CheckCredit]
+// deducts itemCost when sufficient balance is available and reports whether the deduction
+// succeeded; [ReconfigurableRateLimiter.Reserve] and [ReconfigurableRateLimiter.Wait] deduct it in
+// advance and report or wait for the delay until the balance covers it.
 //
 // Typical uses include limiting events per second (for example, calling CheckCredit(1.0) per
 // message) or bytes per second (treat creditsPerSecond as throughput and pass message size as
@@ -29,23 +32,42 @@ type ReconfigurableRateLimiter struct {
 	lock sync.Mutex
 
 	creditsPerSecond float64
-	balance          float64
-	maxBalance       float64
-	lastTick         time.Time
+	// balance is negative while reservations are outstanding.
+	balance    float64
+	maxBalance float64
+	lastTick   time.Time
 
 	timeNow func() time.Time
 }
 
+// RateLimiterOption configures [NewRateLimiter].
+type RateLimiterOption func(rl *ReconfigurableRateLimiter)
+
+// WithRateLimiterClock replaces [time.Now] as the clock of the limiter, for example to make tests
+// deterministic.
+func WithRateLimiterClock(timeNow func() time.Time) RateLimiterOption {
+	return func(rl *ReconfigurableRateLimiter) {
+		rl.timeNow = timeNow
+	}
+}
+
 // NewRateLimiter constructs a [ReconfigurableRateLimiter] with the specified refill rate and maximum
-// balance.
-func NewRateLimiter(creditsPerSecond, maxBalance float64) *ReconfigurableRateLimiter {
-	return &ReconfigurableRateLimiter{
+// balance (burst size). The limiter starts with a full balance.
+func NewRateLimiter(creditsPerSecond, maxBalance float64, opts ...RateLimiterOption) *ReconfigurableRateLimiter {
+	rl := &ReconfigurableRateLimiter{
 		creditsPerSecond: creditsPerSecond,
 		balance:          maxBalance,
 		maxBalance:       maxBalance,
-		lastTick:         time.Now(),
 		timeNow:          time.Now,
 	}
+
+	for _, opt := range opts {
+		opt(rl)
+	}
+
+	rl.lastTick = rl.timeNow()
+
+	return rl
 }
 
 // CheckCredit attempts to deduct itemCost from the current balance and reports whether the
@@ -68,11 +90,106 @@ func (rl *ReconfigurableRateLimiter) CheckCredit(itemCost float64) bool {
 	return false
 }
 
+// Reserve deducts itemCost now and returns how long the caller must wait before the balance covers
+// it; zero means the credit is available immediately. It returns false, deducting nothing, when
+// itemCost exceeds the burst size or cannot be covered because the rate is zero.
+func (rl *ReconfigurableRateLimiter) Reserve(itemCost float64) (time.Duration, bool) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if itemCost > rl.maxBalance {
+		return 0, false
+	}
+
+	rl.updateBalance()
+
+	balance := rl.balance - itemCost
+
+	var delay time.Duration
+
+	if balance < 0 {
+		if rl.creditsPerSecond <= 0 {
+			return 0, false
+		}
+
+		delay = time.Duration(-balance / rl.creditsPerSecond * float64(time.Second))
+	}
+
+	rl.balance = balance
+
+	return delay, true
+}
+
+// Wait blocks until itemCost credits are available and deducts them. It returns an error when
+// itemCost can never be satisfied, see [ReconfigurableRateLimiter.Reserve], or when ctx is done
+// first, in which case the reserved credits are returned to the balance.
+func (rl *ReconfigurableRateLimiter) Wait(ctx context.Context, itemCost float64) error {
+	delay, ok := rl.Reserve(itemCost)
+	if !ok {
+		return fmt.Errorf("ttrace: rate limiter cannot admit cost %v (burst %v, rate %v)", itemCost, rl.Burst(), rl.Rate())
+	}
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		rl.refund(itemCost)
+
+		return ctx.Err()
+	}
+}
+
+// refund returns itemCost credits to the balance, up to the burst size.
+func (rl *ReconfigurableRateLimiter) refund(itemCost float64) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.balance = math.Min(rl.balance+itemCost, rl.maxBalance)
+}
+
+// Balance returns the credits currently available. It is negative while reservations made by
+// [ReconfigurableRateLimiter.Reserve] are outstanding.
+func (rl *ReconfigurableRateLimiter) Balance() float64 {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.updateBalance()
+
+	return rl.balance
+}
+
+// Rate returns the refill rate in credits per second.
+func (rl *ReconfigurableRateLimiter) Rate() float64 {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	return rl.creditsPerSecond
+}
+
+// Burst returns the maximum balance.
+func (rl *ReconfigurableRateLimiter) Burst() float64 {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	return rl.maxBalance
+}
+
 // updateBalance accrues credits based on elapsed time since the last tick. rl.lock must be held.
 func (rl *ReconfigurableRateLimiter) updateBalance() {
-	// calculate how much time passed since the last tick, and update current tick
+	// calculate how much time passed since the last tick, and update current tick; a clock that
+	// goes backwards accrues nothing
 	currentTime := rl.timeNow()
 	elapsedTime := currentTime.Sub(rl.lastTick)
+	if elapsedTime <= 0 {
+		return
+	}
 	rl.lastTick = currentTime
 	// calculate how much credit have we accumulated since the last tick
 	rl.balance += elapsedTime.Seconds() * rl.creditsPerSecond
@@ -101,6 +218,7 @@ func (rl *ReconfigurableRateLimiter) Update(creditsPerSecond, maxBalance float64
 type rateLimitingSampler struct {
 	maxTracesPerSecond float64
 	rateLimiter        *ReconfigurableRateLimiter
+	counters           *samplingCounters
 }
 
 // init ensures the internal limiter matches maxTracesPerSecond and updates s.maxTracesPerSecond.
@@ -122,11 +240,22 @@ func (s *rateLimitingSampler) Description() string {
 // ShouldSample returns RecordAndSample when the rate limiter grants one credit for this decision, otherwise Drop.
 func (s *rateLimitingSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
 	if s.rateLimiter.CheckCredit(1.0) {
+		s.counters.record(p.ParentContext, SamplingDecisionSampled)
 		return trace.SamplingResult{Decision: trace.RecordAndSample}
 	}
+	s.counters.record(p.ParentContext, SamplingDecisionDroppedByRateLimit)
 	return trace.SamplingResult{Decision: trace.Drop}
 }
 
+// Stats returns the decision counts when the sampler was built with counters.
+func (s *rateLimitingSampler) Stats() SamplingStats {
+	if s.counters == nil {
+		return SamplingStats{}
+	}
+
+	return s.counters.stats()
+}
+
 // newRateLimitingRootSampler returns the root sampler that enforces the per-second rate limit.
 func newRateLimitingRootSampler(maxTracesPerSecond float64) trace.Sampler {
 	s := new(rateLimitingSampler)
@@ -134,45 +263,69 @@ func newRateLimitingRootSampler(maxTracesPerSecond float64) trace.Sampler {
 }
 
 // RateLimitingSampler returns a parent-based [trace.Sampler] that limits sampled root traces to
-// approximately maxTracesPerSecond per second.
+// approximately maxTracesPerSecond per second. Its decision counts are available through
+// [SamplerStats] and [SamplingDecisionsMetric].
 func RateLimitingSampler(maxTracesPerSecond float64) trace.Sampler {
-	return trace.ParentBased(newRateLimitingRootSampler(maxTracesPerSecond))
+	s := new(rateLimitingSampler)
+	s.counters = newSamplingCounters("RateLimitingSampler")
+	return newStatsParentBased(s.init(maxTracesPerSecond), s.counters)
 }
 
 // guaranteedThroughputProbabilitySampler applies trace ID ratio sampling, then a per-second rate limit.
 type guaranteedThroughputProbabilitySampler struct {
+	fraction            float64
+	maxTracesPerSecond  float64
 	probabilitySampler  trace.Sampler
-	rateLimitingSampler trace.Sampler
+	rateLimitingSampler *rateLimitingSampler
+	counters            *samplingCounters
 }
 
 // newGuaranteedThroughputProbabilityRootSampler returns the root sampler that applies ratio sampling
 // before the per-second throughput cap.
-func newGuaranteedThroughputProbabilityRootSampler(fraction float64, maxTracesPerSecond float64) trace.Sampler {
+func newGuaranteedThroughputProbabilityRootSampler(fraction float64, maxTracesPerSecond float64) *guaranteedThroughputProbabilitySampler {
 	return &guaranteedThroughputProbabilitySampler{
+		fraction:            fraction,
+		maxTracesPerSecond:  maxTracesPerSecond,
 		probabilitySampler:  trace.TraceIDRatioBased(fraction),
-		rateLimitingSampler: newRateLimitingRootSampler(maxTracesPerSecond),
+		rateLimitingSampler: new(rateLimitingSampler).init(maxTracesPerSecond),
+		counters:            newSamplingCounters("GuaranteedThroughputProbabilitySampler"),
 	}
 }
 
 // GuaranteedThroughputProbabilitySampler returns a parent-based [trace.Sampler] that first applies
 // [trace.TraceIDRatioBased] to root traces and then enforces a throughput cap of approximately
-// maxTracesPerSecond sampled root traces per second.
+// maxTracesPerSecond sampled root traces per second. Its decision counts, per stage, are available
+// through [SamplerStats] and [SamplingDecisionsMetric].
 func GuaranteedThroughputProbabilitySampler(fraction float64, maxTracesPerSecond float64) trace.Sampler {
-	return trace.ParentBased(newGuaranteedThroughputProbabilityRootSampler(fraction, maxTracesPerSecond))
+	s := newGuaranteedThroughputProbabilityRootSampler(fraction, maxTracesPerSecond)
+	return newStatsParentBased(s, s.counters)
 }
 
 // ShouldSample returns the probability sampler result when that stage drops the span; otherwise it
 // delegates to the rate-limiting sampler.
-func (s guaranteedThroughputProbabilitySampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+func (s *guaranteedThroughputProbabilitySampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
 	samplingResult := s.probabilitySampler.ShouldSample(p)
 	if samplingResult.Decision == trace.Drop {
+		s.counters.record(p.ParentContext, SamplingDecisionDroppedByRatio)
 		return samplingResult
 	}
 
-	return s.rateLimitingSampler.ShouldSample(p)
+	samplingResult = s.rateLimitingSampler.ShouldSample(p)
+	if samplingResult.Decision == trace.Drop {
+		s.counters.record(p.ParentContext, SamplingDecisionDroppedByRateLimit)
+	} else {
+		s.counters.record(p.ParentContext, SamplingDecisionSampled)
+	}
+
+	return samplingResult
+}
+
+// Description reports the configured sampling fraction and per-second cap.
+func (s *guaranteedThroughputProbabilitySampler) Description() string {
+	return fmt.Sprintf("GuaranteedThroughputProbabilitySampler(samplingFraction=%v,maxTracesPerSecond=%v)", s.fraction, s.maxTracesPerSecond)
 }
 
-// Description returns the constant string "GuaranteedThroughputProbabilitySampler".
-func (s guaranteedThroughputProbabilitySampler) Description() string {
-	return "GuaranteedThroughputProbabilitySampler"
+// Stats returns the decision counts per stage.
+func (s *guaranteedThroughputProbabilitySampler) Stats() SamplingStats {
+	return s.counters.stats()
 }
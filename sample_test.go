@@ -0,0 +1,178 @@
+package ttrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefillsOverTime(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(2, 2, WithRateLimiterClock(clock.Now))
+
+	tests := []struct {
+		advance time.Duration
+		want    []bool
+	}{
+		{advance: 0, want: []bool{true, true, false}},
+		{advance: 500 * time.Millisecond, want: []bool{true, false}},
+		{advance: 250 * time.Millisecond, want: []bool{false}},
+		{advance: 250 * time.Millisecond, want: []bool{true, false}},
+	}
+
+	for i, tt := range tests {
+		clock.Advance(tt.advance)
+
+		for j, want := range tt.want {
+			got := limiter.CheckCredit(1)
+			if got != want {
+				t.Errorf("step %d, call %d: CheckCredit(1) = %t, want %t", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestRateLimiterCapsBalanceAtBurst(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(10, 3, WithRateLimiterClock(clock.Now))
+
+	for limiter.CheckCredit(1) {
+	}
+
+	clock.Advance(time.Minute)
+
+	got := limiter.Balance()
+	if got != 3 {
+		t.Errorf("Balance() after a minute = %v, want the burst size 3", got)
+	}
+
+	admitted := 0
+	for limiter.CheckCredit(1) {
+		admitted++
+	}
+
+	if admitted != 3 {
+		t.Errorf("admitted %d after a minute, want the burst size 3", admitted)
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(2, 2, WithRateLimiterClock(clock.Now))
+
+	tests := []struct {
+		cost        float64
+		wantDelay   time.Duration
+		wantOK      bool
+		wantBalance float64
+	}{
+		{cost: 1, wantDelay: 0, wantOK: true, wantBalance: 1},
+		{cost: 2, wantDelay: 500 * time.Millisecond, wantOK: true, wantBalance: -1},
+		{cost: 3, wantOK: false, wantBalance: -1},
+	}
+
+	for _, tt := range tests {
+		delay, ok := limiter.Reserve(tt.cost)
+		if delay != tt.wantDelay || ok != tt.wantOK {
+			t.Errorf("Reserve(%v) = %v, %t; want %v, %t", tt.cost, delay, ok, tt.wantDelay, tt.wantOK)
+		}
+
+		got := limiter.Balance()
+		if got != tt.wantBalance {
+			t.Errorf("Balance() after Reserve(%v) = %v, want %v", tt.cost, got, tt.wantBalance)
+		}
+	}
+
+	// The outstanding reservation is paid off before new credit is available.
+	clock.Advance(500 * time.Millisecond)
+
+	if limiter.CheckCredit(1) {
+		t.Error("CheckCredit(1) succeeded while the reservation was being paid off")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+
+	if !limiter.CheckCredit(1) {
+		t.Error("CheckCredit(1) failed after the reservation was paid off")
+	}
+}
+
+func TestRateLimiterReserveWithZeroRate(t *testing.T) {
+	limiter := NewRateLimiter(0, 1, WithRateLimiterClock(newFakeClock().Now))
+
+	if !limiter.CheckCredit(1) {
+		t.Fatal("CheckCredit(1) failed on a full balance")
+	}
+
+	_, ok := limiter.Reserve(1)
+	if ok {
+		t.Error("Reserve(1) with rate 0 and no balance succeeded, want false")
+	}
+
+	if limiter.Balance() != 0 {
+		t.Errorf("Balance() = %v after a rejected reservation, want 0", limiter.Balance())
+	}
+}
+
+func TestRateLimiterWaitRefundsOnCancel(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(1, 1, WithRateLimiterClock(clock.Now))
+
+	err := limiter.Wait(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Wait on a full balance: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The fake clock never advances, so the wait can only end through the context.
+	err = limiter.Wait(ctx, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+
+	if limiter.Balance() != 0 {
+		t.Errorf("Balance() = %v after the cancelled wait, want the reservation refunded to 0", limiter.Balance())
+	}
+
+	err = limiter.Wait(context.Background(), 2)
+	if err == nil {
+		t.Error("Wait for more than the burst size succeeded, want error")
+	}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(2, 2, WithRateLimiterClock(clock.Now))
+
+	limiter.CheckCredit(1)
+	limiter.Update(8, 4)
+
+	if limiter.Rate() != 8 || limiter.Burst() != 4 {
+		t.Errorf("Rate(), Burst() = %v, %v; want 8, 4", limiter.Rate(), limiter.Burst())
+	}
+
+	// The balance keeps its share of the burst size.
+	if limiter.Balance() != 2 {
+		t.Errorf("Balance() = %v after doubling the burst size from half full, want 2", limiter.Balance())
+	}
+
+	clock.Advance(125 * time.Millisecond)
+
+	if limiter.Balance() != 3 {
+		t.Errorf("Balance() = %v after 125ms at the new rate, want 3", limiter.Balance())
+	}
+}
+
+func TestRateLimiterCheckCreditDoesNotAllocate(t *testing.T) {
+	limiter := NewRateLimiter(1e9, 1e9)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		limiter.CheckCredit(1)
+	})
+	if allocs != 0 {
+		t.Errorf("CheckCredit allocates %v times per call, want 0", allocs)
+	}
+}
@@ -0,0 +1,277 @@
+package ttrace
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Defaults applied by [NewAdaptiveSampler] to zero-valued [AdaptiveSamplingConfig] fields.
+const (
+	defaultAdaptiveWindow    = 10 * time.Second
+	defaultAdaptiveBuckets   = 10
+	defaultAdaptiveSmoothing = 0.3
+)
+
+// AdaptiveSamplingConfig configures an [AdaptiveSampler].
+type AdaptiveSamplingConfig struct {
+	// TargetTracesPerSecond is the root-trace throughput the sampler aims for. It must be positive.
+	TargetTracesPerSecond float64
+	// MinSamplingFraction and MaxSamplingFraction bound the adjusted probability. Zero values mean
+	// 0 and 1.
+	MinSamplingFraction float64
+	MaxSamplingFraction float64
+	// InitialSamplingFraction is used until the first window bucket completes. Zero means
+	// MaxSamplingFraction.
+	InitialSamplingFraction float64
+	// LowerBoundTracesPerSecond guarantees a minimum sampling rate: a root span that the
+	// probability stage rejected is still sampled while this budget allows it. Spans the
+	// probability stage accepts also consume the budget, so the floor only tops up the rate.
+	// Zero disables the floor.
+	LowerBoundTracesPerSecond float64
+	// Window is the sliding window over which the incoming root-span rate is measured. Zero means
+	// ten seconds.
+	Window time.Duration
+	// Buckets is the number of sub-intervals the window slides by. Zero means 10.
+	Buckets int
+	// Smoothing is the weight, in (0, 1], of each new probability estimate in the exponentially
+	// weighted moving average of the probability. Zero means 0.3; 1 disables smoothing.
+	Smoothing float64
+}
+
+// withDefaults returns c with zero-valued fields replaced by their defaults.
+func (c AdaptiveSamplingConfig) withDefaults() AdaptiveSamplingConfig {
+	if c.MaxSamplingFraction == 0 {
+		c.MaxSamplingFraction = 1
+	}
+
+	if c.InitialSamplingFraction == 0 {
+		c.InitialSamplingFraction = c.MaxSamplingFraction
+	}
+
+	if c.Window == 0 {
+		c.Window = defaultAdaptiveWindow
+	}
+
+	if c.Buckets == 0 {
+		c.Buckets = defaultAdaptiveBuckets
+	}
+
+	if c.Smoothing == 0 {
+		c.Smoothing = defaultAdaptiveSmoothing
+	}
+
+	return c
+}
+
+// validate reports an error when c, after defaults, contains out-of-range values.
+func (c AdaptiveSamplingConfig) validate() error {
+	if !(c.TargetTracesPerSecond > 0) {
+		return fmt.Errorf("ttrace: invalid adaptive target traces per second: must be > 0 (got %v)", c.TargetTracesPerSecond)
+	}
+
+	if c.MinSamplingFraction < 0 || c.MaxSamplingFraction > 1 || c.MinSamplingFraction > c.MaxSamplingFraction {
+		return fmt.Errorf("ttrace: invalid adaptive sampling bounds: want 0 <= min <= max <= 1 (got %v, %v)", c.MinSamplingFraction, c.MaxSamplingFraction)
+	}
+
+	if c.InitialSamplingFraction < c.MinSamplingFraction || c.InitialSamplingFraction > c.MaxSamplingFraction {
+		return fmt.Errorf("ttrace: invalid adaptive initial sampling fraction: must be within bounds (got %v)", c.InitialSamplingFraction)
+	}
+
+	if c.LowerBoundTracesPerSecond < 0 {
+		return fmt.Errorf("ttrace: invalid adaptive lower bound: must be >= 0 (got %v)", c.LowerBoundTracesPerSecond)
+	}
+
+	if c.Window < 0 || c.Buckets < 1 || c.Window/time.Duration(c.Buckets) <= 0 {
+		return fmt.Errorf("ttrace: invalid adaptive window %v with %d buckets", c.Window, c.Buckets)
+	}
+
+	if c.Smoothing < 0 || c.Smoothing > 1 {
+		return fmt.Errorf("ttrace: invalid adaptive smoothing: must be in (0, 1] (got %v)", c.Smoothing)
+	}
+
+	return nil
+}
+
+// AdaptiveSampler is a root [trace.Sampler] that measures the incoming root-span rate over a
+// sliding window and continuously adjusts its trace ID ratio so that sampled traces approach a
+// target throughput. Each time a window bucket completes, the probability moves toward
+// target/observed rate by the smoothing weight and is clamped to the configured bounds. Unlike the
+// cap of [GuaranteedThroughputProbabilitySampler], the optional lower bound guarantees a minimum
+// rate when the probability is low. Wrap it with [trace.ParentBased] so that child spans inherit
+// their parent decision.
+type AdaptiveSampler struct {
+	lock sync.Mutex
+
+	config AdaptiveSamplingConfig
+
+	buckets        []uint64
+	bucketDuration time.Duration
+	bucketStart    time.Time
+	current        int
+	completed      int
+
+	observedRate       float64
+	probability        float64
+	probabilitySampler trace.Sampler
+	lowerBoundLimiter  *ReconfigurableRateLimiter
+	counters           *samplingCounters
+
+	timeNow func() time.Time
+}
+
+// NewAdaptiveSampler returns an [AdaptiveSampler] for config. It returns an error when config
+// contains out-of-range values.
+func NewAdaptiveSampler(config AdaptiveSamplingConfig) (*AdaptiveSampler, error) {
+	return newAdaptiveSampler(config, time.Now)
+}
+
+// newAdaptiveSampler is [NewAdaptiveSampler] with an injectable clock.
+func newAdaptiveSampler(config AdaptiveSamplingConfig, timeNow func() time.Time) (*AdaptiveSampler, error) {
+	config = config.withDefaults()
+
+	err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &AdaptiveSampler{
+		config: config,
+		// One extra slot holds the bucket being filled, so that a full window of completed
+		// buckets is always available.
+		buckets:        make([]uint64, config.Buckets+1),
+		bucketDuration: config.Window / time.Duration(config.Buckets),
+		bucketStart:    timeNow(),
+		counters:       newSamplingCounters("AdaptiveSampler"),
+		timeNow:        timeNow,
+	}
+
+	s.setProbability(config.InitialSamplingFraction)
+	s.lowerBoundLimiter = updateRateLimiter(nil, config.LowerBoundTracesPerSecond, config.LowerBoundTracesPerSecond > 0, WithRateLimiterClock(timeNow))
+
+	return s, nil
+}
+
+// ShouldSample counts the root span, applies the current probability, and falls back to the
+// lower-bound budget for rejected spans. Accepted spans are debited from the lower-bound budget
+// too, so that the floor does not add to the probability stage.
+func (s *AdaptiveSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	s.lock.Lock()
+	s.advance(s.timeNow())
+	s.buckets[s.current]++
+	probabilitySampler := s.probabilitySampler
+	s.lock.Unlock()
+
+	traceState := oteltrace.SpanContextFromContext(p.ParentContext).TraceState()
+
+	sampled := probabilitySampler.ShouldSample(p).Decision == trace.RecordAndSample
+
+	if s.lowerBoundLimiter != nil {
+		// The debit result only matters for rejected spans; accepted ones merely consume credit.
+		credited := s.lowerBoundLimiter.CheckCredit(1.0)
+		sampled = sampled || credited
+	}
+
+	if !sampled {
+		s.counters.record(p.ParentContext, SamplingDecisionDroppedByRatio)
+
+		return trace.SamplingResult{
+			Decision:   trace.Drop,
+			Tracestate: traceState,
+		}
+	}
+
+	s.counters.record(p.ParentContext, SamplingDecisionSampled)
+
+	return trace.SamplingResult{
+		Decision:   trace.RecordAndSample,
+		Tracestate: traceState,
+	}
+}
+
+// Stats returns the decision counts of the sampler. Root spans rejected by the probability stage
+// and the lower bound are counted as dropped by ratio. Spans that inherited their parent decision
+// are counted when the sampler is installed by [WithAdaptiveSampling].
+func (s *AdaptiveSampler) Stats() SamplingStats {
+	return s.counters.stats()
+}
+
+// Probability returns the current sampling probability.
+func (s *AdaptiveSampler) Probability() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.probability
+}
+
+// ObservedRate returns the incoming root spans per second measured over the last completed
+// window buckets.
+func (s *AdaptiveSampler) ObservedRate() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.observedRate
+}
+
+// Description reports the target, bounds, and current probability.
+func (s *AdaptiveSampler) Description() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return fmt.Sprintf("AdaptiveSampler(targetTracesPerSecond=%v,minSamplingFraction=%v,maxSamplingFraction=%v,lowerBoundTracesPerSecond=%v,probability=%v)",
+		s.config.TargetTracesPerSecond, s.config.MinSamplingFraction, s.config.MaxSamplingFraction, s.config.LowerBoundTracesPerSecond, s.probability)
+}
+
+// advance rotates the window to now and, when at least one bucket completed, re-estimates the
+// probability. s.lock must be held.
+func (s *AdaptiveSampler) advance(now time.Time) {
+	elapsed := int64(now.Sub(s.bucketStart) / s.bucketDuration)
+	if elapsed <= 0 {
+		return
+	}
+
+	s.bucketStart = s.bucketStart.Add(time.Duration(elapsed) * s.bucketDuration)
+
+	for i := int64(0); i < elapsed && i < int64(len(s.buckets)); i++ {
+		s.current = (s.current + 1) % len(s.buckets)
+		s.buckets[s.current] = 0
+	}
+
+	s.completed = int(min(int64(s.completed)+elapsed, int64(s.config.Buckets)))
+
+	s.adjust()
+}
+
+// adjust recomputes the observed rate from the completed buckets and moves the probability toward
+// the target. s.lock must be held.
+func (s *AdaptiveSampler) adjust() {
+	var total uint64
+
+	for i := 1; i <= s.completed; i++ {
+		total += s.buckets[(s.current-i+len(s.buckets))%len(s.buckets)]
+	}
+
+	s.observedRate = float64(total) / (time.Duration(s.completed) * s.bucketDuration).Seconds()
+
+	desired := s.config.MaxSamplingFraction
+	if s.observedRate > 0 {
+		desired = s.config.TargetTracesPerSecond / s.observedRate
+	}
+
+	probability := s.probability + s.config.Smoothing*(desired-s.probability)
+
+	s.setProbability(probability)
+}
+
+// setProbability clamps probability to the configured bounds and installs it. s.lock must be held
+// when the sampler is in use.
+func (s *AdaptiveSampler) setProbability(probability float64) {
+	probability = math.Min(math.Max(probability, s.config.MinSamplingFraction), s.config.MaxSamplingFraction)
+
+	s.probability = probability
+	s.probabilitySampler = trace.TraceIDRatioBased(probability)
+}
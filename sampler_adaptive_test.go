@@ -0,0 +1,122 @@
+package ttrace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAdaptiveSamplerLowerBoundIsNotAdditive(t *testing.T) {
+	clock := newFakeClock()
+
+	// A single hour-long bucket keeps the probability fixed while the clock advances.
+	sampler, err := newAdaptiveSampler(AdaptiveSamplingConfig{
+		TargetTracesPerSecond:     10,
+		LowerBoundTracesPerSecond: 2,
+		Window:                    time.Hour,
+		Buckets:                   1,
+	}, clock.Now)
+	if err != nil {
+		t.Fatalf("newAdaptiveSampler: %v", err)
+	}
+
+	got := countSampled(sampler, "op", 5)
+	if got != 5 {
+		t.Fatalf("probability 1: sampled %d of 5, want 5", got)
+	}
+
+	sampler.lock.Lock()
+	sampler.setProbability(0)
+	sampler.lock.Unlock()
+
+	// The five sampled spans used up the floor budget of this second.
+	got = countSampled(sampler, "op", 100)
+	if got != 0 {
+		t.Errorf("probability 0 after sampled spans: sampled %d of 100, want 0", got)
+	}
+
+	clock.Advance(time.Second)
+
+	got = countSampled(sampler, "op", 100)
+	if got != 2 {
+		t.Errorf("probability 0 in the next second: sampled %d of 100, want 2", got)
+	}
+
+	want := SamplingStats{Sampled: 7, DroppedByRatio: 198}
+
+	stats := sampler.Stats()
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestAdaptiveSamplerConvergesToTarget(t *testing.T) {
+	clock := newFakeClock()
+
+	sampler, err := newAdaptiveSampler(AdaptiveSamplingConfig{
+		TargetTracesPerSecond: 10,
+		Window:                10 * time.Second,
+		Buckets:               10,
+		Smoothing:             1,
+	}, clock.Now)
+	if err != nil {
+		t.Fatalf("newAdaptiveSampler: %v", err)
+	}
+
+	for range 20 {
+		countSampled(sampler, "op", 1000)
+		clock.Advance(time.Second)
+	}
+
+	countSampled(sampler, "op", 1)
+
+	probability := sampler.Probability()
+	if probability < 0.0099 || probability > 0.0101 {
+		t.Errorf("Probability() = %v at 1000 spans/s, want 0.01", probability)
+	}
+
+	rate := sampler.ObservedRate()
+	if rate < 999 || rate > 1001 {
+		t.Errorf("ObservedRate() = %v, want 1000", rate)
+	}
+}
+
+func TestHandleExposesAdaptiveSampler(t *testing.T) {
+	handle, err := New(context.Background(),
+		WithExporter(tracetest.NewInMemoryExporter()),
+		WithAdaptiveSampling(AdaptiveSamplingConfig{TargetTracesPerSecond: 1000}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	if handle.AdaptiveSampler() == nil {
+		t.Fatal("AdaptiveSampler() is nil with adaptive sampling")
+	}
+
+	if handle.Sampler() != nil {
+		t.Error("Sampler() is not nil with adaptive sampling")
+	}
+
+	tracer := handle.TracerProvider().Tracer("test")
+
+	for range 3 {
+		ctx, root := tracer.Start(context.Background(), "root")
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+		root.End()
+	}
+
+	stats, ok := handle.SamplingStats()
+	if !ok {
+		t.Fatal("SamplingStats reported no stats with adaptive sampling")
+	}
+
+	want := SamplingStats{Sampled: 3, InheritedFromParent: 3}
+	if stats != want {
+		t.Errorf("SamplingStats() = %+v, want %+v", stats, want)
+	}
+}
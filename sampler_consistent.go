@@ -0,0 +1,323 @@
+package ttrace
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes recorded by [ConsistentSampler] on sampled spans whose probability is known.
+const (
+	// SamplingThresholdKey holds the OpenTelemetry rejection threshold (the tracestate ot th value)
+	// that the span was sampled with.
+	SamplingThresholdKey = attribute.Key("sampling.threshold")
+	// SamplingAdjustedCountKey holds the number of spans the sampled span represents, the inverse of
+	// its sampling probability.
+	SamplingAdjustedCountKey = attribute.Key("sampling.adjusted_count")
+)
+
+// Constants of the OpenTelemetry consistent probability sampling scheme (OTEP 235).
+const (
+	// otelTraceStateKey is the tracestate key of the OpenTelemetry entry.
+	otelTraceStateKey = "ot"
+	// maxThreshold is 2^56, the exclusive upper bound of thresholds and randomness values.
+	maxThreshold = uint64(1) << 56
+	// randomnessMask selects the 56 randomness bits of a trace ID.
+	randomnessMask = maxThreshold - 1
+	// thresholdDigits is the hex digit count of an untrimmed threshold and of an rv value.
+	thresholdDigits = 14
+)
+
+// ConsistentSampler samples by comparing the 56-bit trace randomness (the tracestate rv value, or
+// the low 56 bits of the trace ID) with a rejection threshold derived from the sampling fraction,
+// following OpenTelemetry consistent probability sampling (OTEP 235). Sampled root spans write the
+// threshold to the W3C tracestate as ot=th:<hex>, so every service and the backend know the
+// probability of the trace, and carry [SamplingThresholdKey] and [SamplingAdjustedCountKey] for
+// span-count extrapolation.
+//
+// Root spans without explicit randomness draw a fresh value and write it to the tracestate as
+// ot=rv:<hex>. The SDK does not set the W3C random trace flag, so downstream samplers cannot rely
+// on trace ID randomness, for example with a custom [trace.IDGenerator]; the explicit value lets
+// them decide consistently with this root.
+//
+// Child spans follow their parent decision. A sampled parent threshold is kept when it is
+// consistent with the trace randomness and erased otherwise. ConsistentSampler therefore replaces
+// [trace.ParentBased] and must not be wrapped with it. Because it has no rate-limiting stage, the
+// effective probability always equals the configured fraction.
+type ConsistentSampler struct {
+	state atomic.Pointer[consistentSamplerState]
+}
+
+// consistentSamplerState is the immutable configuration of a [ConsistentSampler].
+type consistentSamplerState struct {
+	fraction float64
+	// threshold is the rejection threshold; roots with randomness below it are dropped.
+	threshold uint64
+	// encoded is the th value written to the tracestate.
+	encoded string
+	// never is true when fraction is too small to be represented and nothing is sampled.
+	never bool
+}
+
+// NewConsistentSampler returns a [ConsistentSampler] that samples root traces with probability
+// fraction. It returns an error when fraction is outside [0, 1].
+func NewConsistentSampler(fraction float64) (*ConsistentSampler, error) {
+	s := &ConsistentSampler{}
+
+	err := s.Update(fraction)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Update replaces the sampling fraction for subsequent root spans. It returns an error, leaving
+// the configuration unchanged, when fraction is outside [0, 1].
+func (s *ConsistentSampler) Update(fraction float64) error {
+	if math.IsNaN(fraction) || fraction < 0 || fraction > 1 {
+		return fmt.Errorf("ttrace: invalid consistent sampling fraction: must be between 0 and 1 (got %v)", fraction)
+	}
+
+	state := &consistentSamplerState{
+		fraction: fraction,
+	}
+
+	// Thresholds are quantized to 2^-56; fractions below that cannot be represented.
+	rejected := math.Round((1 - fraction) * float64(maxThreshold))
+	if rejected >= float64(maxThreshold) {
+		state.never = true
+	} else {
+		state.threshold = uint64(rejected)
+		state.encoded = encodeThreshold(state.threshold)
+	}
+
+	s.state.Store(state)
+
+	return nil
+}
+
+// Fraction returns the configured sampling fraction.
+func (s *ConsistentSampler) Fraction() float64 {
+	return s.state.Load().fraction
+}
+
+// ShouldSample decides root spans by threshold and follows the parent decision otherwise.
+func (s *ConsistentSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	parent := oteltrace.SpanContextFromContext(p.ParentContext)
+	traceState := parent.TraceState()
+	values := parseOTelTraceState(traceState.Get(otelTraceStateKey))
+
+	randomness, ok := values.randomness()
+
+	if parent.IsValid() {
+		if !ok {
+			randomness = binary.BigEndian.Uint64(p.TraceID[8:]) & randomnessMask
+		}
+
+		return s.parentResult(parent, traceState, values, randomness)
+	}
+
+	if !ok {
+		randomness = rand.Uint64() & randomnessMask
+		values.set("rv", fmt.Sprintf("%0*x", thresholdDigits, randomness))
+	}
+
+	state := s.state.Load()
+
+	if state.never || randomness < state.threshold {
+		values.delete("th")
+
+		return trace.SamplingResult{
+			Decision:   trace.Drop,
+			Tracestate: values.apply(traceState),
+		}
+	}
+
+	values.set("th", state.encoded)
+
+	return trace.SamplingResult{
+		Decision:   trace.RecordAndSample,
+		Attributes: thresholdAttributes(state.encoded, state.threshold),
+		Tracestate: values.apply(traceState),
+	}
+}
+
+// parentResult follows the decision of a valid parent, keeping its threshold only when the trace
+// randomness is consistent with it.
+func (s *ConsistentSampler) parentResult(parent oteltrace.SpanContext, traceState oteltrace.TraceState, values *otelTraceStateValues, randomness uint64) trace.SamplingResult {
+	if !parent.IsSampled() {
+		return trace.SamplingResult{
+			Decision:   trace.Drop,
+			Tracestate: traceState,
+		}
+	}
+
+	encoded, ok := values.get("th")
+	if !ok {
+		return trace.SamplingResult{
+			Decision:   trace.RecordAndSample,
+			Tracestate: traceState,
+		}
+	}
+
+	threshold, err := decodeThreshold(encoded)
+	if err != nil || randomness < threshold {
+		values.delete("th")
+
+		return trace.SamplingResult{
+			Decision:   trace.RecordAndSample,
+			Tracestate: values.apply(traceState),
+		}
+	}
+
+	return trace.SamplingResult{
+		Decision:   trace.RecordAndSample,
+		Attributes: thresholdAttributes(encoded, threshold),
+		Tracestate: traceState,
+	}
+}
+
+// Description reports the fraction and the encoded threshold.
+func (s *ConsistentSampler) Description() string {
+	state := s.state.Load()
+
+	return fmt.Sprintf("ConsistentSampler{fraction=%v,th=%s}", state.fraction, state.encoded)
+}
+
+// thresholdAttributes returns the span attributes describing threshold.
+func thresholdAttributes(encoded string, threshold uint64) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		SamplingThresholdKey.String(encoded),
+		SamplingAdjustedCountKey.Float64(float64(maxThreshold) / float64(maxThreshold-threshold)),
+	}
+}
+
+// encodeThreshold formats threshold as 14 hex digits with trailing zeros removed, or "0".
+func encodeThreshold(threshold uint64) string {
+	encoded := strings.TrimRight(fmt.Sprintf("%0*x", thresholdDigits, threshold), "0")
+	if encoded == "" {
+		return "0"
+	}
+
+	return encoded
+}
+
+// decodeThreshold parses a th value of 1 to 14 hex digits, padding it with trailing zeros.
+func decodeThreshold(encoded string) (uint64, error) {
+	if encoded == "" || len(encoded) > thresholdDigits {
+		return 0, fmt.Errorf("ttrace: invalid sampling threshold %q", encoded)
+	}
+
+	threshold, err := strconv.ParseUint(encoded+strings.Repeat("0", thresholdDigits-len(encoded)), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ttrace: invalid sampling threshold %q: %w", encoded, err)
+	}
+
+	return threshold, nil
+}
+
+// otelTraceStateValues holds the sub-keys of the ot tracestate entry in order.
+type otelTraceStateValues struct {
+	keys   []string
+	values map[string]string
+}
+
+// parseOTelTraceState splits an ot tracestate value such as "th:c;rv:0123456789abcd". Malformed
+// sub-entries are dropped.
+func parseOTelTraceState(value string) *otelTraceStateValues {
+	values := &otelTraceStateValues{
+		values: make(map[string]string),
+	}
+
+	for _, item := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(item, ":")
+		if !ok || key == "" {
+			continue
+		}
+
+		if _, exists := values.values[key]; !exists {
+			values.keys = append(values.keys, key)
+		}
+
+		values.values[key] = val
+	}
+
+	return values
+}
+
+// get returns the sub-key value.
+func (v *otelTraceStateValues) get(key string) (string, bool) {
+	val, ok := v.values[key]
+
+	return val, ok
+}
+
+// set replaces or adds a sub-key.
+func (v *otelTraceStateValues) set(key, val string) {
+	if _, ok := v.values[key]; !ok {
+		v.keys = append(v.keys, key)
+	}
+
+	v.values[key] = val
+}
+
+// delete removes a sub-key.
+func (v *otelTraceStateValues) delete(key string) {
+	if _, ok := v.values[key]; !ok {
+		return
+	}
+
+	delete(v.values, key)
+
+	for i, k := range v.keys {
+		if k == key {
+			v.keys = append(v.keys[:i], v.keys[i+1:]...)
+
+			break
+		}
+	}
+}
+
+// randomness returns the explicit rv value when it is exactly 14 hex digits.
+func (v *otelTraceStateValues) randomness() (uint64, bool) {
+	rv, ok := v.values["rv"]
+	if !ok || len(rv) != thresholdDigits {
+		return 0, false
+	}
+
+	randomness, err := strconv.ParseUint(rv, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return randomness, true
+}
+
+// apply writes the sub-keys back into traceState as the ot entry, removing the entry when no
+// sub-keys remain. traceState is returned unchanged when the entry cannot be encoded.
+func (v *otelTraceStateValues) apply(traceState oteltrace.TraceState) oteltrace.TraceState {
+	if len(v.keys) == 0 {
+		return traceState.Delete(otelTraceStateKey)
+	}
+
+	items := make([]string, 0, len(v.keys))
+	for _, key := range v.keys {
+		items = append(items, key+":"+v.values[key])
+	}
+
+	updated, err := traceState.Insert(otelTraceStateKey, strings.Join(items, ";"))
+	if err != nil {
+		return traceState
+	}
+
+	return updated
+}
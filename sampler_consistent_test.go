@@ -0,0 +1,262 @@
+package ttrace
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestConsistentSamplerWritesRandomnessForRoots(t *testing.T) {
+	sampler, err := NewConsistentSampler(0.5)
+	if err != nil {
+		t.Fatalf("NewConsistentSampler: %v", err)
+	}
+
+	threshold, err := decodeThreshold("8")
+	if err != nil {
+		t.Fatalf("decodeThreshold: %v", err)
+	}
+
+	seen := make(map[string]bool)
+
+	for range 200 {
+		result := sampler.ShouldSample(trace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       newTestTraceID(),
+			Name:          "root",
+		})
+
+		values := parseOTelTraceState(result.Tracestate.Get(otelTraceStateKey))
+
+		rv, ok := values.get("rv")
+		if !ok || len(rv) != thresholdDigits {
+			t.Fatalf("tracestate %q, want a %d-digit rv value", result.Tracestate, thresholdDigits)
+		}
+
+		seen[rv] = true
+
+		randomness, err := strconv.ParseUint(rv, 16, 64)
+		if err != nil {
+			t.Fatalf("parse rv %q: %v", rv, err)
+		}
+
+		sampled := result.Decision == trace.RecordAndSample
+		if sampled != (randomness >= threshold) {
+			t.Fatalf("rv %s: sampled = %v, want %v", rv, sampled, randomness >= threshold)
+		}
+
+		th, ok := values.get("th")
+		if sampled != ok || ok && th != "8" {
+			t.Fatalf("tracestate %q for sampled = %v, want th:8 only on sampled roots", result.Tracestate, sampled)
+		}
+	}
+
+	if len(seen) < 190 {
+		t.Errorf("got %d distinct rv values in 200 roots, want fresh randomness per root", len(seen))
+	}
+}
+
+func TestConsistentSamplerUsesParentRandomness(t *testing.T) {
+	sampler, err := NewConsistentSampler(0.5)
+	if err != nil {
+		t.Fatalf("NewConsistentSampler: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		traceState string
+		wantState  string
+	}{
+		{name: "consistent threshold is kept", traceState: "ot=th:8;rv:c0000000000000", wantState: "ot=th:8;rv:c0000000000000"},
+		{name: "inconsistent threshold is erased", traceState: "ot=th:8;rv:40000000000000", wantState: "ot=rv:40000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceState, err := oteltrace.ParseTraceState(tt.traceState)
+			if err != nil {
+				t.Fatalf("ParseTraceState: %v", err)
+			}
+
+			parent := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+				TraceID:    newTestTraceID(),
+				SpanID:     oteltrace.SpanID{1},
+				TraceFlags: oteltrace.FlagsSampled,
+				TraceState: traceState,
+				Remote:     true,
+			})
+
+			result := sampler.ShouldSample(trace.SamplingParameters{
+				ParentContext: oteltrace.ContextWithRemoteSpanContext(context.Background(), parent),
+				TraceID:       parent.TraceID(),
+				Name:          "child",
+			})
+
+			if result.Decision != trace.RecordAndSample {
+				t.Errorf("decision = %v, want the sampled parent decision", result.Decision)
+			}
+
+			if result.Tracestate.String() != tt.wantState {
+				t.Errorf("tracestate = %q, want %q", result.Tracestate, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestWithConsistentSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	handle, err := New(context.Background(), WithExporter(exporter), WithConsistentSampling(1))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	defer handle.Shutdown(context.Background())
+
+	tracer := handle.TracerProvider().Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+
+	// The in-memory exporter forgets its spans on shutdown, so flush instead.
+	err = handle.TracerProvider().ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+
+	for _, span := range spans {
+		values := parseOTelTraceState(span.SpanContext.TraceState().Get(otelTraceStateKey))
+
+		th, _ := values.get("th")
+		_, ok := values.get("rv")
+
+		if th != "0" || !ok {
+			t.Errorf("span %s tracestate = %q, want th:0 and an rv value", span.Name, span.SpanContext.TraceState())
+		}
+	}
+
+	if spans[0].SpanContext.TraceState().String() != spans[1].SpanContext.TraceState().String() {
+		t.Errorf("child tracestate %q differs from root tracestate %q", spans[0].SpanContext.TraceState(), spans[1].SpanContext.TraceState())
+	}
+
+	if handle.Sampler() != nil {
+		t.Error("Handle.Sampler() is not nil with consistent sampling")
+	}
+}
+
+func TestWithConsistentSamplingRejectsInvalidFraction(t *testing.T) {
+	_, err := New(context.Background(), WithExporter(tracetest.NewInMemoryExporter()), WithConsistentSampling(1.5))
+	if err == nil {
+		t.Fatal("New with fraction 1.5 succeeded, want error")
+	}
+}
+
+func TestNewConsistentSamplerValidatesFraction(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		wantErr  bool
+	}{
+		{fraction: 0},
+		{fraction: 0.5},
+		{fraction: 1},
+		{fraction: -0.1, wantErr: true},
+		{fraction: 1.5, wantErr: true},
+		{fraction: math.NaN(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := NewConsistentSampler(tt.fraction)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewConsistentSampler(%v) error = %v, want error %t", tt.fraction, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConsistentSamplerRootDecisions(t *testing.T) {
+	tests := []struct {
+		fraction     float64
+		wantDecision trace.SamplingDecision
+		wantTh       string
+	}{
+		{fraction: 1, wantDecision: trace.RecordAndSample, wantTh: "0"},
+		{fraction: 0, wantDecision: trace.Drop},
+	}
+
+	for _, tt := range tests {
+		sampler, err := NewConsistentSampler(tt.fraction)
+		if err != nil {
+			t.Fatalf("NewConsistentSampler: %v", err)
+		}
+
+		result := sampler.ShouldSample(trace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       newTestTraceID(),
+			Name:          "root",
+		})
+
+		if result.Decision != tt.wantDecision {
+			t.Errorf("fraction %v: decision = %v, want %v", tt.fraction, result.Decision, tt.wantDecision)
+		}
+
+		th, _ := parseOTelTraceState(result.Tracestate.Get(otelTraceStateKey)).get("th")
+		if th != tt.wantTh {
+			t.Errorf("fraction %v: th = %q, want %q", tt.fraction, th, tt.wantTh)
+		}
+	}
+}
+
+func TestConsistentSamplerUpdate(t *testing.T) {
+	sampler, err := NewConsistentSampler(1)
+	if err != nil {
+		t.Fatalf("NewConsistentSampler: %v", err)
+	}
+
+	err = sampler.Update(2)
+	if err == nil {
+		t.Error("Update(2) succeeded, want error")
+	}
+
+	if sampler.Fraction() != 1 {
+		t.Errorf("Fraction() = %v after a rejected update, want 1", sampler.Fraction())
+	}
+
+	err = sampler.Update(0)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if countSampled(sampler, "root", 10) != 0 {
+		t.Error("sampler with fraction 0 sampled a root span")
+	}
+}
+
+func TestThresholdEncodingRoundTrip(t *testing.T) {
+	for _, fraction := range []float64{1, 0.5, 0.25, 0.001} {
+		threshold := uint64(math.Round((1 - fraction) * float64(maxThreshold)))
+
+		got, err := decodeThreshold(encodeThreshold(threshold))
+		if err != nil {
+			t.Fatalf("decodeThreshold(%q): %v", encodeThreshold(threshold), err)
+		}
+
+		if got != threshold {
+			t.Errorf("fraction %v: decoded threshold %x, want %x", fraction, got, threshold)
+		}
+	}
+
+	if encodeThreshold(maxThreshold/2) != "8" {
+		t.Errorf("encodeThreshold(2^55) = %q, want \"8\"", encodeThreshold(maxThreshold/2))
+	}
+}
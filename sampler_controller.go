@@ -0,0 +1,292 @@
+package ttrace
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SamplingConfig holds the two root sampling knobs. Either value may be -1 to disable that stage;
+// when both are -1, every root trace is sampled.
+type SamplingConfig struct {
+	// SamplingFraction is the trace ID ratio applied to root spans, see [TracerSamplingFraction].
+	SamplingFraction float64
+	// MaxTracesPerSecond caps sampled root traces per second, see [TracerMaxTracesPerSec].
+	MaxTracesPerSecond float64
+}
+
+// validate reports an error when either value is below -1.
+func (c SamplingConfig) validate() error {
+	err := validateSamplingConfigValue(TracerSamplingFraction, c.SamplingFraction)
+	if err != nil {
+		return err
+	}
+
+	return validateSamplingConfigValue(TracerMaxTracesPerSec, c.MaxTracesPerSecond)
+}
+
+// SamplerController is a root [trace.Sampler] whose ratio and throughput stages can be changed at
+// runtime. It applies the trace ID ratio stage first and the per-second cap second, like
+// [GuaranteedThroughputProbabilitySampler]. Wrap it with [trace.ParentBased] so that child spans
+// inherit their parent decision; [Init] does so automatically unless [WithSampler] is used.
+type SamplerController struct {
+	lock sync.Mutex
+
+	state       atomic.Pointer[samplerState]
+	rateLimiter *ReconfigurableRateLimiter
+	counters    *samplingCounters
+}
+
+// samplerState is an immutable snapshot of the controller configuration, swapped atomically on
+// update so that ShouldSample always observes a consistent pair of values.
+type samplerState struct {
+	config             SamplingConfig
+	probabilitySampler trace.Sampler
+}
+
+// NewSamplerController returns a [SamplerController] for samplingFraction and maxTracesPerSecond.
+// It returns an error when either value is below -1.
+func NewSamplerController(samplingFraction, maxTracesPerSecond float64) (*SamplerController, error) {
+	c := &SamplerController{
+		counters: newSamplingCounters("SamplerController"),
+	}
+
+	err := c.Update(samplingFraction, maxTracesPerSecond)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Update replaces the sampling fraction and the per-second cap. Both values take effect together
+// for subsequent sampling decisions. It returns an error, leaving the configuration unchanged,
+// when either value is below -1.
+func (c *SamplerController) Update(samplingFraction, maxTracesPerSecond float64) error {
+	config := SamplingConfig{
+		SamplingFraction:   samplingFraction,
+		MaxTracesPerSecond: maxTracesPerSecond,
+	}
+
+	err := config.validate()
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if maxTracesPerSecond != -1 {
+		if c.rateLimiter == nil {
+			c.rateLimiter = NewRateLimiter(maxTracesPerSecond, math.Max(maxTracesPerSecond, 1.0))
+		} else {
+			c.rateLimiter.Update(maxTracesPerSecond, math.Max(maxTracesPerSecond, 1.0))
+		}
+	}
+
+	state := &samplerState{
+		config: config,
+	}
+
+	if samplingFraction != -1 {
+		state.probabilitySampler = trace.TraceIDRatioBased(samplingFraction)
+	}
+
+	c.state.Store(state)
+
+	return nil
+}
+
+// Config returns the current sampling configuration.
+func (c *SamplerController) Config() SamplingConfig {
+	return c.state.Load().config
+}
+
+// ShouldSample applies the ratio stage, when enabled, and then the per-second cap, when enabled.
+func (c *SamplerController) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	state := c.state.Load()
+
+	if state.probabilitySampler != nil {
+		samplingResult := state.probabilitySampler.ShouldSample(p)
+		if samplingResult.Decision == trace.Drop {
+			c.counters.record(p.ParentContext, SamplingDecisionDroppedByRatio)
+
+			return samplingResult
+		}
+	}
+
+	if state.config.MaxTracesPerSecond != -1 && !c.rateLimiter.CheckCredit(1.0) {
+		c.counters.record(p.ParentContext, SamplingDecisionDroppedByRateLimit)
+
+		return trace.SamplingResult{
+			Decision:   trace.Drop,
+			Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+
+	c.counters.record(p.ParentContext, SamplingDecisionSampled)
+
+	return trace.SamplingResult{
+		Decision:   trace.RecordAndSample,
+		Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+// Stats returns the decision counts of the controller. Spans that inherited their parent decision
+// are counted when the controller is installed by [Init], which also reports all counts through
+// [SamplingDecisionsMetric].
+func (c *SamplerController) Stats() SamplingStats {
+	return c.counters.stats()
+}
+
+// Description reports the current configuration.
+func (c *SamplerController) Description() string {
+	config := c.Config()
+
+	return fmt.Sprintf("SamplerController(samplingFraction=%v,maxTracesPerSecond=%v)", config.SamplingFraction, config.MaxTracesPerSecond)
+}
+
+// SamplingSource loads a sampling configuration for [SamplerController.Watch]. Values that the
+// source does not define should be copied from current.
+type SamplingSource func(ctx context.Context, current SamplingConfig) (SamplingConfig, error)
+
+// Watch calls source every interval and applies the configuration it returns until ctx is done.
+// Source and validation errors are logged and leave the configuration unchanged.
+func (c *SamplerController) Watch(ctx context.Context, interval time.Duration, source SamplingSource) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := c.Config()
+
+			config, err := source(ctx, current)
+			if err != nil {
+				log.Printf("ttrace: reload sampling configuration: %v", err)
+
+				continue
+			}
+
+			if config == current {
+				continue
+			}
+
+			err = c.Update(config.SamplingFraction, config.MaxTracesPerSecond)
+			if err != nil {
+				log.Printf("ttrace: apply sampling configuration: %v", err)
+			}
+		}
+	}()
+}
+
+// TcfgSamplingSource returns a [SamplingSource] that re-reads [TracerSamplingFraction] and
+// [TracerMaxTracesPerSec] through [github.com/choveylee/tcfg]. Unset keys keep their current
+// values.
+func TcfgSamplingSource() SamplingSource {
+	return func(_ context.Context, current SamplingConfig) (SamplingConfig, error) {
+		config := current
+
+		var err error
+
+		val, ok := ttraceValue(TracerSamplingFraction)
+		if ok {
+			config.SamplingFraction, err = strconv.ParseFloat(val, 64)
+			if err != nil {
+				return current, fmt.Errorf("ttrace: invalid %s %q: %w", TracerSamplingFraction, val, err)
+			}
+		}
+
+		val, ok = ttraceValue(TracerMaxTracesPerSec)
+		if ok {
+			config.MaxTracesPerSecond, err = strconv.ParseFloat(val, 64)
+			if err != nil {
+				return current, fmt.Errorf("ttrace: invalid %s %q: %w", TracerMaxTracesPerSec, val, err)
+			}
+		}
+
+		return config, nil
+	}
+}
+
+// FileSamplingSource returns a [SamplingSource] that reads path as KEY=VALUE lines using the
+// [TracerSamplingFraction] and [TracerMaxTracesPerSec] key names. Blank lines and lines starting
+// with '#' are ignored, other keys are skipped, and keys absent from the file keep their current
+// values.
+func FileSamplingSource(path string) SamplingSource {
+	return func(_ context.Context, current SamplingConfig) (SamplingConfig, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return current, fmt.Errorf("ttrace: open sampling file: %w", err)
+		}
+		defer file.Close()
+
+		config := current
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			key, val, ok := strings.Cut(line, "=")
+			if !ok {
+				return current, fmt.Errorf("ttrace: invalid sampling file line %q: want KEY=VALUE", line)
+			}
+
+			key = strings.TrimSpace(key)
+			val = strings.TrimSpace(val)
+
+			var target *float64
+
+			switch key {
+			case TracerSamplingFraction:
+				target = &config.SamplingFraction
+			case TracerMaxTracesPerSec:
+				target = &config.MaxTracesPerSecond
+			default:
+				continue
+			}
+
+			*target, err = strconv.ParseFloat(val, 64)
+			if err != nil {
+				return current, fmt.Errorf("ttrace: invalid %s %q in sampling file: %w", key, val, err)
+			}
+		}
+
+		err = scanner.Err()
+		if err != nil {
+			return current, fmt.Errorf("ttrace: read sampling file: %w", err)
+		}
+
+		return config, nil
+	}
+}
+
+var (
+	installedSampler atomic.Pointer[SamplerController]
+)
+
+// Sampler returns the [SamplerController] of the TracerProvider installed by [Init] or
+// [InitFromEnv], or nil when tracing is disabled or a custom sampler was supplied with
+// [WithSampler].
+func Sampler() *SamplerController {
+	return installedSampler.Load()
+}
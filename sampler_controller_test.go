@@ -0,0 +1,287 @@
+package ttrace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeSamplingSource is a [SamplingSource] that returns a configurable result and counts its calls.
+type fakeSamplingSource struct {
+	config atomic.Pointer[SamplingConfig]
+	err    atomic.Pointer[error]
+	calls  atomic.Int64
+}
+
+// source returns the [SamplingSource] backed by s. Without a configured result it returns current.
+func (s *fakeSamplingSource) source() SamplingSource {
+	return func(_ context.Context, current SamplingConfig) (SamplingConfig, error) {
+		s.calls.Add(1)
+
+		err := s.err.Load()
+		if err != nil {
+			return current, *err
+		}
+
+		config := s.config.Load()
+		if config == nil {
+			return current, nil
+		}
+
+		return *config, nil
+	}
+}
+
+// set makes s return config from now on.
+func (s *fakeSamplingSource) set(config SamplingConfig) {
+	s.config.Store(&config)
+}
+
+// fail makes s return err from now on.
+func (s *fakeSamplingSource) fail(err error) {
+	s.err.Store(&err)
+}
+
+// waitForCalls waits until the source has been called at least n more times than before.
+func (s *fakeSamplingSource) waitForCalls(t *testing.T, n int64) {
+	t.Helper()
+
+	want := s.calls.Load() + n
+	deadline := time.Now().Add(5 * time.Second)
+
+	for s.calls.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d source calls, want %d", s.calls.Load(), want)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSamplerControllerWatchReloads(t *testing.T) {
+	controller, err := NewSamplerController(0.1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &fakeSamplingSource{}
+	controller.Watch(ctx, time.Millisecond, source.source())
+
+	want := SamplingConfig{SamplingFraction: 0.5, MaxTracesPerSecond: -1}
+	source.set(want)
+	source.waitForCalls(t, 2)
+
+	got := controller.Config()
+	if got != want {
+		t.Errorf("got config %+v, want %+v", got, want)
+	}
+}
+
+func TestSamplerControllerWatchKeepsConfigOnErrors(t *testing.T) {
+	want := SamplingConfig{SamplingFraction: 0.1, MaxTracesPerSecond: 1}
+
+	controller, err := NewSamplerController(want.SamplingFraction, want.MaxTracesPerSecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &fakeSamplingSource{}
+	source.set(SamplingConfig{SamplingFraction: 0.5, MaxTracesPerSecond: 1})
+	source.fail(errors.New("source unavailable"))
+	controller.Watch(ctx, time.Millisecond, source.source())
+	source.waitForCalls(t, 2)
+
+	got := controller.Config()
+	if got != want {
+		t.Errorf("got config %+v after source errors, want %+v", got, want)
+	}
+
+	source.set(SamplingConfig{SamplingFraction: -2, MaxTracesPerSecond: 1})
+	source.err.Store(nil)
+	source.waitForCalls(t, 2)
+
+	got = controller.Config()
+	if got != want {
+		t.Errorf("got config %+v after an invalid configuration, want %+v", got, want)
+	}
+}
+
+func TestSamplerControllerWatchStopsOnCancel(t *testing.T) {
+	controller, err := NewSamplerController(0.1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	source := &fakeSamplingSource{}
+	controller.Watch(ctx, time.Millisecond, source.source())
+	source.waitForCalls(t, 1)
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	calls := source.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+
+	got := source.calls.Load()
+	if got != calls {
+		t.Errorf("got %d source calls after cancel, want %d", got, calls)
+	}
+}
+
+func TestHandleShutdownStopsSamplingWatcher(t *testing.T) {
+	source := &fakeSamplingSource{}
+
+	handle, err := New(context.Background(),
+		WithExporter(tracetest.NewInMemoryExporter()),
+		WithSampling(0.1, 1),
+		WithSamplingWatcher(time.Millisecond, source.source()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := SamplingConfig{SamplingFraction: 1, MaxTracesPerSecond: -1}
+	source.set(want)
+	source.waitForCalls(t, 2)
+
+	got := handle.Sampler().Config()
+	if got != want {
+		t.Errorf("got config %+v, want %+v", got, want)
+	}
+
+	err = handle.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	calls := source.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+
+	gotCalls := source.calls.Load()
+	if gotCalls != calls {
+		t.Errorf("got %d source calls after Shutdown, want %d", gotCalls, calls)
+	}
+}
+
+func TestFileSamplingSource(t *testing.T) {
+	current := SamplingConfig{SamplingFraction: 0.1, MaxTracesPerSecond: 1}
+
+	tests := []struct {
+		name    string
+		content string
+		want    SamplingConfig
+		wantErr string
+	}{
+		{
+			name:    "both keys",
+			content: "# sampling\n\nTRACER_SAMPLING_FRACTION = 0.5\nTRACER_MAX_TRACES_PER_SEC=10\n",
+			want:    SamplingConfig{SamplingFraction: 0.5, MaxTracesPerSecond: 10},
+		},
+		{
+			name:    "missing key keeps current value",
+			content: "TRACER_MAX_TRACES_PER_SEC=-1\nOTHER_KEY=x\n",
+			want:    SamplingConfig{SamplingFraction: 0.1, MaxTracesPerSecond: -1},
+		},
+		{
+			name:    "malformed line",
+			content: "TRACER_SAMPLING_FRACTION\n",
+			want:    current,
+			wantErr: "want KEY=VALUE",
+		},
+		{
+			name:    "malformed value",
+			content: "TRACER_SAMPLING_FRACTION=abc\n",
+			want:    current,
+			wantErr: TracerSamplingFraction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sampling.conf")
+
+			err := os.WriteFile(path, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := FileSamplingSource(path)(context.Background(), current)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("got error %v, want nil", err)
+			}
+
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got error %v, want one containing %s", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("got config %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := FileSamplingSource(filepath.Join(t.TempDir(), "missing.conf"))(context.Background(), current)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v for a missing file, want os.ErrNotExist", err)
+	}
+}
+
+func TestTcfgSamplingSource(t *testing.T) {
+	current := SamplingConfig{SamplingFraction: 0.1, MaxTracesPerSecond: 1}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    SamplingConfig
+		wantErr bool
+	}{
+		{
+			name: "unset keys keep current values",
+			env:  map[string]string{},
+			want: current,
+		},
+		{
+			name: "both keys",
+			env:  map[string]string{TracerSamplingFraction: "0.5", TracerMaxTracesPerSec: "-1"},
+			want: SamplingConfig{SamplingFraction: 0.5, MaxTracesPerSecond: -1},
+		},
+		{
+			name:    "malformed value",
+			env:     map[string]string{TracerMaxTracesPerSec: "abc"},
+			want:    current,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestEnv(t, tt.env)
+
+			got, err := TcfgSamplingSource()(context.Background(), current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("got config %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
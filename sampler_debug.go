@@ -0,0 +1,184 @@
+package ttrace
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Defaults used by [DebugSamplingOptions].
+const (
+	DefaultDebugBaggageKey         = "ttrace.debug"
+	DefaultDebugMaxTracesPerSecond = 1.0
+)
+
+// Span attributes recorded on spans sampled because of a debug signal.
+const (
+	// DebugSampledKey is set to true on spans that a debug signal forced into the sample.
+	DebugSampledKey = attribute.Key("ttrace.sampling.forced")
+	// DebugIDKey holds the value of the debug signal, such as a ticket or correlation ID, so that
+	// forced traces can be searched for.
+	DebugIDKey = attribute.Key("ttrace.debug.id")
+)
+
+// DebugSamplingOptions configures the debug signals honored by [NewDebugSampler]. At least one of
+// BaggageKey, Header, and TraceStateKey must be set for the sampler to force anything.
+type DebugSamplingOptions struct {
+	// BaggageKey names a W3C Baggage member, such as [DefaultDebugBaggageKey], whose presence with a
+	// value other than "0" or "false" forces sampling.
+	BaggageKey string
+	// Header names a request header, in the style of jaeger-debug-id, whose presence forces
+	// sampling. The header is read and written by [DebugHeaderPropagator], which [Init] adds to the
+	// configured propagator.
+	Header string
+	// TraceStateKey names a W3C tracestate key whose presence forces sampling.
+	TraceStateKey string
+	// MaxTracesPerSecond limits how many traces per second a debug signal may force, so that the
+	// signal cannot be abused to sample everything. Zero means [DefaultDebugMaxTracesPerSecond].
+	MaxTracesPerSecond float64
+}
+
+// enabled reports whether any debug signal is configured.
+func (o DebugSamplingOptions) enabled() bool {
+	return o.BaggageKey != "" || o.Header != "" || o.TraceStateKey != ""
+}
+
+// DebugSampler wraps a [trace.Sampler] and samples spans carrying a configured debug signal
+// regardless of the wrapped sampler, bypassing its ratio and rate-limiting stages and an unsampled
+// remote parent. Forced decisions are rate-limited and recorded with [DebugSampledKey] and
+// [DebugIDKey]. Spans with a sampled or local parent are left to the wrapped sampler, so a forced
+// trace consumes one credit per service and is never sampled only in part.
+type DebugSampler struct {
+	delegate    trace.Sampler
+	options     DebugSamplingOptions
+	rateLimiter *ReconfigurableRateLimiter
+}
+
+// NewDebugSampler returns a [DebugSampler] that forces sampling for the signals in options and
+// otherwise delegates to delegate. Wrap the complete sampler, including [trace.ParentBased], so
+// that an unsampled upstream decision can be overridden.
+func NewDebugSampler(delegate trace.Sampler, options DebugSamplingOptions) (*DebugSampler, error) {
+	return newDebugSampler(delegate, options, time.Now)
+}
+
+// newDebugSampler is [NewDebugSampler] with the clock used by the debug rate limit.
+func newDebugSampler(delegate trace.Sampler, options DebugSamplingOptions, timeNow func() time.Time) (*DebugSampler, error) {
+	if delegate == nil {
+		return nil, fmt.Errorf("ttrace: missing sampler for debug sampling")
+	}
+
+	if options.MaxTracesPerSecond < 0 {
+		return nil, fmt.Errorf("ttrace: invalid debug max traces per second: must be >= 0 (got %v)", options.MaxTracesPerSecond)
+	}
+
+	if options.MaxTracesPerSecond == 0 {
+		options.MaxTracesPerSecond = DefaultDebugMaxTracesPerSecond
+	}
+
+	return &DebugSampler{
+		delegate:    delegate,
+		options:     options,
+		rateLimiter: NewRateLimiter(options.MaxTracesPerSecond, math.Max(options.MaxTracesPerSecond, 1.0), WithRateLimiterClock(timeNow)),
+	}, nil
+}
+
+// ShouldSample forces RecordAndSample when the parent context carries a debug signal, the parent
+// is remote or absent and not already sampled, and the debug rate limit allows it. Otherwise it
+// delegates.
+func (s *DebugSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	parent := oteltrace.SpanContextFromContext(p.ParentContext)
+	if parent.IsSampled() || parent.IsValid() && !parent.IsRemote() {
+		return s.delegate.ShouldSample(p)
+	}
+
+	debugID, ok := s.debugID(p.ParentContext, parent)
+	if !ok || !s.rateLimiter.CheckCredit(1.0) {
+		return s.delegate.ShouldSample(p)
+	}
+
+	return trace.SamplingResult{
+		Decision: trace.RecordAndSample,
+		Attributes: []attribute.KeyValue{
+			DebugSampledKey.Bool(true),
+			DebugIDKey.String(debugID),
+		},
+		Tracestate: parent.TraceState(),
+	}
+}
+
+// Description reports the configured signals, the debug rate limit, and the wrapped sampler.
+func (s *DebugSampler) Description() string {
+	return fmt.Sprintf("DebugSampler(baggageKey=%s,header=%s,traceStateKey=%s,maxTracesPerSecond=%v){%s}",
+		s.options.BaggageKey, s.options.Header, s.options.TraceStateKey, s.options.MaxTracesPerSecond, s.delegate.Description())
+}
+
+// debugID returns the value of the first configured debug signal found in ctx.
+func (s *DebugSampler) debugID(ctx context.Context, parent oteltrace.SpanContext) (string, bool) {
+	if s.options.BaggageKey != "" {
+		val := baggage.FromContext(ctx).Member(s.options.BaggageKey).Value()
+		if val != "" && val != "0" && !strings.EqualFold(val, "false") {
+			return val, true
+		}
+	}
+
+	if s.options.Header != "" {
+		val, ok := ctx.Value(debugHeaderContextKey{}).(string)
+		if ok && val != "" {
+			return val, true
+		}
+	}
+
+	if s.options.TraceStateKey != "" {
+		val := parent.TraceState().Get(s.options.TraceStateKey)
+		if val != "" {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// debugHeaderContextKey stores the debug header value extracted by [DebugHeaderPropagator].
+type debugHeaderContextKey struct{}
+
+// DebugHeaderPropagator returns a propagator that extracts the debug header for [DebugSampler]
+// into the context and injects it again on outgoing requests, so downstream services honor it too.
+func DebugHeaderPropagator(header string) propagation.TextMapPropagator {
+	return debugHeaderPropagator{header: strings.ToLower(header)}
+}
+
+// debugHeaderPropagator implements [DebugHeaderPropagator].
+type debugHeaderPropagator struct {
+	header string
+}
+
+// Inject writes the debug header when ctx carries one.
+func (p debugHeaderPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	val, ok := ctx.Value(debugHeaderContextKey{}).(string)
+	if ok && val != "" {
+		carrier.Set(p.header, val)
+	}
+}
+
+// Extract stores the debug header value in the returned context.
+func (p debugHeaderPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	val := strings.TrimSpace(carrier.Get(p.header))
+	if val == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, debugHeaderContextKey{}, val)
+}
+
+// Fields returns the debug header name.
+func (p debugHeaderPropagator) Fields() []string {
+	return []string{p.header}
+}
@@ -0,0 +1,272 @@
+package ttrace
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// testDebugOptions enables every debug signal.
+var testDebugOptions = DebugSamplingOptions{
+	BaggageKey:         DefaultDebugBaggageKey,
+	Header:             "X-Debug-Id",
+	TraceStateKey:      "debug",
+	MaxTracesPerSecond: 100,
+}
+
+// withDebugBaggage returns ctx carrying the debug baggage member set to val.
+func withDebugBaggage(t *testing.T, ctx context.Context, val string) context.Context {
+	t.Helper()
+
+	member, err := baggage.NewMember(DefaultDebugBaggageKey, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// withRemoteParent returns ctx carrying a remote parent with the given flags and tracestate.
+func withRemoteParent(t *testing.T, ctx context.Context, flags oteltrace.TraceFlags, traceState string) context.Context {
+	t.Helper()
+
+	state, err := oteltrace.ParseTraceState(traceState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := newTestSpanContext().WithTraceFlags(flags).WithTraceState(state)
+
+	return oteltrace.ContextWithRemoteSpanContext(ctx, parent)
+}
+
+// countForced calls sampler n times for root spans with parent context ctx and returns how many
+// were sampled.
+func countForced(sampler trace.Sampler, ctx context.Context, n int) int {
+	sampled := 0
+
+	for range n {
+		p := trace.SamplingParameters{
+			ParentContext: ctx,
+			TraceID:       newTestTraceID(),
+			Name:          "request",
+		}
+
+		if sampler.ShouldSample(p).Decision == trace.RecordAndSample {
+			sampled++
+		}
+	}
+
+	return sampled
+}
+
+func TestDebugSamplerForcesSampling(t *testing.T) {
+	headerContext := DebugHeaderPropagator(testDebugOptions.Header).Extract(context.Background(),
+		propagation.MapCarrier{"x-debug-id": "ticket-2"})
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		wantID string
+	}{
+		{
+			name:   "baggage",
+			ctx:    withDebugBaggage(t, context.Background(), "ticket-1"),
+			wantID: "ticket-1",
+		},
+		{
+			name: "disabled baggage",
+			ctx:  withDebugBaggage(t, context.Background(), "false"),
+		},
+		{
+			name:   "header",
+			ctx:    headerContext,
+			wantID: "ticket-2",
+		},
+		{
+			name:   "tracestate",
+			ctx:    withRemoteParent(t, context.Background(), 0, "debug=ticket-3"),
+			wantID: "ticket-3",
+		},
+		{
+			name:   "unsampled remote parent",
+			ctx:    withRemoteParent(t, withDebugBaggage(t, context.Background(), "ticket-4"), 0, ""),
+			wantID: "ticket-4",
+		},
+		{
+			name: "sampled remote parent",
+			ctx:  withRemoteParent(t, withDebugBaggage(t, context.Background(), "ticket-5"), oteltrace.FlagsSampled, ""),
+		},
+		{
+			name: "local parent",
+			ctx:  oteltrace.ContextWithSpanContext(withDebugBaggage(t, context.Background(), "ticket-6"), newTestSpanContext().WithTraceFlags(0)),
+		},
+		{
+			name: "no signal",
+			ctx:  context.Background(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler, err := NewDebugSampler(trace.NeverSample(), testDebugOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := sampler.ShouldSample(trace.SamplingParameters{
+				ParentContext: tt.ctx,
+				TraceID:       newTestTraceID(),
+				Name:          "request",
+			})
+
+			if tt.wantID == "" {
+				if result.Decision != trace.Drop || len(result.Attributes) != 0 {
+					t.Errorf("got decision %v with attributes %v, want the delegate decision", result.Decision, result.Attributes)
+				}
+
+				return
+			}
+
+			if result.Decision != trace.RecordAndSample {
+				t.Fatalf("got decision %v, want RecordAndSample", result.Decision)
+			}
+
+			want := []string{DebugSampledKey.Bool(true).Value.Emit(), tt.wantID}
+			got := make([]string, 0, len(result.Attributes))
+			for _, attr := range result.Attributes {
+				got = append(got, attr.Value.Emit())
+			}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("got attributes %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDebugSamplerRateLimit(t *testing.T) {
+	clock := newFakeClock()
+
+	sampler, err := newDebugSampler(trace.NeverSample(), DebugSamplingOptions{
+		BaggageKey:         DefaultDebugBaggageKey,
+		MaxTracesPerSecond: 2,
+	}, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := withDebugBaggage(t, context.Background(), "ticket-1")
+
+	got := countForced(sampler, ctx, 5)
+	if got != 2 {
+		t.Errorf("got %d forced traces, want the burst of 2", got)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+
+	got = countForced(sampler, ctx, 5)
+	if got != 1 {
+		t.Errorf("got %d forced traces after 500ms, want 1", got)
+	}
+}
+
+func TestNewDebugSamplerValidates(t *testing.T) {
+	_, err := NewDebugSampler(nil, testDebugOptions)
+	if err == nil {
+		t.Error("got nil error for a missing sampler, want error")
+	}
+
+	_, err = NewDebugSampler(trace.AlwaysSample(), DebugSamplingOptions{BaggageKey: DefaultDebugBaggageKey, MaxTracesPerSecond: -1})
+	if err == nil {
+		t.Error("got nil error for a negative limit, want error")
+	}
+
+	sampler, err := NewDebugSampler(trace.AlwaysSample(), DebugSamplingOptions{BaggageKey: DefaultDebugBaggageKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sampler.options.MaxTracesPerSecond != DefaultDebugMaxTracesPerSecond {
+		t.Errorf("got limit %v, want %v", sampler.options.MaxTracesPerSecond, DefaultDebugMaxTracesPerSecond)
+	}
+}
+
+func TestDebugHeaderPropagator(t *testing.T) {
+	propagator := DebugHeaderPropagator("X-Debug-Id")
+
+	fields := propagator.Fields()
+	if !slices.Equal(fields, []string{"x-debug-id"}) {
+		t.Errorf("got fields %v, want [x-debug-id]", fields)
+	}
+
+	ctx := propagator.Extract(context.Background(), propagation.MapCarrier{"x-debug-id": " ticket-1 "})
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	got := carrier.Get("x-debug-id")
+	if got != "ticket-1" {
+		t.Errorf("got injected header %q, want ticket-1", got)
+	}
+
+	carrier = propagation.MapCarrier{}
+	propagator.Inject(context.Background(), carrier)
+
+	if len(carrier) != 0 {
+		t.Errorf("got headers %v without a debug header, want none", carrier)
+	}
+}
+
+func TestInitAddsDebugHeaderPropagator(t *testing.T) {
+	restoreGlobals(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	handle, err := Init(context.Background(),
+		WithExporter(exporter),
+		WithSampling(0, -1),
+		WithDebugSampling(DebugSamplingOptions{Header: "X-Debug-Id"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	fields := otel.GetTextMapPropagator().Fields()
+	for _, field := range []string{"traceparent", "baggage", "x-debug-id"} {
+		if !slices.Contains(fields, field) {
+			t.Errorf("got propagator fields %v, want %s", fields, field)
+		}
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{"x-debug-id": "ticket-1"})
+	_, span := otel.Tracer("test").Start(ctx, "forced")
+	span.End()
+
+	_, span = otel.Tracer("test").Start(context.Background(), "dropped")
+	span.End()
+
+	err = handle.TracerProvider().ForceFlush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := spanNames(exporter)
+	if !slices.Equal(got, []string{"forced"}) {
+		t.Errorf("got spans %v, want only the forced span", got)
+	}
+}
@@ -0,0 +1,290 @@
+package ttrace
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OperationStrategy configures how root spans of one operation (span name) are sampled by a
+// [PerOperationSampler].
+type OperationStrategy struct {
+	// SamplingFraction is the trace ID ratio for the operation. Set it to -1 to disable the ratio
+	// stage so that only the rate limits apply.
+	SamplingFraction float64
+	// MaxTracesPerSecond caps traces sampled by the ratio stage for the operation. Set it to -1 to
+	// disable the cap.
+	MaxTracesPerSecond float64
+	// LowerBoundTracesPerSecond guarantees a minimum sampling rate for the operation: a root span
+	// that the ratio stage or the cap rejected is still sampled while this budget allows it. Spans
+	// the ratio stage and cap accept also consume the budget, so the floor only tops up the rate
+	// instead of adding to the cap. Zero disables the floor.
+	LowerBoundTracesPerSecond float64
+}
+
+// validate reports an error when the strategy contains out-of-range values.
+func (s OperationStrategy) validate() error {
+	if s.SamplingFraction < -1 {
+		return fmt.Errorf("ttrace: invalid operation sampling fraction: must be -1 or >= 0 (got %v)", s.SamplingFraction)
+	}
+
+	if s.MaxTracesPerSecond < -1 {
+		return fmt.Errorf("ttrace: invalid operation max traces per second: must be -1 or >= 0 (got %v)", s.MaxTracesPerSecond)
+	}
+
+	if s.LowerBoundTracesPerSecond < 0 {
+		return fmt.Errorf("ttrace: invalid operation lower bound: must be >= 0 (got %v)", s.LowerBoundTracesPerSecond)
+	}
+
+	return nil
+}
+
+// PerOperationConfig configures a [PerOperationSampler].
+type PerOperationConfig struct {
+	// Default applies to operations without an entry in Operations.
+	Default OperationStrategy
+	// Operations maps span names to dedicated strategies.
+	Operations map[string]OperationStrategy
+	// MaxOperations caps how many distinct operations receive their own sampler state. Operations
+	// seen after the cap is reached share a single sampler using the Default strategy. Zero or
+	// negative values mean 2000.
+	MaxOperations int
+}
+
+// defaultMaxOperations is the [PerOperationConfig.MaxOperations] used when none is configured.
+const (
+	defaultMaxOperations = 2000
+)
+
+// PerOperationSampler is a root [trace.Sampler] that keeps a separate ratio, throughput cap, and
+// guaranteed lower-bound rate for every span name, in the spirit of Jaeger's per-operation
+// sampling strategies. Chatty operations therefore cannot consume the sampling budget of rare
+// ones. Wrap it with [trace.ParentBased] so that child spans inherit their parent decision.
+type PerOperationSampler struct {
+	lock sync.RWMutex
+
+	config     PerOperationConfig
+	operations map[string]*operationSampler
+	overflow   *operationSampler
+	counters   *samplingCounters
+
+	timeNow func() time.Time
+}
+
+// NewPerOperationSampler returns a [PerOperationSampler] for config. It returns an error when any
+// strategy contains out-of-range values.
+func NewPerOperationSampler(config PerOperationConfig) (*PerOperationSampler, error) {
+	return newPerOperationSampler(config, time.Now)
+}
+
+// newPerOperationSampler is [NewPerOperationSampler] with an injectable clock for the rate limiters.
+func newPerOperationSampler(config PerOperationConfig, timeNow func() time.Time) (*PerOperationSampler, error) {
+	s := &PerOperationSampler{
+		operations: make(map[string]*operationSampler),
+		counters:   newSamplingCounters("PerOperationSampler"),
+		timeNow:    timeNow,
+	}
+
+	err := s.Update(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Update applies config. Existing operations keep their rate limiter balances, operations removed
+// from config fall back to the default strategy, and new operations are added up to the cap. It
+// returns an error, leaving the sampler unchanged, when any strategy is invalid.
+func (s *PerOperationSampler) Update(config PerOperationConfig) error {
+	err := config.Default.validate()
+	if err != nil {
+		return err
+	}
+
+	for name, strategy := range config.Operations {
+		err = strategy.validate()
+		if err != nil {
+			return fmt.Errorf("ttrace: operation %q: %w", name, err)
+		}
+	}
+
+	if config.MaxOperations <= 0 {
+		config.MaxOperations = defaultMaxOperations
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.config = config
+
+	for name, sampler := range s.operations {
+		sampler.update(s.strategy(name))
+	}
+
+	for name, strategy := range config.Operations {
+		if _, ok := s.operations[name]; !ok && len(s.operations) < config.MaxOperations {
+			s.operations[name] = newOperationSampler(strategy, s.timeNow)
+		}
+	}
+
+	if s.overflow == nil {
+		s.overflow = newOperationSampler(config.Default, s.timeNow)
+	} else {
+		s.overflow.update(config.Default)
+	}
+
+	return nil
+}
+
+// strategy returns the configured strategy for name, or the default strategy. s.lock must be held.
+func (s *PerOperationSampler) strategy(name string) OperationStrategy {
+	strategy, ok := s.config.Operations[name]
+	if !ok {
+		return s.config.Default
+	}
+
+	return strategy
+}
+
+// sampler returns the operation sampler for name, creating it while the operation cap allows.
+func (s *PerOperationSampler) sampler(name string) *operationSampler {
+	s.lock.RLock()
+	sampler, ok := s.operations[name]
+	s.lock.RUnlock()
+
+	if ok {
+		return sampler
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sampler, ok = s.operations[name]
+	if ok {
+		return sampler
+	}
+
+	if len(s.operations) >= s.config.MaxOperations {
+		return s.overflow
+	}
+
+	sampler = newOperationSampler(s.strategy(name), s.timeNow)
+	s.operations[name] = sampler
+
+	return sampler
+}
+
+// ShouldSample applies the strategy of the operation named by the span.
+func (s *PerOperationSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	decision := s.sampler(p.Name).shouldSample(p)
+	s.counters.record(p.ParentContext, decision)
+
+	if decision != SamplingDecisionSampled {
+		return trace.SamplingResult{Decision: trace.Drop}
+	}
+
+	return trace.SamplingResult{Decision: trace.RecordAndSample}
+}
+
+// Stats returns the decision counts of the sampler.
+func (s *PerOperationSampler) Stats() SamplingStats {
+	return s.counters.stats()
+}
+
+// Description reports the default strategy and the number of tracked operations.
+func (s *PerOperationSampler) Description() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return fmt.Sprintf("PerOperationSampler(default=%+v,operations=%d,maxOperations=%d)", s.config.Default, len(s.operations), s.config.MaxOperations)
+}
+
+// operationSampler holds the sampling state of one operation.
+type operationSampler struct {
+	lock sync.RWMutex
+
+	strategy           OperationStrategy
+	probabilitySampler trace.Sampler
+	rateLimiter        *ReconfigurableRateLimiter
+	lowerBoundLimiter  *ReconfigurableRateLimiter
+
+	timeNow func() time.Time
+}
+
+// newOperationSampler returns an operationSampler for strategy whose rate limiters use timeNow.
+func newOperationSampler(strategy OperationStrategy, timeNow func() time.Time) *operationSampler {
+	s := &operationSampler{
+		timeNow: timeNow,
+	}
+	s.update(strategy)
+
+	return s
+}
+
+// update applies strategy, reusing existing rate limiters so their balances carry over.
+func (s *operationSampler) update(strategy OperationStrategy) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.strategy = strategy
+
+	s.probabilitySampler = nil
+	if strategy.SamplingFraction != -1 {
+		s.probabilitySampler = trace.TraceIDRatioBased(strategy.SamplingFraction)
+	}
+
+	clock := WithRateLimiterClock(s.timeNow)
+
+	s.rateLimiter = updateRateLimiter(s.rateLimiter, strategy.MaxTracesPerSecond, strategy.MaxTracesPerSecond != -1, clock)
+	s.lowerBoundLimiter = updateRateLimiter(s.lowerBoundLimiter, strategy.LowerBoundTracesPerSecond, strategy.LowerBoundTracesPerSecond > 0, clock)
+}
+
+// shouldSample samples when the ratio stage and cap accept the span, and otherwise when the
+// lower-bound budget has credit left. Accepted spans are debited from the lower-bound budget too,
+// so that the floor and the cap are not additive. It returns one of the SamplingDecision values.
+func (s *operationSampler) shouldSample(p trace.SamplingParameters) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	decision := SamplingDecisionSampled
+
+	if s.probabilitySampler != nil && s.probabilitySampler.ShouldSample(p).Decision == trace.Drop {
+		decision = SamplingDecisionDroppedByRatio
+	}
+
+	if decision == SamplingDecisionSampled && s.rateLimiter != nil && !s.rateLimiter.CheckCredit(1.0) {
+		decision = SamplingDecisionDroppedByRateLimit
+	}
+
+	if s.lowerBoundLimiter != nil {
+		// The debit result only matters for rejected spans; accepted ones merely consume credit.
+		credited := s.lowerBoundLimiter.CheckCredit(1.0)
+		if credited {
+			decision = SamplingDecisionSampled
+		}
+	}
+
+	return decision
+}
+
+// updateRateLimiter returns rateLimiter reconfigured for creditsPerSecond, a new limiter built with
+// opts when none exists yet, or nil when enabled is false.
+func updateRateLimiter(rateLimiter *ReconfigurableRateLimiter, creditsPerSecond float64, enabled bool, opts ...RateLimiterOption) *ReconfigurableRateLimiter {
+	if !enabled {
+		return nil
+	}
+
+	maxBalance := math.Max(creditsPerSecond, 1.0)
+
+	if rateLimiter == nil {
+		return NewRateLimiter(creditsPerSecond, maxBalance, opts...)
+	}
+
+	rateLimiter.Update(creditsPerSecond, maxBalance)
+
+	return rateLimiter
+}
@@ -0,0 +1,222 @@
+package ttrace
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// fakeClock is a manually advanced clock for rate limiters and samplers under test.
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// newFakeClock returns a fakeClock set to a fixed instant.
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the current fake time.
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// newTestTraceID returns a random trace ID.
+func newTestTraceID() oteltrace.TraceID {
+	var traceID oteltrace.TraceID
+	_, _ = rand.Read(traceID[:])
+
+	return traceID
+}
+
+// countSampled calls sampler n times for root spans named name and returns how many were sampled.
+func countSampled(sampler trace.Sampler, name string, n int) int {
+	sampled := 0
+
+	for range n {
+		p := trace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       newTestTraceID(),
+			Name:          name,
+		}
+
+		if sampler.ShouldSample(p).Decision == trace.RecordAndSample {
+			sampled++
+		}
+	}
+
+	return sampled
+}
+
+func TestPerOperationSamplerLowerBoundIsNotAdditive(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy OperationStrategy
+		want     int
+	}{
+		{
+			name:     "floor below cap",
+			strategy: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: 5, LowerBoundTracesPerSecond: 2},
+			want:     5,
+		},
+		{
+			name:     "floor equal to cap",
+			strategy: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: 3, LowerBoundTracesPerSecond: 3},
+			want:     3,
+		},
+		{
+			name:     "floor above cap",
+			strategy: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: 2, LowerBoundTracesPerSecond: 4},
+			want:     4,
+		},
+		{
+			name:     "floor only",
+			strategy: OperationStrategy{SamplingFraction: 0, MaxTracesPerSecond: -1, LowerBoundTracesPerSecond: 2},
+			want:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+
+			sampler, err := newPerOperationSampler(PerOperationConfig{Default: tt.strategy}, clock.Now)
+			if err != nil {
+				t.Fatalf("newPerOperationSampler: %v", err)
+			}
+
+			for second := range 3 {
+				got := countSampled(sampler, "op", 100)
+				if got != tt.want {
+					t.Errorf("second %d: sampled %d of 100, want %d", second, got, tt.want)
+				}
+
+				clock.Advance(time.Second)
+			}
+		})
+	}
+}
+
+func TestPerOperationSamplerKeepsOperationsApart(t *testing.T) {
+	clock := newFakeClock()
+
+	sampler, err := newPerOperationSampler(PerOperationConfig{
+		Default: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: 1},
+		Operations: map[string]OperationStrategy{
+			"chatty": {SamplingFraction: 1, MaxTracesPerSecond: 3},
+		},
+	}, clock.Now)
+	if err != nil {
+		t.Fatalf("newPerOperationSampler: %v", err)
+	}
+
+	got := countSampled(sampler, "chatty", 100)
+	if got != 3 {
+		t.Errorf("chatty: sampled %d of 100, want 3", got)
+	}
+
+	got = countSampled(sampler, "rare", 100)
+	if got != 1 {
+		t.Errorf("rare: sampled %d of 100, want 1", got)
+	}
+}
+
+func TestPerOperationSamplerAppliesStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy OperationStrategy
+		want     int
+	}{
+		{name: "ratio 0", strategy: OperationStrategy{SamplingFraction: 0, MaxTracesPerSecond: -1}, want: 0},
+		{name: "ratio 1 without cap", strategy: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: -1}, want: 100},
+		{name: "ratio 1 with cap", strategy: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: 3}, want: 3},
+		{name: "lower bound only", strategy: OperationStrategy{SamplingFraction: 0, MaxTracesPerSecond: -1, LowerBoundTracesPerSecond: 2}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler, err := NewPerOperationSampler(PerOperationConfig{
+				Operations: map[string]OperationStrategy{"op": tt.strategy},
+			})
+			if err != nil {
+				t.Fatalf("NewPerOperationSampler: %v", err)
+			}
+
+			got := countSampled(sampler, "op", 100)
+			if got != tt.want {
+				t.Errorf("sampled %d of 100, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerOperationSamplerSharesOverflowSampler(t *testing.T) {
+	sampler, err := NewPerOperationSampler(PerOperationConfig{
+		Default:       OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: 2},
+		MaxOperations: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewPerOperationSampler: %v", err)
+	}
+
+	// The first operation gets its own budget; later ones share the overflow sampler.
+	for _, tt := range []struct {
+		name string
+		want int
+	}{
+		{name: "first", want: 2},
+		{name: "second", want: 2},
+		{name: "third", want: 0},
+	} {
+		got := countSampled(sampler, tt.name, 100)
+		if got != tt.want {
+			t.Errorf("%s: sampled %d of 100, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPerOperationSamplerUpdate(t *testing.T) {
+	sampler, err := NewPerOperationSampler(PerOperationConfig{
+		Default: OperationStrategy{SamplingFraction: 1, MaxTracesPerSecond: -1},
+	})
+	if err != nil {
+		t.Fatalf("NewPerOperationSampler: %v", err)
+	}
+
+	got := countSampled(sampler, "op", 10)
+	if got != 10 {
+		t.Fatalf("before update: sampled %d of 10, want 10", got)
+	}
+
+	err = sampler.Update(PerOperationConfig{Default: OperationStrategy{SamplingFraction: -2}})
+	if err == nil {
+		t.Error("Update with fraction -2 succeeded, want error")
+	}
+
+	err = sampler.Update(PerOperationConfig{Default: OperationStrategy{SamplingFraction: 0, MaxTracesPerSecond: -1}})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got = countSampled(sampler, "op", 10)
+	if got != 0 {
+		t.Errorf("after update: sampled %d of 10, want 0", got)
+	}
+}
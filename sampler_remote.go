@@ -0,0 +1,385 @@
+package ttrace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultRemoteSamplingInterval is the polling interval used when [RemoteSamplerConfig] does not
+// set one.
+const (
+	defaultRemoteSamplingInterval = time.Minute
+)
+
+// RemoteSamplerConfig configures a [RemoteSampler].
+type RemoteSamplerConfig struct {
+	// URL is the sampling strategy endpoint, for example "http://jaeger-agent:5778/sampling". The
+	// service query parameter is added automatically.
+	URL string
+	// ServiceName identifies the service whose strategy is requested.
+	ServiceName string
+	// RefreshInterval is the polling interval. Zero means one minute.
+	RefreshInterval time.Duration
+	// Client performs the requests. Nil means a client with a ten-second timeout.
+	Client *http.Client
+	// Fallback is the root sampler used before the first successful poll and, when MaxStaleness is
+	// set, once polls have failed for longer than MaxStaleness. Nil means a [SamplerController]
+	// with the default sampling fraction 0.1 and cap 1.0.
+	Fallback trace.Sampler
+	// MaxStaleness is how long the last strategy stays active while polls fail, measured from the
+	// last successful poll. Zero keeps the last strategy until a poll succeeds again.
+	MaxStaleness time.Duration
+	// MaxOperations caps the operations tracked for per-operation strategies, see
+	// [PerOperationConfig.MaxOperations].
+	MaxOperations int
+}
+
+// RemoteSampler is a root [trace.Sampler] controlled by a Jaeger-compatible sampling strategy
+// service. It polls RemoteSamplerConfig.URL with ?service=<name> and applies probabilistic,
+// rate-limiting, and per-operation strategies to [ReconfigurableRateLimiter]-based samplers whose
+// limiter balances persist across updates. Wrap it with [trace.ParentBased] so that child spans
+// inherit their parent decision.
+//
+// Decisions taken by the strategies are counted in [RemoteSampler.Stats]; decisions taken by the
+// fallback sampler are counted by the fallback itself.
+type RemoteSampler struct {
+	config RemoteSamplerConfig
+
+	active       atomic.Pointer[remoteActiveSampler]
+	controller   *SamplerController
+	perOperation *PerOperationSampler
+
+	// lastSuccess is the time of the last successful poll in Unix nanoseconds, or zero.
+	lastSuccess atomic.Int64
+	timeNow     func() time.Time
+}
+
+// remoteActiveSampler wraps the sampler that currently makes decisions so that it can be swapped
+// atomically.
+type remoteActiveSampler struct {
+	sampler trace.Sampler
+}
+
+// NewRemoteSampler returns a [RemoteSampler] that uses the fallback sampler until [RemoteSampler.Start]
+// or [RemoteSampler.Refresh] obtains a strategy.
+func NewRemoteSampler(config RemoteSamplerConfig) (*RemoteSampler, error) {
+	return newRemoteSampler(config, time.Now)
+}
+
+// newRemoteSampler is [NewRemoteSampler] with an injectable clock for the staleness limit.
+func newRemoteSampler(config RemoteSamplerConfig, timeNow func() time.Time) (*RemoteSampler, error) {
+	if strings.TrimSpace(config.URL) == "" {
+		return nil, fmt.Errorf("ttrace: missing remote sampling URL")
+	}
+
+	if config.MaxStaleness < 0 {
+		return nil, fmt.Errorf("ttrace: invalid remote sampling max staleness: must be >= 0 (got %v)", config.MaxStaleness)
+	}
+
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultRemoteSamplingInterval
+	}
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: otlpExportTimeout}
+	}
+
+	if config.Fallback == nil {
+		fallback, err := NewSamplerController(0.1, 1.0)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Fallback = fallback
+	}
+
+	controller, err := NewSamplerController(-1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	perOperation, err := NewPerOperationSampler(PerOperationConfig{MaxOperations: config.MaxOperations})
+	if err != nil {
+		return nil, err
+	}
+
+	// Both strategy samplers count into the same counters.
+	controller.counters = newSamplingCounters("RemoteSampler")
+	perOperation.counters = controller.counters
+
+	s := &RemoteSampler{
+		config:       config,
+		controller:   controller,
+		perOperation: perOperation,
+		timeNow:      timeNow,
+	}
+
+	s.active.Store(&remoteActiveSampler{sampler: config.Fallback})
+
+	return s, nil
+}
+
+// Start polls the strategy service immediately and then every refresh interval until ctx is done.
+// Poll failures are logged and handled as described for [RemoteSampler.Refresh].
+func (s *RemoteSampler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			err := s.Refresh(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("ttrace: refresh remote sampling strategy: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Refresh fetches the strategy once and applies it. On failure, the error is returned and the last
+// strategy stays active, unless no poll has succeeded yet or the last success is older than
+// RemoteSamplerConfig.MaxStaleness, in which case the fallback sampler becomes active.
+func (s *RemoteSampler) Refresh(ctx context.Context) error {
+	strategy, err := s.fetch(ctx)
+	if err == nil {
+		err = s.apply(strategy)
+	}
+
+	now := s.timeNow()
+
+	if err != nil {
+		if s.stale(now) {
+			s.active.Store(&remoteActiveSampler{sampler: s.config.Fallback})
+		}
+
+		return err
+	}
+
+	s.lastSuccess.Store(now.UnixNano())
+
+	return nil
+}
+
+// stale reports whether the last successful poll is missing or older than the staleness limit.
+func (s *RemoteSampler) stale(now time.Time) bool {
+	lastSuccess := s.lastSuccess.Load()
+	if lastSuccess == 0 {
+		return true
+	}
+
+	return s.config.MaxStaleness > 0 && now.Sub(time.Unix(0, lastSuccess)) > s.config.MaxStaleness
+}
+
+// ShouldSample delegates to the active strategy.
+func (s *RemoteSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	return s.active.Load().sampler.ShouldSample(p)
+}
+
+// Stats returns the decision counts of the probabilistic, rate-limiting, and per-operation
+// strategies. Decisions of the fallback sampler are not included.
+func (s *RemoteSampler) Stats() SamplingStats {
+	return s.controller.counters.stats()
+}
+
+// Description reports the endpoint and the active strategy.
+func (s *RemoteSampler) Description() string {
+	return fmt.Sprintf("RemoteSampler(url=%s,service=%s,active=%s)", s.config.URL, s.config.ServiceName, s.active.Load().sampler.Description())
+}
+
+// fetch requests and decodes the strategy for the configured service.
+func (s *RemoteSampler) fetch(ctx context.Context) (*samplingStrategyResponse, error) {
+	endpoint, err := url.Parse(s.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: parse remote sampling URL: %w", err)
+	}
+
+	query := endpoint.Query()
+	query.Set("service", s.config.ServiceName)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: build remote sampling request: %w", err)
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: fetch remote sampling strategy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return nil, fmt.Errorf("ttrace: fetch remote sampling strategy: unexpected status %s", resp.Status)
+	}
+
+	strategy := &samplingStrategyResponse{}
+
+	err = json.NewDecoder(resp.Body).Decode(strategy)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: decode remote sampling strategy: %w", err)
+	}
+
+	return strategy, nil
+}
+
+// apply configures the sampler matching strategy and makes it active. Per-operation strategies
+// take precedence over the top-level strategy type, as in Jaeger clients.
+func (s *RemoteSampler) apply(strategy *samplingStrategyResponse) error {
+	if strategy.OperationSampling != nil {
+		operationSampling := strategy.OperationSampling
+
+		config := PerOperationConfig{
+			Default:       operationSampling.strategy(operationSampling.DefaultSamplingProbability),
+			Operations:    make(map[string]OperationStrategy, len(operationSampling.PerOperationStrategies)),
+			MaxOperations: s.config.MaxOperations,
+		}
+
+		for _, operation := range operationSampling.PerOperationStrategies {
+			if operation.ProbabilisticSampling == nil {
+				continue
+			}
+
+			config.Operations[operation.Operation] = operationSampling.strategy(operation.ProbabilisticSampling.SamplingRate)
+		}
+
+		err := s.perOperation.Update(config)
+		if err != nil {
+			return err
+		}
+
+		s.active.Store(&remoteActiveSampler{sampler: s.perOperation})
+
+		return nil
+	}
+
+	switch strategy.StrategyType {
+	case samplingStrategyProbabilistic:
+		if strategy.ProbabilisticSampling == nil {
+			return fmt.Errorf("ttrace: remote sampling strategy: missing probabilisticSampling")
+		}
+
+		err := s.controller.Update(strategy.ProbabilisticSampling.SamplingRate, -1)
+		if err != nil {
+			return err
+		}
+	case samplingStrategyRateLimiting:
+		if strategy.RateLimitingSampling == nil {
+			return fmt.Errorf("ttrace: remote sampling strategy: missing rateLimitingSampling")
+		}
+
+		err := s.controller.Update(-1, strategy.RateLimitingSampling.MaxTracesPerSecond)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("ttrace: remote sampling strategy: unsupported strategy type %q", strategy.StrategyType)
+	}
+
+	s.active.Store(&remoteActiveSampler{sampler: s.controller})
+
+	return nil
+}
+
+// Strategy type names used by the Jaeger sampling API.
+const (
+	samplingStrategyProbabilistic = "PROBABILISTIC"
+	samplingStrategyRateLimiting  = "RATE_LIMITING"
+)
+
+// samplingStrategyType accepts the strategy type either as its name or as the numeric enum value
+// returned by older Jaeger agents (0 probabilistic, 1 rate limiting).
+type samplingStrategyType string
+
+// UnmarshalJSON decodes a strategy name or enum number.
+func (t *samplingStrategyType) UnmarshalJSON(data []byte) error {
+	var name string
+
+	err := json.Unmarshal(data, &name)
+	if err == nil {
+		*t = samplingStrategyType(strings.ToUpper(name))
+
+		return nil
+	}
+
+	var number int
+
+	err = json.Unmarshal(data, &number)
+	if err != nil {
+		return fmt.Errorf("ttrace: invalid strategyType %s", data)
+	}
+
+	switch number {
+	case 0:
+		*t = samplingStrategyProbabilistic
+	case 1:
+		*t = samplingStrategyRateLimiting
+	default:
+		*t = samplingStrategyType(strconv.Itoa(number))
+	}
+
+	return nil
+}
+
+// samplingStrategyResponse mirrors the JSON form of Jaeger's SamplingStrategyResponse.
+type samplingStrategyResponse struct {
+	StrategyType          samplingStrategyType         `json:"strategyType"`
+	ProbabilisticSampling *probabilisticSamplingConfig `json:"probabilisticSampling"`
+	RateLimitingSampling  *rateLimitingSamplingConfig  `json:"rateLimitingSampling"`
+	OperationSampling     *operationSamplingConfig     `json:"operationSampling"`
+}
+
+// probabilisticSamplingConfig mirrors Jaeger's ProbabilisticSamplingStrategy.
+type probabilisticSamplingConfig struct {
+	SamplingRate float64 `json:"samplingRate"`
+}
+
+// rateLimitingSamplingConfig mirrors Jaeger's RateLimitingSamplingStrategy.
+type rateLimitingSamplingConfig struct {
+	MaxTracesPerSecond float64 `json:"maxTracesPerSecond"`
+}
+
+// operationSamplingConfig mirrors Jaeger's PerOperationSamplingStrategies.
+type operationSamplingConfig struct {
+	DefaultSamplingProbability       float64                   `json:"defaultSamplingProbability"`
+	DefaultLowerBoundTracesPerSecond float64                   `json:"defaultLowerBoundTracesPerSecond"`
+	DefaultUpperBoundTracesPerSecond float64                   `json:"defaultUpperBoundTracesPerSecond"`
+	PerOperationStrategies           []operationStrategyConfig `json:"perOperationStrategies"`
+}
+
+// strategy converts samplingRate and the shared bounds into an [OperationStrategy].
+func (c *operationSamplingConfig) strategy(samplingRate float64) OperationStrategy {
+	maxTracesPerSecond := -1.0
+	if c.DefaultUpperBoundTracesPerSecond > 0 {
+		maxTracesPerSecond = c.DefaultUpperBoundTracesPerSecond
+	}
+
+	return OperationStrategy{
+		SamplingFraction:          samplingRate,
+		MaxTracesPerSecond:        maxTracesPerSecond,
+		LowerBoundTracesPerSecond: c.DefaultLowerBoundTracesPerSecond,
+	}
+}
+
+// operationStrategyConfig mirrors Jaeger's OperationSamplingStrategy.
+type operationStrategyConfig struct {
+	Operation             string                       `json:"operation"`
+	ProbabilisticSampling *probabilisticSamplingConfig `json:"probabilisticSampling"`
+}
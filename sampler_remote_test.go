@@ -0,0 +1,365 @@
+package ttrace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// strategyServer is an httptest stand-in for a Jaeger sampling strategy endpoint. It serves the
+// configured status and body and records the requested service names.
+type strategyServer struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	status   int
+	body     string
+	services []string
+}
+
+// newStrategyServer starts a strategyServer answering with status and body. It is closed when the
+// test ends.
+func newStrategyServer(t *testing.T, status int, body string) *strategyServer {
+	t.Helper()
+
+	s := &strategyServer{status: status, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// serveHTTP records the service query parameter and writes the configured response.
+func (s *strategyServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.services = append(s.services, r.URL.Query().Get("service"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(s.status)
+	_, _ = w.Write([]byte(s.body))
+}
+
+// respond replaces the response served to subsequent requests.
+func (s *strategyServer) respond(status int, body string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.status = status
+	s.body = body
+}
+
+// requestedServices returns the service names of all requests received so far.
+func (s *strategyServer) requestedServices() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string(nil), s.services...)
+}
+
+// newTestRemoteSampler returns a [RemoteSampler] for server whose fallback never samples.
+func newTestRemoteSampler(t *testing.T, server *strategyServer, refreshInterval time.Duration) *RemoteSampler {
+	t.Helper()
+
+	sampler, err := NewRemoteSampler(RemoteSamplerConfig{
+		URL:             server.URL + "/sampling",
+		ServiceName:     "checkout",
+		RefreshInterval: refreshInterval,
+		Fallback:        trace.NeverSample(),
+	})
+	if err != nil {
+		t.Fatalf("NewRemoteSampler: %v", err)
+	}
+
+	return sampler
+}
+
+func TestRemoteSamplerAppliesStrategies(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		active string
+	}{
+		{
+			name:   "probabilistic",
+			body:   `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":0.25}}`,
+			active: "SamplerController(samplingFraction=0.25,maxTracesPerSecond=-1)",
+		},
+		{
+			name:   "probabilistic enum",
+			body:   `{"strategyType":0,"probabilisticSampling":{"samplingRate":0.5}}`,
+			active: "SamplerController(samplingFraction=0.5,maxTracesPerSecond=-1)",
+		},
+		{
+			name:   "rate limiting",
+			body:   `{"strategyType":"rate_limiting","rateLimitingSampling":{"maxTracesPerSecond":7}}`,
+			active: "SamplerController(samplingFraction=-1,maxTracesPerSecond=7)",
+		},
+		{
+			name:   "rate limiting enum",
+			body:   `{"strategyType":1,"rateLimitingSampling":{"maxTracesPerSecond":3}}`,
+			active: "SamplerController(samplingFraction=-1,maxTracesPerSecond=3)",
+		},
+		{
+			name: "per operation",
+			body: `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1},
+				"operationSampling":{"defaultSamplingProbability":0.1,"defaultLowerBoundTracesPerSecond":0.5,
+				"defaultUpperBoundTracesPerSecond":20,"perOperationStrategies":[
+				{"operation":"GET /health","probabilisticSampling":{"samplingRate":0}}]}}`,
+			active: "PerOperationSampler(default={SamplingFraction:0.1 MaxTracesPerSecond:20 LowerBoundTracesPerSecond:0.5},operations=1,",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStrategyServer(t, http.StatusOK, tt.body)
+			sampler := newTestRemoteSampler(t, server, time.Minute)
+
+			err := sampler.Refresh(context.Background())
+			if err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+
+			description := sampler.Description()
+			if !strings.Contains(description, "active="+tt.active) {
+				t.Errorf("Description() = %q, want active sampler %q", description, tt.active)
+			}
+
+			services := server.requestedServices()
+			if len(services) != 1 || services[0] != "checkout" {
+				t.Errorf("requested services = %q, want [checkout]", services)
+			}
+		})
+	}
+}
+
+func TestRemoteSamplerKeepsLastStrategy(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "server error", status: http.StatusInternalServerError, body: `{}`},
+		{name: "malformed JSON", status: http.StatusOK, body: `{"strategyType":`},
+		{name: "unsupported type", status: http.StatusOK, body: `{"strategyType":"LOWER_BOUND"}`},
+		{name: "missing probabilistic config", status: http.StatusOK, body: `{"strategyType":"PROBABILISTIC"}`},
+		{name: "invalid rate", status: http.StatusOK, body: `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":-3}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStrategyServer(t, http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1}}`)
+			sampler := newTestRemoteSampler(t, server, time.Minute)
+
+			err := sampler.Refresh(context.Background())
+			if err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+
+			server.respond(tt.status, tt.body)
+
+			err = sampler.Refresh(context.Background())
+			if err == nil {
+				t.Fatal("Refresh with an unusable response succeeded, want error")
+			}
+
+			if !strings.Contains(sampler.Description(), "samplingFraction=1") {
+				t.Errorf("Description() = %q, want the last strategy active", sampler.Description())
+			}
+
+			got := countSampled(sampler, "op", 10)
+			if got != 10 {
+				t.Errorf("last strategy sampled %d of 10, want 10", got)
+			}
+		})
+	}
+}
+
+func TestRemoteSamplerFallsBackWhenStale(t *testing.T) {
+	server := newStrategyServer(t, http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1}}`)
+	clock := newFakeClock()
+
+	sampler, err := newRemoteSampler(RemoteSamplerConfig{
+		URL:          server.URL + "/sampling",
+		ServiceName:  "checkout",
+		Fallback:     trace.NeverSample(),
+		MaxStaleness: time.Minute,
+	}, clock.Now)
+	if err != nil {
+		t.Fatalf("newRemoteSampler: %v", err)
+	}
+
+	err = sampler.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	server.respond(http.StatusServiceUnavailable, ``)
+
+	tests := []struct {
+		advance time.Duration
+		active  string
+	}{
+		{advance: 30 * time.Second, active: "samplingFraction=1"},
+		{advance: 30 * time.Second, active: "samplingFraction=1"},
+		{advance: time.Second, active: "active=AlwaysOffSampler"},
+	}
+
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+
+		err = sampler.Refresh(context.Background())
+		if err == nil {
+			t.Fatal("Refresh with an unavailable server succeeded, want error")
+		}
+
+		if !strings.Contains(sampler.Description(), tt.active) {
+			t.Errorf("after %v more: Description() = %q, want it to contain %q", tt.advance, sampler.Description(), tt.active)
+		}
+	}
+}
+
+func TestNewRemoteSamplerRejectsNegativeStaleness(t *testing.T) {
+	_, err := NewRemoteSampler(RemoteSamplerConfig{URL: "http://localhost/sampling", MaxStaleness: -time.Second})
+	if err == nil {
+		t.Fatal("NewRemoteSampler with a negative max staleness succeeded, want error")
+	}
+}
+
+func TestRemoteSamplerCountsStrategyDecisions(t *testing.T) {
+	server := newStrategyServer(t, http.StatusOK, `{"strategyType":"PROBABILISTIC","operationSampling":{`+
+		`"defaultSamplingProbability":0,"perOperationStrategies":[`+
+		`{"operation":"always","probabilisticSampling":{"samplingRate":1}}]}}`)
+	sampler := newTestRemoteSampler(t, server, time.Minute)
+
+	err := sampler.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	countSampled(sampler, "always", 3)
+	countSampled(sampler, "never", 2)
+
+	server.respond(http.StatusOK, `{"strategyType":"RATE_LIMITING","rateLimitingSampling":{"maxTracesPerSecond":1}}`)
+
+	err = sampler.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	countSampled(sampler, "op", 4)
+
+	got, ok := SamplerStats(sampler)
+	want := SamplingStats{Sampled: 4, DroppedByRatio: 2, DroppedByRateLimit: 3}
+
+	if !ok || got != want {
+		t.Errorf("SamplerStats() = %+v, %t; want %+v, true", got, ok, want)
+	}
+}
+
+func TestRemoteSamplerFallsBackWhenUnreachable(t *testing.T) {
+	server := newStrategyServer(t, http.StatusOK, `{}`)
+	sampler := newTestRemoteSampler(t, server, time.Minute)
+	server.Close()
+
+	err := sampler.Refresh(context.Background())
+	if err == nil {
+		t.Fatal("Refresh against a closed server succeeded, want error")
+	}
+
+	if !strings.Contains(sampler.Description(), "active=AlwaysOffSampler") {
+		t.Errorf("Description() = %q, want the fallback sampler active", sampler.Description())
+	}
+}
+
+func TestRemoteSamplerPollsUntilStopped(t *testing.T) {
+	server := newStrategyServer(t, http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":0.5}}`)
+	sampler := newTestRemoteSampler(t, server, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sampler.Start(ctx)
+
+	waitForDescription(t, sampler, "samplingFraction=0.5")
+
+	server.respond(http.StatusOK, `{"strategyType":"RATE_LIMITING","rateLimitingSampling":{"maxTracesPerSecond":2}}`)
+	waitForDescription(t, sampler, "maxTracesPerSecond=2")
+
+	cancel()
+
+	// Let an in-flight poll finish before counting.
+	time.Sleep(50 * time.Millisecond)
+
+	polls := len(server.requestedServices())
+
+	time.Sleep(50 * time.Millisecond)
+
+	got := len(server.requestedServices())
+	if got != polls {
+		t.Errorf("sampler polled %d times after its context was cancelled", got-polls)
+	}
+}
+
+// waitForDescription waits up to five seconds for the description of sampler to contain want.
+func waitForDescription(t *testing.T, sampler *RemoteSampler, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !strings.Contains(sampler.Description(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Description() = %q, want it to contain %q", sampler.Description(), want)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandleSamplingStatsCountsRemoteStrategies(t *testing.T) {
+	server := newStrategyServer(t, http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1}}`)
+
+	handle, err := New(context.Background(),
+		WithExporter(tracetest.NewInMemoryExporter()),
+		WithSampling(0, -1),
+		WithRemoteSampling(server.URL+"/sampling", time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	tracer := handle.TracerProvider().Tracer("test")
+	deadline := time.Now().Add(5 * time.Second)
+	started := 0
+
+	// The fallback drops every span until the first strategy arrives.
+	for {
+		_, span := tracer.Start(context.Background(), "op")
+		span.End()
+		started++
+
+		if span.SpanContext().IsSampled() {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("no span was sampled after the remote strategy arrived")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	got, ok := handle.SamplingStats()
+	want := SamplingStats{Sampled: 1, DroppedByRatio: uint64(started - 1)}
+
+	if !ok || got != want {
+		t.Errorf("SamplingStats() = %+v, %t; want %+v, true", got, ok, want)
+	}
+}
@@ -0,0 +1,324 @@
+package ttrace
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleSamplerType names the sampler a [SamplingRule] assigns to matching root spans.
+type RuleSamplerType string
+
+// Sampler types accepted in [RuleSamplerSpec.Type].
+const (
+	RuleSamplerAlways    RuleSamplerType = "always"
+	RuleSamplerNever     RuleSamplerType = "never"
+	RuleSamplerRatio     RuleSamplerType = "ratio"
+	RuleSamplerRateLimit RuleSamplerType = "rate_limit"
+)
+
+// RuleSamplerSpec describes the sampler of a [SamplingRule] or the rule set default.
+type RuleSamplerSpec struct {
+	// Type selects the sampler.
+	Type RuleSamplerType `json:"type" yaml:"type"`
+	// Ratio is the trace ID ratio used by [RuleSamplerRatio].
+	Ratio float64 `json:"ratio,omitempty" yaml:"ratio,omitempty"`
+	// MaxTracesPerSecond is the cap used by [RuleSamplerRateLimit].
+	MaxTracesPerSecond float64 `json:"maxTracesPerSecond,omitempty" yaml:"maxTracesPerSecond,omitempty"`
+}
+
+// newSampler builds the root sampler described by s.
+func (s RuleSamplerSpec) newSampler() (trace.Sampler, error) {
+	switch s.Type {
+	case RuleSamplerAlways:
+		return trace.AlwaysSample(), nil
+	case RuleSamplerNever:
+		return trace.NeverSample(), nil
+	case RuleSamplerRatio:
+		if s.Ratio < 0 || s.Ratio > 1 {
+			return nil, fmt.Errorf("ttrace: invalid sampling rule ratio: must be between 0 and 1 (got %v)", s.Ratio)
+		}
+
+		return trace.TraceIDRatioBased(s.Ratio), nil
+	case RuleSamplerRateLimit:
+		if s.MaxTracesPerSecond < 0 {
+			return nil, fmt.Errorf("ttrace: invalid sampling rule max traces per second: must be >= 0 (got %v)", s.MaxTracesPerSecond)
+		}
+
+		return newRateLimitingRootSampler(s.MaxTracesPerSecond), nil
+	default:
+		return nil, fmt.Errorf("ttrace: unsupported sampling rule sampler type %q", s.Type)
+	}
+}
+
+// SamplingRule assigns a sampler to root spans that match all of its non-empty conditions.
+type SamplingRule struct {
+	// Name identifies the rule in [RuleSampler.Description] and error messages.
+	Name string `json:"name" yaml:"name"`
+	// SpanName is a glob matched against the span name, where '*' matches any sequence of
+	// characters and '?' matches a single character.
+	SpanName string `json:"spanName,omitempty" yaml:"spanName,omitempty"`
+	// SpanNameRegex is a regular expression matched against the span name.
+	SpanNameRegex string `json:"spanNameRegex,omitempty" yaml:"spanNameRegex,omitempty"`
+	// SpanKind is the span kind: "internal", "server", "client", "producer", or "consumer".
+	SpanKind string `json:"spanKind,omitempty" yaml:"spanKind,omitempty"`
+	// Attributes maps start attribute keys, such as http.route, http.request.method, or url.path, to
+	// globs matched against the attribute value. A span without the attribute does not match.
+	Attributes map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// Sampler decides for matching spans.
+	Sampler RuleSamplerSpec `json:"sampler" yaml:"sampler"`
+}
+
+// SamplingRules is an ordered rule set for [NewRuleSampler], typically loaded with
+// [LoadSamplingRules].
+type SamplingRules struct {
+	// Rules are evaluated in order; the first match decides.
+	Rules []SamplingRule `json:"rules" yaml:"rules"`
+	// Default decides for spans that match no rule. When nil, the fallback sampler passed to
+	// [NewRuleSampler] decides.
+	Default *RuleSamplerSpec `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// LoadSamplingRules reads a rule set from path. Files ending in ".json" are decoded as JSON and all
+// other files as YAML.
+func LoadSamplingRules(path string) (SamplingRules, error) {
+	var rules SamplingRules
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("ttrace: read sampling rules: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &rules)
+	} else {
+		err = yaml.Unmarshal(data, &rules)
+	}
+
+	if err != nil {
+		return rules, fmt.Errorf("ttrace: decode sampling rules %s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// RuleSampler is a root [trace.Sampler] that evaluates [SamplingRule] values in order and delegates
+// to the sampler of the first matching rule, or to the default. Wrap it with [trace.ParentBased] so
+// that child spans inherit their parent decision.
+type RuleSampler struct {
+	rules    []*compiledSamplingRule
+	fallback trace.Sampler
+
+	// defaultRule reports whether fallback is the rule set default rather than the fallback
+	// sampler passed to [NewRuleSampler].
+	defaultRule bool
+	// counters records decisions taken by rules and the rule set default. [New] shares the counters
+	// of the default sampler chain so that they include rule decisions.
+	counters *samplingCounters
+}
+
+// compiledSamplingRule is a [SamplingRule] with its patterns and sampler prepared.
+type compiledSamplingRule struct {
+	spanName    *regexp.Regexp
+	spanKind    oteltrace.SpanKind
+	attributes  map[attribute.Key]*regexp.Regexp
+	sampler     trace.Sampler
+	description string
+}
+
+// NewRuleSampler returns a [RuleSampler] for rules. Spans that match no rule are sampled by the
+// rules default when set and by fallback otherwise. It returns an error when a pattern, span kind,
+// or sampler is invalid, or when neither a default nor fallback is given.
+func NewRuleSampler(rules SamplingRules, fallback trace.Sampler) (*RuleSampler, error) {
+	if rules.Default != nil {
+		sampler, err := rules.Default.newSampler()
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: default sampling rule: %w", err)
+		}
+
+		fallback = sampler
+	}
+
+	if fallback == nil {
+		return nil, fmt.Errorf("ttrace: missing default sampler for sampling rules")
+	}
+
+	s := &RuleSampler{
+		rules:       make([]*compiledSamplingRule, 0, len(rules.Rules)),
+		fallback:    fallback,
+		defaultRule: rules.Default != nil,
+	}
+
+	for i, rule := range rules.Rules {
+		compiled, err := compileSamplingRule(rule)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+
+			return nil, fmt.Errorf("ttrace: sampling rule %s: %w", name, err)
+		}
+
+		s.rules = append(s.rules, compiled)
+	}
+
+	return s, nil
+}
+
+// ShouldSample delegates to the sampler of the first rule matching p, or to the default.
+func (s *RuleSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	for _, rule := range s.rules {
+		if rule.match(p) {
+			return s.record(p, rule.sampler.ShouldSample(p))
+		}
+	}
+
+	if s.defaultRule {
+		return s.record(p, s.fallback.ShouldSample(p))
+	}
+
+	// A fallback sampler such as [SamplerController] counts its own decisions.
+	return s.fallback.ShouldSample(p)
+}
+
+// record counts result as a rule decision and returns it.
+func (s *RuleSampler) record(p trace.SamplingParameters, result trace.SamplingResult) trace.SamplingResult {
+	if result.Decision == trace.RecordAndSample {
+		s.counters.record(p.ParentContext, SamplingDecisionSampled)
+	} else {
+		s.counters.record(p.ParentContext, SamplingDecisionDroppedByRule)
+	}
+
+	return result
+}
+
+// Description lists the rules in evaluation order followed by the default.
+func (s *RuleSampler) Description() string {
+	descriptions := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		descriptions = append(descriptions, rule.description)
+	}
+
+	return fmt.Sprintf("RuleSampler(rules=[%s],default=%s)", strings.Join(descriptions, ";"), s.fallback.Description())
+}
+
+// compileSamplingRule validates rule and prepares its patterns and sampler.
+func compileSamplingRule(rule SamplingRule) (*compiledSamplingRule, error) {
+	if rule.SpanName != "" && rule.SpanNameRegex != "" {
+		return nil, fmt.Errorf("spanName and spanNameRegex are mutually exclusive")
+	}
+
+	compiled := &compiledSamplingRule{}
+
+	var conditions []string
+
+	var err error
+
+	switch {
+	case rule.SpanName != "":
+		compiled.spanName = globRegexp(rule.SpanName)
+		conditions = append(conditions, "spanName="+rule.SpanName)
+	case rule.SpanNameRegex != "":
+		compiled.spanName, err = regexp.Compile(rule.SpanNameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spanNameRegex: %w", err)
+		}
+
+		conditions = append(conditions, "spanNameRegex="+rule.SpanNameRegex)
+	}
+
+	if rule.SpanKind != "" {
+		compiled.spanKind, err = parseSpanKind(rule.SpanKind)
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, "spanKind="+compiled.spanKind.String())
+	}
+
+	if len(rule.Attributes) > 0 {
+		compiled.attributes = make(map[attribute.Key]*regexp.Regexp, len(rule.Attributes))
+
+		for _, key := range slices.Sorted(maps.Keys(rule.Attributes)) {
+			compiled.attributes[attribute.Key(key)] = globRegexp(rule.Attributes[key])
+			conditions = append(conditions, key+"="+rule.Attributes[key])
+		}
+	}
+
+	compiled.sampler, err = rule.Sampler.newSampler()
+	if err != nil {
+		return nil, err
+	}
+
+	compiled.description = fmt.Sprintf("%s{%s}->%s", rule.Name, strings.Join(conditions, ","), compiled.sampler.Description())
+
+	return compiled, nil
+}
+
+// match reports whether p satisfies every condition of the rule.
+func (r *compiledSamplingRule) match(p trace.SamplingParameters) bool {
+	if r.spanName != nil && !r.spanName.MatchString(p.Name) {
+		return false
+	}
+
+	if r.spanKind != oteltrace.SpanKindUnspecified && r.spanKind != p.Kind {
+		return false
+	}
+
+	for key, pattern := range r.attributes {
+		matched := false
+
+		for _, attr := range p.Attributes {
+			if attr.Key == key {
+				matched = pattern.MatchString(attr.Value.Emit())
+
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// globRegexp compiles a glob in which '*' matches any sequence of characters, including '/', and
+// '?' matches a single character into an anchored regular expression.
+func globRegexp(glob string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// parseSpanKind parses a span kind name.
+func parseSpanKind(kind string) (oteltrace.SpanKind, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "internal":
+		return oteltrace.SpanKindInternal, nil
+	case "server":
+		return oteltrace.SpanKindServer, nil
+	case "client":
+		return oteltrace.SpanKindClient, nil
+	case "producer":
+		return oteltrace.SpanKindProducer, nil
+	case "consumer":
+		return oteltrace.SpanKindConsumer, nil
+	default:
+		return oteltrace.SpanKindUnspecified, fmt.Errorf("invalid spanKind %q", kind)
+	}
+}
@@ -0,0 +1,139 @@
+package ttrace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHandleSamplingStatsCountRuleDecisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules SamplingRules
+		want  SamplingStats
+	}{
+		{
+			name: "controller decides unmatched spans",
+			rules: SamplingRules{
+				Rules: []SamplingRule{
+					{Name: "health", SpanName: "GET /health*", Sampler: RuleSamplerSpec{Type: RuleSamplerNever}},
+					{Name: "checkout", SpanName: "checkout", Sampler: RuleSamplerSpec{Type: RuleSamplerAlways}},
+				},
+			},
+			want: SamplingStats{Sampled: 3, DroppedByRule: 3, InheritedFromParent: 1},
+		},
+		{
+			name: "rule set default decides unmatched spans",
+			rules: SamplingRules{
+				Rules: []SamplingRule{
+					{Name: "health", SpanName: "GET /health*", Sampler: RuleSamplerSpec{Type: RuleSamplerNever}},
+					{Name: "checkout", SpanName: "checkout", Sampler: RuleSamplerSpec{Type: RuleSamplerAlways}},
+				},
+				Default: &RuleSamplerSpec{Type: RuleSamplerNever},
+			},
+			want: SamplingStats{Sampled: 2, DroppedByRule: 4, InheritedFromParent: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle, err := New(context.Background(),
+				WithExporter(tracetest.NewInMemoryExporter()),
+				WithSampling(-1, -1),
+				WithSamplingRules(tt.rules),
+			)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer handle.Shutdown(context.Background())
+
+			tracer := handle.TracerProvider().Tracer("test")
+
+			for range 3 {
+				_, span := tracer.Start(context.Background(), "GET /healthz")
+				span.End()
+			}
+
+			_, span := tracer.Start(context.Background(), "checkout")
+			span.End()
+
+			ctx, span := tracer.Start(context.Background(), "checkout")
+			_, child := tracer.Start(ctx, "charge")
+			child.End()
+			span.End()
+
+			_, span = tracer.Start(context.Background(), "other")
+			span.End()
+
+			stats, ok := handle.SamplingStats()
+			if !ok {
+				t.Fatal("SamplingStats reported no stats")
+			}
+
+			if stats != tt.want {
+				t.Errorf("SamplingStats() = %+v, want %+v", stats, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleSamplingStatsWithCustomSampler(t *testing.T) {
+	sampler, err := NewConsistentSampler(1)
+	if err != nil {
+		t.Fatalf("NewConsistentSampler: %v", err)
+	}
+
+	handle, err := New(context.Background(), WithExporter(tracetest.NewInMemoryExporter()), WithSampler(sampler))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	_, ok := handle.SamplingStats()
+	if ok {
+		t.Error("SamplingStats reported stats for a custom sampler")
+	}
+}
+
+func TestLoadSamplingRules(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+	}{
+		{
+			file: "rules.yaml",
+			data: "default: {type: ratio, ratio: 0.5}\nrules:\n  - name: health\n    spanName: \"GET /health*\"\n    sampler: {type: never}\n",
+		},
+		{
+			file: "rules.json",
+			data: `{"default":{"type":"ratio","ratio":0.5},"rules":[{"name":"health","spanName":"GET /health*","sampler":{"type":"never"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+
+			err := os.WriteFile(path, []byte(tt.data), 0o600)
+			if err != nil {
+				t.Fatalf("write rules: %v", err)
+			}
+
+			rules, err := LoadSamplingRules(path)
+			if err != nil {
+				t.Fatalf("LoadSamplingRules: %v", err)
+			}
+
+			if len(rules.Rules) != 1 || rules.Rules[0].Name != "health" || rules.Rules[0].Sampler.Type != RuleSamplerNever {
+				t.Errorf("rules = %+v, want the health rule", rules.Rules)
+			}
+
+			if rules.Default == nil || rules.Default.Type != RuleSamplerRatio || rules.Default.Ratio != 0.5 {
+				t.Errorf("default = %+v, want ratio 0.5", rules.Default)
+			}
+		})
+	}
+}
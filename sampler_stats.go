@@ -0,0 +1,166 @@
+package ttrace
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SamplingDecisionsMetric is the name of the counter, recorded through the global MeterProvider
+// under instrumentation scope [TracerName], that counts sampling decisions by sampler and
+// decision. Its attributes are [SamplerNameKey] and [SamplingDecisionKey].
+const (
+	SamplingDecisionsMetric = "ttrace.sampling.decisions"
+)
+
+// Attributes of [SamplingDecisionsMetric].
+const (
+	// SamplerNameKey identifies the sampler, for example "SamplerController".
+	SamplerNameKey = attribute.Key("ttrace.sampler")
+	// SamplingDecisionKey is one of the SamplingDecision* values.
+	SamplingDecisionKey = attribute.Key("ttrace.sampling.decision")
+)
+
+// Values of [SamplingDecisionKey].
+const (
+	SamplingDecisionSampled            = "sampled"
+	SamplingDecisionDroppedByRatio     = "dropped_by_ratio"
+	SamplingDecisionDroppedByRateLimit = "dropped_by_rate_limit"
+	SamplingDecisionDroppedByRule      = "dropped_by_rule"
+	SamplingDecisionInherited          = "inherited_from_parent"
+)
+
+// SamplingStats holds cumulative decision counts of a sampler.
+type SamplingStats struct {
+	// Sampled counts root spans that passed every stage or were sampled by a sampling rule.
+	Sampled uint64
+	// DroppedByRatio counts root spans rejected by the trace ID ratio stage.
+	DroppedByRatio uint64
+	// DroppedByRateLimit counts root spans that passed the ratio stage but were rejected by the
+	// per-second cap.
+	DroppedByRateLimit uint64
+	// DroppedByRule counts root spans rejected by a [SamplingRule] or the rule set default.
+	DroppedByRule uint64
+	// InheritedFromParent counts spans whose decision was taken from a valid parent span context.
+	InheritedFromParent uint64
+}
+
+// SamplerStats returns the decision counts of sampler when it reports them, as do
+// [SamplerController], [AdaptiveSampler], [PerOperationSampler], [RemoteSampler], and the samplers
+// returned by [GuaranteedThroughputProbabilitySampler] and [RateLimitingSampler].
+func SamplerStats(sampler trace.Sampler) (SamplingStats, bool) {
+	statsSampler, ok := sampler.(interface{ Stats() SamplingStats })
+	if !ok {
+		return SamplingStats{}, false
+	}
+
+	return statsSampler.Stats(), true
+}
+
+// samplingCounters counts the decisions of one sampler in memory and through
+// [SamplingDecisionsMetric]. A nil *samplingCounters ignores records.
+type samplingCounters struct {
+	sampled            atomic.Uint64
+	droppedByRatio     atomic.Uint64
+	droppedByRateLimit atomic.Uint64
+	droppedByRule      atomic.Uint64
+	inherited          atomic.Uint64
+
+	counter    metric.Int64Counter
+	attributes map[string]metric.AddOption
+}
+
+// newSamplingCounters returns counters for the sampler named samplerName.
+func newSamplingCounters(samplerName string) *samplingCounters {
+	c := &samplingCounters{
+		attributes: make(map[string]metric.AddOption),
+	}
+
+	counter, err := otel.Meter(TracerName).Int64Counter(SamplingDecisionsMetric,
+		metric.WithDescription("Sampling decisions by sampler and decision."),
+		metric.WithUnit("{span}"),
+	)
+	if err == nil {
+		c.counter = counter
+	}
+
+	for _, decision := range []string{SamplingDecisionSampled, SamplingDecisionDroppedByRatio, SamplingDecisionDroppedByRateLimit, SamplingDecisionDroppedByRule, SamplingDecisionInherited} {
+		c.attributes[decision] = metric.WithAttributeSet(attribute.NewSet(SamplerNameKey.String(samplerName), SamplingDecisionKey.String(decision)))
+	}
+
+	return c
+}
+
+// record counts one decision.
+func (c *samplingCounters) record(ctx context.Context, decision string) {
+	if c == nil {
+		return
+	}
+
+	switch decision {
+	case SamplingDecisionSampled:
+		c.sampled.Add(1)
+	case SamplingDecisionDroppedByRatio:
+		c.droppedByRatio.Add(1)
+	case SamplingDecisionDroppedByRateLimit:
+		c.droppedByRateLimit.Add(1)
+	case SamplingDecisionDroppedByRule:
+		c.droppedByRule.Add(1)
+	case SamplingDecisionInherited:
+		c.inherited.Add(1)
+	}
+
+	if c.counter != nil {
+		c.counter.Add(ctx, 1, c.attributes[decision])
+	}
+}
+
+// stats returns a snapshot of the counts.
+func (c *samplingCounters) stats() SamplingStats {
+	return SamplingStats{
+		Sampled:             c.sampled.Load(),
+		DroppedByRatio:      c.droppedByRatio.Load(),
+		DroppedByRateLimit:  c.droppedByRateLimit.Load(),
+		DroppedByRule:       c.droppedByRule.Load(),
+		InheritedFromParent: c.inherited.Load(),
+	}
+}
+
+// statsParentBased is [trace.ParentBased] over root that counts inherited decisions in counters,
+// which root shares for its own stages.
+type statsParentBased struct {
+	parentBased trace.Sampler
+	counters    *samplingCounters
+}
+
+// newStatsParentBased returns [trace.ParentBased] for root, counting inherited decisions.
+func newStatsParentBased(root trace.Sampler, counters *samplingCounters) trace.Sampler {
+	return statsParentBased{
+		parentBased: trace.ParentBased(root),
+		counters:    counters,
+	}
+}
+
+// ShouldSample counts spans with a valid parent and delegates to the parent-based sampler.
+func (s statsParentBased) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	if oteltrace.SpanContextFromContext(p.ParentContext).IsValid() {
+		s.counters.record(p.ParentContext, SamplingDecisionInherited)
+	}
+
+	return s.parentBased.ShouldSample(p)
+}
+
+// Description returns the parent-based sampler description.
+func (s statsParentBased) Description() string {
+	return s.parentBased.Description()
+}
+
+// Stats returns the decision counts of the sampler chain.
+func (s statsParentBased) Stats() SamplingStats {
+	return s.counters.stats()
+}
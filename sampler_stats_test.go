@@ -0,0 +1,178 @@
+package ttrace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// decisionKey identifies one data point of [SamplingDecisionsMetric].
+type decisionKey struct {
+	sampler  string
+	decision string
+}
+
+// installManualReader installs a global MeterProvider backed by a manual reader for the duration of
+// t. Sampling counters created afterwards record into it.
+func installManualReader(t *testing.T) *sdkmetric.ManualReader {
+	t.Helper()
+
+	previous := otel.GetMeterProvider()
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	t.Cleanup(func() {
+		otel.SetMeterProvider(previous)
+	})
+
+	return reader
+}
+
+// collectDecisions returns the [SamplingDecisionsMetric] values collected by reader.
+func collectDecisions(t *testing.T, reader *sdkmetric.ManualReader) map[decisionKey]int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+
+	err := reader.Collect(context.Background(), &rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions := make(map[decisionKey]int64)
+
+	for _, scopeMetrics := range rm.ScopeMetrics {
+		if scopeMetrics.Scope.Name != TracerName {
+			continue
+		}
+
+		for _, m := range scopeMetrics.Metrics {
+			if m.Name != SamplingDecisionsMetric {
+				continue
+			}
+
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("got %s data %T, want metricdata.Sum[int64]", m.Name, m.Data)
+			}
+
+			for _, point := range sum.DataPoints {
+				sampler, _ := point.Attributes.Value(SamplerNameKey)
+				decision, _ := point.Attributes.Value(SamplingDecisionKey)
+				decisions[decisionKey{sampler.AsString(), decision.AsString()}] = point.Value
+			}
+		}
+	}
+
+	return decisions
+}
+
+func TestSamplerControllerCountsDecisions(t *testing.T) {
+	reader := installManualReader(t)
+
+	controller, err := NewSamplerController(1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	countSampled(controller, "request", 10)
+
+	err = controller.Update(0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	countSampled(controller, "request", 4)
+
+	stats := controller.Stats()
+	if stats.Sampled != 5 || stats.DroppedByRateLimit != 5 || stats.DroppedByRatio != 4 {
+		t.Errorf("got stats %+v, want 5 sampled, 5 dropped by rate limit, 4 dropped by ratio", stats)
+	}
+
+	got := collectDecisions(t, reader)
+	want := map[decisionKey]int64{
+		{"SamplerController", SamplingDecisionSampled}:            int64(stats.Sampled),
+		{"SamplerController", SamplingDecisionDroppedByRateLimit}: int64(stats.DroppedByRateLimit),
+		{"SamplerController", SamplingDecisionDroppedByRatio}:     int64(stats.DroppedByRatio),
+	}
+	for key, val := range want {
+		if got[key] != val {
+			t.Errorf("got %s %v = %d, want %d", SamplingDecisionsMetric, key, got[key], val)
+		}
+	}
+}
+
+func TestHandleSamplingStatsCountsEveryStage(t *testing.T) {
+	reader := installManualReader(t)
+
+	handle, err := New(context.Background(),
+		WithExporter(tracetest.NewInMemoryExporter()),
+		WithSampling(-1, -1),
+		WithSamplingRules(SamplingRules{Rules: []SamplingRule{
+			{Name: "health", SpanName: "health", Sampler: RuleSamplerSpec{Type: RuleSamplerNever}},
+		}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	tracer := handle.TracerProvider().Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	for range 3 {
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+	}
+	root.End()
+
+	for range 2 {
+		_, health := tracer.Start(context.Background(), "health")
+		health.End()
+	}
+
+	stats, ok := handle.SamplingStats()
+	if !ok {
+		t.Fatal("got no sampling stats, want the controller counts")
+	}
+
+	if stats.Sampled != 1 || stats.InheritedFromParent != 3 || stats.DroppedByRule != 2 {
+		t.Errorf("got stats %+v, want 1 sampled, 3 inherited, and 2 dropped by rule", stats)
+	}
+
+	got := collectDecisions(t, reader)
+	want := map[decisionKey]int64{
+		{"SamplerController", SamplingDecisionSampled}:       1,
+		{"SamplerController", SamplingDecisionInherited}:     3,
+		{"SamplerController", SamplingDecisionDroppedByRule}: 2,
+	}
+	for key, val := range want {
+		if got[key] != val {
+			t.Errorf("got %s %v = %d, want %d", SamplingDecisionsMetric, key, got[key], val)
+		}
+	}
+}
+
+func TestSamplerStats(t *testing.T) {
+	controller, err := NewSamplerController(1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	countSampled(controller, "request", 2)
+
+	stats, ok := SamplerStats(controller)
+	if !ok || stats.Sampled != 2 {
+		t.Errorf("got stats %+v, %t, want 2 sampled", stats, ok)
+	}
+
+	_, ok = SamplerStats(trace.AlwaysSample())
+	if ok {
+		t.Error("got stats for a sampler without counts, want none")
+	}
+}
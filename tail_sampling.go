@@ -0,0 +1,452 @@
+package ttrace
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults applied by [NewTailSamplingProcessor] to zero-valued [TailSamplingConfig] fields.
+const (
+	defaultTailDecisionWait     = 10 * time.Second
+	defaultTailMaxTraces        = 10000
+	defaultTailMaxSpansPerTrace = 1000
+	defaultTailMaxPendingSpans  = 20000
+)
+
+// TailSamplingConfig configures a [TailSamplingProcessor]. A trace is kept when any of its local
+// spans was sampled by the head sampler, has error status, lasts at least LatencyThreshold, or
+// satisfies Predicate.
+type TailSamplingConfig struct {
+	// DecisionWait is how long spans of a trace are buffered, from the first ended span, before
+	// the trace is kept or dropped. Zero means ten seconds.
+	DecisionWait time.Duration
+	// LatencyThreshold keeps traces containing a span that lasted at least this long. Zero
+	// disables the latency policy.
+	LatencyThreshold time.Duration
+	// Predicate keeps traces containing a span for which it returns true, for example a span with
+	// a given attribute. Nil disables the predicate policy.
+	Predicate func(span sdktrace.ReadOnlySpan) bool
+	// MaxTraces caps the traces buffered at once. When the cap is reached, the oldest trace is
+	// decided early. Zero means 10000.
+	MaxTraces int
+	// MaxSpansPerTrace caps the spans buffered for one trace; further spans are dropped. Zero means
+	// 1000.
+	MaxSpansPerTrace int
+	// MaxPendingSpans caps the spans of kept traces that wait for the next export tick, such as
+	// spans ending after their trace was kept or traces decided early because MaxTraces was
+	// reached. Further spans are dropped. Zero means 20000.
+	MaxPendingSpans int
+}
+
+// TailSamplingStats holds cumulative [TailSamplingProcessor] counters.
+type TailSamplingStats struct {
+	// TracesKept counts traces exported after a decision.
+	TracesKept uint64
+	// TracesDropped counts traces discarded because no policy matched.
+	TracesDropped uint64
+	// TracesEvicted counts traces decided before their decision wait elapsed because MaxTraces was
+	// reached. They are also counted as kept or dropped.
+	TracesEvicted uint64
+	// SpansExported counts spans passed to the exporter, including spans that ended after their
+	// trace was kept.
+	SpansExported uint64
+	// SpansDropped counts spans discarded because their trace reached MaxSpansPerTrace, because
+	// MaxPendingSpans spans were already waiting for export, or because they ended after their
+	// trace was dropped.
+	SpansDropped uint64
+	// ExportErrors counts failed export calls.
+	ExportErrors uint64
+}
+
+// TailSamplingProcessor is a [sdktrace.SpanProcessor] that buffers ended spans per trace ID for a
+// decision window and exports the whole local trace when any span is an error, a latency
+// outlier, or matches a predicate. Traces the head sampler sampled are always kept. It exports to
+// its exporter directly, in place of a batch span processor, and sets the sampled flag on the span
+// context of every exported span.
+//
+// The head sampler must record the spans it does not sample for them to reach the processor;
+// [WithTailSampling] arranges this by turning Drop decisions into RecordOnly ones.
+type TailSamplingProcessor struct {
+	exporter sdktrace.SpanExporter
+	config   TailSamplingConfig
+
+	lock    sync.Mutex
+	traces  map[trace.TraceID]*list.Element
+	order   *list.List
+	decided map[trace.TraceID]*list.Element
+	// decidedOrder lists the decisions from least to most recently used, which is also the order
+	// of their expiry. It holds at most MaxTraces decisions.
+	decidedOrder *list.List
+	pending      [][]sdktrace.ReadOnlySpan
+	pendingSpans int
+
+	exportLock sync.Mutex
+
+	stop    chan struct{}
+	stopped chan struct{}
+	closed  atomic.Bool
+
+	tracesKept    atomic.Uint64
+	tracesDropped atomic.Uint64
+	tracesEvicted atomic.Uint64
+	spansExported atomic.Uint64
+	spansDropped  atomic.Uint64
+	exportErrors  atomic.Uint64
+}
+
+// tailTrace buffers the spans of one undecided trace.
+type tailTrace struct {
+	traceID  trace.TraceID
+	spans    []sdktrace.ReadOnlySpan
+	deadline time.Time
+	keep     bool
+}
+
+// tailDecision remembers the outcome for a decided trace so that late spans follow it.
+type tailDecision struct {
+	traceID trace.TraceID
+	keep    bool
+	expires time.Time
+}
+
+// NewTailSamplingProcessor returns a [TailSamplingProcessor] exporting kept traces to exporter and
+// starts its decision loop. It returns an error when a limit in config is negative.
+func NewTailSamplingProcessor(exporter sdktrace.SpanExporter, config TailSamplingConfig) (*TailSamplingProcessor, error) {
+	if exporter == nil {
+		return nil, fmt.Errorf("ttrace: missing exporter for tail sampling")
+	}
+
+	if config.DecisionWait < 0 || config.LatencyThreshold < 0 || config.MaxTraces < 0 || config.MaxSpansPerTrace < 0 || config.MaxPendingSpans < 0 {
+		return nil, fmt.Errorf("ttrace: invalid tail sampling configuration: values must be >= 0")
+	}
+
+	if config.DecisionWait == 0 {
+		config.DecisionWait = defaultTailDecisionWait
+	}
+
+	if config.MaxTraces == 0 {
+		config.MaxTraces = defaultTailMaxTraces
+	}
+
+	if config.MaxSpansPerTrace == 0 {
+		config.MaxSpansPerTrace = defaultTailMaxSpansPerTrace
+	}
+
+	if config.MaxPendingSpans == 0 {
+		config.MaxPendingSpans = defaultTailMaxPendingSpans
+	}
+
+	p := &TailSamplingProcessor{
+		exporter:     exporter,
+		config:       config,
+		traces:       make(map[trace.TraceID]*list.Element),
+		order:        list.New(),
+		decided:      make(map[trace.TraceID]*list.Element),
+		decidedOrder: list.New(),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+
+	go p.run(max(config.DecisionWait/10, 10*time.Millisecond))
+
+	return p, nil
+}
+
+// OnStart does nothing; spans are evaluated when they end.
+func (p *TailSamplingProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd buffers span with its trace, or handles it according to an earlier decision.
+func (p *TailSamplingProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	if p.closed.Load() {
+		return
+	}
+
+	traceID := span.SpanContext().TraceID()
+	matched := p.matches(span)
+	now := time.Now()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	decided, ok := p.decided[traceID]
+	if ok {
+		decision := decided.Value.(*tailDecision)
+		decision.expires = now.Add(p.config.DecisionWait)
+		p.decidedOrder.MoveToBack(decided)
+
+		if decision.keep {
+			p.enqueue([]sdktrace.ReadOnlySpan{span})
+		} else {
+			p.spansDropped.Add(1)
+		}
+
+		return
+	}
+
+	element, ok := p.traces[traceID]
+	if !ok {
+		if p.order.Len() >= p.config.MaxTraces {
+			p.tracesEvicted.Add(1)
+			p.enqueue(p.decide(p.order.Front(), now))
+		}
+
+		element = p.order.PushBack(&tailTrace{
+			traceID:  traceID,
+			deadline: now.Add(p.config.DecisionWait),
+		})
+		p.traces[traceID] = element
+	}
+
+	buffered := element.Value.(*tailTrace)
+	buffered.keep = buffered.keep || matched
+
+	if len(buffered.spans) >= p.config.MaxSpansPerTrace {
+		p.spansDropped.Add(1)
+
+		return
+	}
+
+	buffered.spans = append(buffered.spans, span)
+}
+
+// Shutdown decides and exports all buffered traces, stops the decision loop, and shuts down the
+// exporter.
+func (p *TailSamplingProcessor) Shutdown(ctx context.Context) error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+
+	close(p.stop)
+	<-p.stopped
+
+	err := p.flush(ctx, true)
+
+	return errors.Join(err, p.exporter.Shutdown(ctx))
+}
+
+// ForceFlush decides all buffered traces immediately, exports the kept ones, and flushes the
+// exporter when it supports flushing.
+func (p *TailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	if p.closed.Load() {
+		return nil
+	}
+
+	err := p.flush(ctx, true)
+
+	return errors.Join(err, forceFlushExporter(ctx, p.exporter))
+}
+
+// Stats returns the cumulative counters.
+func (p *TailSamplingProcessor) Stats() TailSamplingStats {
+	return TailSamplingStats{
+		TracesKept:    p.tracesKept.Load(),
+		TracesDropped: p.tracesDropped.Load(),
+		TracesEvicted: p.tracesEvicted.Load(),
+		SpansExported: p.spansExported.Load(),
+		SpansDropped:  p.spansDropped.Load(),
+		ExportErrors:  p.exportErrors.Load(),
+	}
+}
+
+// run decides expired traces every interval until the processor is shut down.
+func (p *TailSamplingProcessor) run(interval time.Duration) {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+
+		err := p.flush(ctx, false)
+		if err != nil {
+			log.Printf("ttrace: tail sampling export failed: %v", err)
+		}
+
+		cancel()
+	}
+}
+
+// flush decides the traces whose decision wait elapsed, or all traces when all is true, and
+// exports the kept spans.
+func (p *TailSamplingProcessor) flush(ctx context.Context, all bool) error {
+	now := time.Now()
+
+	p.lock.Lock()
+
+	pending := p.pending
+	p.pending = nil
+	p.pendingSpans = 0
+
+	// Traces decided here are exported right away, so they bypass MaxPendingSpans.
+	for element := p.order.Front(); element != nil; element = p.order.Front() {
+		if !all && element.Value.(*tailTrace).deadline.After(now) {
+			break
+		}
+
+		spans := p.decide(element, now)
+		if len(spans) > 0 {
+			pending = append(pending, spans)
+		}
+	}
+
+	for element := p.decidedOrder.Front(); element != nil; element = p.decidedOrder.Front() {
+		decision := element.Value.(*tailDecision)
+		if !decision.expires.Before(now) {
+			break
+		}
+
+		p.decidedOrder.Remove(element)
+		delete(p.decided, decision.traceID)
+	}
+
+	p.lock.Unlock()
+
+	return p.export(ctx, pending)
+}
+
+// decide removes the trace in element from the buffer, remembers the decision, and returns the
+// spans to export, or nil when the trace is dropped. When MaxTraces decisions are remembered, the
+// least recently used one is forgotten. p.lock must be held.
+func (p *TailSamplingProcessor) decide(element *list.Element, now time.Time) []sdktrace.ReadOnlySpan {
+	buffered := p.order.Remove(element).(*tailTrace)
+	delete(p.traces, buffered.traceID)
+
+	if p.decidedOrder.Len() >= p.config.MaxTraces {
+		oldest := p.decidedOrder.Remove(p.decidedOrder.Front()).(*tailDecision)
+		delete(p.decided, oldest.traceID)
+	}
+
+	p.decided[buffered.traceID] = p.decidedOrder.PushBack(&tailDecision{
+		traceID: buffered.traceID,
+		keep:    buffered.keep,
+		expires: now.Add(p.config.DecisionWait),
+	})
+
+	if !buffered.keep {
+		p.tracesDropped.Add(1)
+
+		return nil
+	}
+
+	p.tracesKept.Add(1)
+
+	return buffered.spans
+}
+
+// enqueue queues spans for the next export tick, dropping them when MaxPendingSpans spans are
+// already waiting. p.lock must be held.
+func (p *TailSamplingProcessor) enqueue(spans []sdktrace.ReadOnlySpan) {
+	if len(spans) == 0 {
+		return
+	}
+
+	if p.pendingSpans+len(spans) > p.config.MaxPendingSpans {
+		p.spansDropped.Add(uint64(len(spans)))
+
+		return
+	}
+
+	p.pending = append(p.pending, spans)
+	p.pendingSpans += len(spans)
+}
+
+// export passes each batch to the exporter, serializing exporter calls.
+func (p *TailSamplingProcessor) export(ctx context.Context, batches [][]sdktrace.ReadOnlySpan) error {
+	if len(batches) == 0 {
+		return nil
+	}
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, batch := range batches {
+		for _, span := range batch {
+			spans = append(spans, keptSpan(span))
+		}
+	}
+
+	p.exportLock.Lock()
+	defer p.exportLock.Unlock()
+
+	err := p.exporter.ExportSpans(ctx, spans)
+	if err != nil {
+		p.exportErrors.Add(1)
+
+		return err
+	}
+
+	p.spansExported.Add(uint64(len(spans)))
+
+	return nil
+}
+
+// keptSpan returns span with the sampled flag set on its span context, since the head sampler
+// recorded it without sampling it.
+func keptSpan(span sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	if span.SpanContext().IsSampled() {
+		return span
+	}
+
+	return sampledSpan{ReadOnlySpan: span}
+}
+
+// sampledSpan is a [sdktrace.ReadOnlySpan] whose span context reports the sampled flag.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+// SpanContext returns the span context of the span with the sampled flag set.
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	spanContext := s.ReadOnlySpan.SpanContext()
+
+	return spanContext.WithTraceFlags(spanContext.TraceFlags().WithSampled(true))
+}
+
+// matches reports whether span alone qualifies its trace to be kept.
+func (p *TailSamplingProcessor) matches(span sdktrace.ReadOnlySpan) bool {
+	if span.SpanContext().IsSampled() || span.Status().Code == codes.Error {
+		return true
+	}
+
+	if p.config.LatencyThreshold > 0 && span.EndTime().Sub(span.StartTime()) >= p.config.LatencyThreshold {
+		return true
+	}
+
+	return p.config.Predicate != nil && p.config.Predicate(span)
+}
+
+// tailRecordingSampler turns Drop decisions of its delegate into RecordOnly, so that unsampled
+// spans still reach a [TailSamplingProcessor] while sampled ones keep their flag.
+type tailRecordingSampler struct {
+	delegate sdktrace.Sampler
+}
+
+// ShouldSample returns the delegate decision, recording spans it would drop.
+func (s tailRecordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.delegate.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+
+	return result
+}
+
+// Description reports the wrapped sampler.
+func (s tailRecordingSampler) Description() string {
+	return fmt.Sprintf("TailRecording{%s}", s.delegate.Description())
+}
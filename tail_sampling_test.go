@@ -0,0 +1,206 @@
+package ttrace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tailSpan returns an ended, unsampled span of traceID named name, with error status when failed.
+func tailSpan(traceID trace.TraceID, name string, failed bool) sdktrace.ReadOnlySpan {
+	stub := tracetest.SpanStub{
+		Name: name,
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID,
+			SpanID:  trace.SpanID{1},
+		}),
+		StartTime: time.Now(),
+		EndTime:   time.Now(),
+	}
+
+	if failed {
+		stub.Status.Code = codes.Error
+	}
+
+	return stub.Snapshot()
+}
+
+// newTestTailSampling returns a processor exporting to an in-memory exporter. Its decision wait is
+// long enough that only ForceFlush decides traces.
+func newTestTailSampling(t *testing.T, config TailSamplingConfig) (*TailSamplingProcessor, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	config.DecisionWait = time.Hour
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	processor, err := NewTailSamplingProcessor(exporter, config)
+	if err != nil {
+		t.Fatalf("NewTailSamplingProcessor: %v", err)
+	}
+	t.Cleanup(func() { _ = processor.Shutdown(context.Background()) })
+
+	return processor, exporter
+}
+
+func TestTailSamplingKeepsErrorTraces(t *testing.T) {
+	processor, exporter := newTestTailSampling(t, TailSamplingConfig{})
+
+	kept := newTestTraceID()
+	dropped := newTestTraceID()
+
+	processor.OnEnd(tailSpan(kept, "ok", false))
+	processor.OnEnd(tailSpan(kept, "failed", true))
+	processor.OnEnd(tailSpan(dropped, "ok", false))
+
+	err := processor.ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	// Late spans follow the decision of their trace.
+	processor.OnEnd(tailSpan(kept, "late", false))
+	processor.OnEnd(tailSpan(dropped, "late", false))
+
+	err = processor.ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID() != kept {
+			t.Errorf("exported span %q of the dropped trace", span.Name)
+		}
+
+		if !span.SpanContext.IsSampled() {
+			t.Errorf("exported span %q without the sampled flag", span.Name)
+		}
+
+		names = append(names, span.Name)
+	}
+
+	if len(names) != 3 {
+		t.Errorf("exported spans %q, want ok, failed, and late of the kept trace", names)
+	}
+
+	// The late span of the dropped trace is counted as dropped.
+	want := TailSamplingStats{TracesKept: 1, TracesDropped: 1, SpansExported: 3, SpansDropped: 1}
+
+	stats := processor.Stats()
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestTailSamplingCapsRememberedDecisions(t *testing.T) {
+	processor, _ := newTestTailSampling(t, TailSamplingConfig{MaxTraces: 2})
+
+	traceIDs := make([]trace.TraceID, 5)
+
+	for i := range traceIDs {
+		traceIDs[i] = newTestTraceID()
+		processor.OnEnd(tailSpan(traceIDs[i], "op", true))
+
+		err := processor.ForceFlush(context.Background())
+		if err != nil {
+			t.Fatalf("ForceFlush: %v", err)
+		}
+	}
+
+	processor.lock.Lock()
+	decided := len(processor.decided)
+	ordered := processor.decidedOrder.Len()
+	_, oldest := processor.decided[traceIDs[0]]
+	_, newest := processor.decided[traceIDs[4]]
+	processor.lock.Unlock()
+
+	if decided != 2 || ordered != 2 {
+		t.Errorf("remembered %d decisions (%d ordered), want MaxTraces = 2", decided, ordered)
+	}
+
+	if oldest || !newest {
+		t.Errorf("remembered oldest = %v, newest = %v; want only the most recent decisions", oldest, newest)
+	}
+}
+
+func TestTailSamplingRefreshesUsedDecisions(t *testing.T) {
+	processor, _ := newTestTailSampling(t, TailSamplingConfig{MaxTraces: 2})
+
+	first := newTestTraceID()
+	second := newTestTraceID()
+	third := newTestTraceID()
+
+	for _, traceID := range []trace.TraceID{first, second} {
+		processor.OnEnd(tailSpan(traceID, "op", true))
+	}
+
+	err := processor.ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	// A late span makes the first decision the most recently used one.
+	processor.OnEnd(tailSpan(first, "late", false))
+
+	processor.OnEnd(tailSpan(third, "op", true))
+
+	err = processor.ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	processor.lock.Lock()
+	_, firstKept := processor.decided[first]
+	_, secondKept := processor.decided[second]
+	processor.lock.Unlock()
+
+	if !firstKept || secondKept {
+		t.Errorf("remembered first = %v, second = %v; want the least recently used decision evicted", firstKept, secondKept)
+	}
+}
+
+func TestTailSamplingCapsPendingSpans(t *testing.T) {
+	processor, exporter := newTestTailSampling(t, TailSamplingConfig{MaxPendingSpans: 3})
+
+	traceID := newTestTraceID()
+
+	processor.OnEnd(tailSpan(traceID, "failed", true))
+
+	err := processor.ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	for range 5 {
+		processor.OnEnd(tailSpan(traceID, "late", false))
+	}
+
+	stats := processor.Stats()
+	if stats.SpansDropped != 2 {
+		t.Errorf("SpansDropped = %d, want 2 late spans beyond MaxPendingSpans", stats.SpansDropped)
+	}
+
+	err = processor.ForceFlush(context.Background())
+	if err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+
+	got := len(exporter.GetSpans())
+	if got != 4 {
+		t.Errorf("exported %d spans, want the failed span and 3 late spans", got)
+	}
+
+	// The export emptied the pending queue, so later spans fit again.
+	processor.OnEnd(tailSpan(traceID, "later", false))
+
+	stats = processor.Stats()
+	if stats.SpansDropped != 2 {
+		t.Errorf("SpansDropped = %d after the export, want still 2", stats.SpansDropped)
+	}
+}
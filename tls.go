@@ -0,0 +1,217 @@
+package ttrace
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSOptions describes the transport security used by the OTLP exporters. File-based material is
+// re-read whenever the modification time of a file changes, so rotated certificates take effect on
+// the next connection without restarting the process.
+type TLSOptions struct {
+	// Enabled switches the OTLP client from plaintext to TLS. It is implied when any other field is
+	// set.
+	Enabled bool
+
+	// CAFile is a PEM bundle of trusted root certificates. When empty, the system roots are used.
+	CAFile string
+
+	// CertFile and KeyFile are the PEM client certificate and private key presented for mutual TLS.
+	// Both must be set together.
+	CertFile string
+	KeyFile  string
+
+	// ServerName overrides the host name used for SNI and certificate verification. When empty,
+	// the server certificate must be valid for the endpoint host, which may be an IP address.
+	ServerName string
+}
+
+// enabled reports whether opts requests TLS.
+func (opts TLSOptions) enabled() bool {
+	return opts.Enabled || opts.CAFile != "" || opts.CertFile != "" || opts.KeyFile != "" || opts.ServerName != ""
+}
+
+// newTLSConfig builds a [tls.Config] for opts and the OTLP endpoint (host:port). Certificate files
+// are loaded once up front so that missing or malformed material is reported immediately, and are
+// reloaded on change afterwards.
+func newTLSConfig(opts TLSOptions, endpoint string) (*tls.Config, error) {
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("ttrace: %s and %s must be set together", OTLPCertFile, OTLPKeyFile)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	reloader := &tlsReloader{
+		serverName: opts.ServerName,
+		caFile:     opts.CAFile,
+		certFile:   opts.CertFile,
+		keyFile:    opts.KeyFile,
+	}
+
+	if reloader.serverName == "" {
+		reloader.serverName = endpointHost(endpoint)
+	}
+
+	if opts.CAFile != "" {
+		_, err := reloader.rootCAs()
+		if err != nil {
+			return nil, err
+		}
+
+		// Standard verification is replaced by verifyConnection so that the root pool can be
+		// swapped when the CA bundle is rotated.
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = reloader.verifyConnection
+	}
+
+	if opts.CertFile != "" {
+		_, err := reloader.clientCertificate(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.GetClientCertificate = reloader.clientCertificate
+	}
+
+	return tlsConfig, nil
+}
+
+// tlsReloader caches TLS material loaded from disk and reloads it when the backing files change.
+type tlsReloader struct {
+	lock sync.Mutex
+
+	// serverName is the host name or IP address the server certificate must be valid for.
+	serverName string
+
+	caFile    string
+	caModTime time.Time
+	caPool    *x509.CertPool
+
+	certFile    string
+	keyFile     string
+	certModTime time.Time
+	keyModTime  time.Time
+	cert        *tls.Certificate
+}
+
+// rootCAs returns the CA pool, reloading caFile when its modification time has changed.
+func (r *tlsReloader) rootCAs() (*x509.CertPool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	modTime, err := fileModTime(r.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: stat CA file %s: %w", r.caFile, err)
+	}
+
+	if r.caPool != nil && modTime.Equal(r.caModTime) {
+		return r.caPool, nil
+	}
+
+	pem, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: read CA file %s: %w", r.caFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ttrace: no certificates found in CA file %s", r.caFile)
+	}
+
+	r.caPool = pool
+	r.caModTime = modTime
+
+	return pool, nil
+}
+
+// clientCertificate returns the client key pair, reloading certFile and keyFile when either
+// modification time has changed. It satisfies [tls.Config.GetClientCertificate].
+func (r *tlsReloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	certModTime, err := fileModTime(r.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: stat certificate file %s: %w", r.certFile, err)
+	}
+
+	keyModTime, err := fileModTime(r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: stat key file %s: %w", r.keyFile, err)
+	}
+
+	if r.cert != nil && certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ttrace: load client key pair: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+
+	return r.cert, nil
+}
+
+// verifyConnection verifies the server certificate chain against the current CA pool and the
+// configured server name, which may be an IP address. It satisfies [tls.Config.VerifyConnection].
+func (r *tlsReloader) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("ttrace: server presented no certificates")
+	}
+
+	pool, err := r.rootCAs()
+	if err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// The negotiated server name is empty for IP endpoints, which send no SNI, so the name is
+	// checked separately against the configured one.
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return err
+	}
+
+	return state.PeerCertificates[0].VerifyHostname(r.serverName)
+}
+
+// endpointHost returns the host part of endpoint (host:port), or endpoint itself when it has no
+// port.
+func endpointHost(endpoint string) string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint
+	}
+
+	return host
+}
+
+// fileModTime returns the modification time of path.
+func fileModTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
@@ -0,0 +1,304 @@
+package ttrace
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testServerName is the DNS name in the server certificates issued by [testCA].
+const (
+	testServerName = "collector.ttrace.test"
+)
+
+// testCA is a self-signed certificate authority issuing test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA returns a new self-signed CA named name.
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA certificate: %v", err)
+	}
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a key pair for commonName signed by ca, valid for serving testServerName and for
+// client authentication, with the PEM encodings of its certificate and key.
+func (ca *testCA) issue(t *testing.T, commonName string) (tls.Certificate, []byte, []byte) {
+	t.Helper()
+
+	return ca.issueFor(t, commonName, []string{testServerName}, nil)
+}
+
+// issueFor is like issue but makes the certificate valid for dnsNames and ips instead.
+func (ca *testCA) issueFor(t *testing.T, commonName string, dnsNames []string, ips []net.IP) (tls.Certificate, []byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("load key pair: %v", err)
+	}
+
+	return pair, certPEM, keyPEM
+}
+
+// writeRotatedFile writes data to path and moves its modification time forward by step, so that
+// the reloader notices the change even on file systems with coarse timestamps.
+func writeRotatedFile(t *testing.T, path string, data []byte, step int) {
+	t.Helper()
+
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+
+	modTime := time.Now().Add(time.Duration(step) * time.Minute)
+
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("touch %s: %v", path, err)
+	}
+}
+
+func TestOTLPHTTPExportFollowsRotatedCA(t *testing.T) {
+	oldCA := newTestCA(t, "old CA")
+	newCA := newTestCA(t, "new CA")
+
+	oldServerCert, _, _ := oldCA.issue(t, "old server")
+	newServerCert, _, _ := newCA.issue(t, "new server")
+
+	var serverCert atomic.Pointer[tls.Certificate]
+	serverCert.Store(&oldServerCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return serverCert.Load(), nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeRotatedFile(t, caFile, oldCA.pem, 0)
+
+	exporter, err := newTraceExporter(context.Background(), strings.TrimPrefix(server.URL, "https://"), newConfig(
+		WithTLS(TLSOptions{CAFile: caFile, ServerName: testServerName}),
+	))
+	if err != nil {
+		t.Fatalf("newTraceExporter: %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	export := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return exporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "span"}}.Snapshots())
+	}
+
+	err = export()
+	if err != nil {
+		t.Fatalf("export with the original CA: %v", err)
+	}
+
+	// The server rotates to a certificate from the new CA before the client trusts it.
+	serverCert.Store(&newServerCert)
+	server.CloseClientConnections()
+
+	err = export()
+	if err == nil {
+		t.Fatal("export to a server signed by an untrusted CA succeeded, want error")
+	}
+
+	writeRotatedFile(t, caFile, newCA.pem, 1)
+	server.CloseClientConnections()
+
+	err = export()
+	if err != nil {
+		t.Fatalf("export after rotating the CA file: %v", err)
+	}
+}
+
+func TestTLSReloaderReloadsClientCertificate(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	dir := t.TempDir()
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	_, certPEM, keyPEM := ca.issue(t, "first client")
+	writeRotatedFile(t, certFile, certPEM, 0)
+	writeRotatedFile(t, keyFile, keyPEM, 0)
+
+	tlsConfig, err := newTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile}, "")
+	if err != nil {
+		t.Fatalf("newTLSConfig: %v", err)
+	}
+
+	commonName := func() string {
+		cert, err := tlsConfig.GetClientCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetClientCertificate: %v", err)
+		}
+
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("parse client certificate: %v", err)
+		}
+
+		return leaf.Subject.CommonName
+	}
+
+	got := commonName()
+	if got != "first client" {
+		t.Fatalf("client certificate = %q, want %q", got, "first client")
+	}
+
+	_, certPEM, keyPEM = ca.issue(t, "second client")
+	writeRotatedFile(t, certFile, certPEM, 1)
+	writeRotatedFile(t, keyFile, keyPEM, 1)
+
+	got = commonName()
+	if got != "second client" {
+		t.Fatalf("client certificate after rotation = %q, want %q", got, "second client")
+	}
+}
+
+func TestNewTLSConfigRejectsIncompleteKeyPair(t *testing.T) {
+	_, err := newTLSConfig(TLSOptions{CertFile: "client.pem"}, "")
+	if err == nil {
+		t.Fatal("newTLSConfig with a certificate but no key succeeded, want error")
+	}
+}
+
+func TestNewTLSConfigVerifiesServerName(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	loopback := []net.IP{net.IPv4(127, 0, 0, 1)}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeRotatedFile(t, caFile, ca.pem, 0)
+
+	tests := []struct {
+		name       string
+		dnsNames   []string
+		ips        []net.IP
+		serverName string
+		wantErr    bool
+	}{
+		{name: "IP endpoint with IP SAN", ips: loopback},
+		{name: "IP endpoint without IP SAN", dnsNames: []string{testServerName}, wantErr: true},
+		{name: "server name with matching SAN", dnsNames: []string{testServerName}, serverName: testServerName},
+		{name: "server name with wrong SAN", dnsNames: []string{"other.ttrace.test"}, ips: loopback, serverName: testServerName, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverCert, _, _ := ca.issueFor(t, "server", tt.dnsNames, tt.ips)
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+			server.StartTLS()
+			defer server.Close()
+
+			endpoint := strings.TrimPrefix(server.URL, "https://")
+
+			tlsConfig, err := newTLSConfig(TLSOptions{CAFile: caFile, ServerName: tt.serverName}, endpoint)
+			if err != nil {
+				t.Fatalf("newTLSConfig: %v", err)
+			}
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GET %s error = %v, want error %t", endpoint, err, tt.wantErr)
+			}
+		})
+	}
+}
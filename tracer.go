@@ -2,16 +2,20 @@ package ttrace
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/choveylee/tcfg"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
@@ -19,138 +23,515 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// otlpExportTimeout matches the default per-export timeout of the OTLP exporters and applies to the
+// HTTP client built when a [HeaderProvider] is configured.
+const (
+	otlpExportTimeout = 10 * time.Second
+)
+
+// installedHandle is the Handle most recently installed by [Init], shut down by [Shutdown].
 var (
-	tracerProvider *sdktrace.TracerProvider
+	installedHandle atomic.Pointer[Handle]
 )
 
-// init loads tracing configuration through tcfg, installs an SDK-backed TracerProvider when
-// possible, and falls back to noop tracing if initialization fails.
-func init() {
-	ctx := context.Background()
+// Handle owns the TracerProvider built by [New] or [Init]. A Handle whose provider is nil represents
+// disabled (noop) tracing; its methods remain safe to call.
+type Handle struct {
+	tracerProvider *sdktrace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	sampler        *SamplerController
+	adaptive       *AdaptiveSampler
+	counters       *samplingCounters
+	tailSampling   *TailSamplingProcessor
+	queue          *PersistentQueueExporter
+
+	// stopBackground stops the sampling watcher and remote sampling poller, when running.
+	stopBackground context.CancelFunc
+
+	settings []Setting
+}
+
+// TracerProvider returns the SDK [*sdktrace.TracerProvider] owned by h, or nil when tracing is
+// disabled.
+func (h *Handle) TracerProvider() *sdktrace.TracerProvider {
+	if h == nil {
+		return nil
+	}
+
+	return h.tracerProvider
+}
+
+// Propagator returns the TextMapPropagator that [Init] installs for h.
+func (h *Handle) Propagator() propagation.TextMapPropagator {
+	if h == nil || h.propagator == nil {
+		return defaultPropagator()
+	}
+
+	return h.propagator
+}
+
+// Sampler returns the [SamplerController] used by h, or nil when tracing is disabled or a custom
+// sampler was supplied with [WithSampler]. With [WithRemoteSampling], it is the fallback sampler.
+func (h *Handle) Sampler() *SamplerController {
+	if h == nil {
+		return nil
+	}
+
+	return h.sampler
+}
+
+// AdaptiveSampler returns the [AdaptiveSampler] used by h, or nil when adaptive sampling is not
+// enabled with [WithAdaptiveSampling].
+func (h *Handle) AdaptiveSampler() *AdaptiveSampler {
+	if h == nil {
+		return nil
+	}
+
+	return h.adaptive
+}
+
+// SamplingStats returns the decision counts of the default sampler chain of h, the
+// [SamplerController] or, with [WithAdaptiveSampling], the [AdaptiveSampler], including decisions
+// taken by [WithSamplingRules] and by the strategies of [WithRemoteSampling]. It returns false when tracing is disabled or a custom sampler was
+// supplied with [WithSampler] or [WithConsistentSampling].
+func (h *Handle) SamplingStats() (SamplingStats, bool) {
+	if h == nil || h.counters == nil {
+		return SamplingStats{}, false
+	}
+
+	return h.counters.stats(), true
+}
+
+// TailSampling returns the [TailSamplingProcessor] used by h, or nil when tail sampling is not
+// enabled with [WithTailSampling].
+func (h *Handle) TailSampling() *TailSamplingProcessor {
+	if h == nil {
+		return nil
+	}
+
+	return h.tailSampling
+}
+
+// PersistentQueue returns the [PersistentQueueExporter] of the primary exporter of h, or nil when
+// the persistent queue is not enabled with [WithPersistentQueue].
+func (h *Handle) PersistentQueue() *PersistentQueueExporter {
+	if h == nil {
+		return nil
+	}
+
+	return h.queue
+}
+
+// Settings returns the effective configuration values and their sources when h was created by
+// [InitFromEnv]. It returns nil for handles created from explicit options only.
+func (h *Handle) Settings() []Setting {
+	if h == nil {
+		return nil
+	}
+
+	return h.settings
+}
+
+// Shutdown stops the sampling watcher and remote sampling poller, then flushes pending spans and shuts down the
+// TracerProvider owned by h, when present.
+func (h *Handle) Shutdown(ctx context.Context) error {
+	if h == nil {
+		return nil
+	}
+
+	if h.stopBackground != nil {
+		h.stopBackground()
+	}
+
+	if h.tracerProvider == nil {
+		return nil
+	}
+
+	return h.tracerProvider.Shutdown(ctx)
+}
+
+// New builds a TracerProvider from opts without installing it globally. It returns an error when the
+// mode is unsupported or when resource, sampler, or exporter construction fails. A disabled mode
+// without [WithExporter] or [WithDestinations] yields a Handle with a nil provider.
+func New(ctx context.Context, opts ...Option) (*Handle, error) {
+	return newHandle(ctx, newConfig(opts...))
+}
+
+// Init builds a TracerProvider from opts with [New] and installs it, together with the configured
+// propagator (W3C Trace Context and Baggage by default), as the global OpenTelemetry providers. Disabled tracing installs
+// a noop TracerProvider. A Handle installed by an earlier Init is shut down once the new one is
+// installed. On failure, the global providers and the earlier Handle are left untouched and the
+// error is returned, unless [WithNoopFallback] is enabled.
+func Init(ctx context.Context, opts ...Option) (*Handle, error) {
+	return initTracer(ctx, newConfig(opts...))
+}
 
-	tracerMode := tcfg.DefaultInt(tcfg.LocalKey(TracerMode), TracerModeDisable)
+// InitFromEnv calls [Init] with options loaded through [github.com/choveylee/tcfg] from keys such
+// as [TracerMode], [OTLPEndpoint], [TracerSamplingFraction], and [AppName]. When a ttrace key is
+// unset, the equivalent standard variable (OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES,
+// OTEL_TRACES_EXPORTER, OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_TRACES_SAMPLER and _ARG,
+// OTEL_PROPAGATORS, or OTEL_SDK_DISABLED) is used, and built-in defaults apply last.
+// [Handle.Settings] reports the source of each effective value. Options in opts are applied after
+// the configured ones and therefore take precedence.
+func InitFromEnv(ctx context.Context, opts ...Option) (*Handle, error) {
+	return Init(ctx, append(envOptions(), opts...)...)
+}
 
-	err := startTracer(ctx, tracerMode)
+// initTracer builds a Handle from cfg and installs it globally, applying the noop fallback policy
+// on failure.
+func initTracer(ctx context.Context, cfg *config) (*Handle, error) {
+	handle, err := newHandle(ctx, cfg)
 	if err != nil {
+		if !cfg.noopFallback {
+			return nil, err
+		}
+
 		log.Printf("ttrace: tracer initialization failed: %v; falling back to noop tracing", err)
 
-		err = installNoopTracing()
+		handle = &Handle{
+			propagator: configuredPropagator(cfg),
+			settings:   cfg.settings,
+		}
+	}
+
+	previous := installHandle(handle)
+	if previous != nil {
+		err = previous.Shutdown(ctx)
 		if err != nil {
-			log.Printf("ttrace: noop tracing installation failed: %v", err)
+			log.Printf("ttrace: shutdown of the previous tracer failed: %v", err)
 		}
 	}
+
+	return handle, nil
 }
 
-// GetTracerProvider returns the package-level [*sdktrace.TracerProvider] when stdout or OTLP startup
-// succeeds. It returns nil when tracing is disabled or the noop fallback is active.
+// GetTracerProvider returns the global [*sdktrace.TracerProvider], such as the one installed by
+// [Init] or [InitFromEnv]. It returns nil before initialization, when tracing is disabled, or when
+// the noop fallback is active.
 func GetTracerProvider() *sdktrace.TracerProvider {
+	tracerProvider, _ := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+
 	return tracerProvider
 }
 
-// Shutdown flushes and shuts down the SDK [sdktrace.TracerProvider] returned by
-// [GetTracerProvider], when present.
+// Shutdown shuts down the [Handle] installed by [Init] or [InitFromEnv] with [Handle.Shutdown],
+// which also stops its sampling watcher and remote sampling poller. Without an installed Handle, it
+// flushes and shuts down the SDK [sdktrace.TracerProvider] returned by [GetTracerProvider], when
+// present.
 func Shutdown() error {
-	if tracerProvider != nil {
-		err := tracerProvider.Shutdown(context.Background())
-		if err != nil {
-			log.Printf("ttrace: tracer shutdown failed: %v", err)
+	var err error
 
-			return err
+	handle := installedHandle.Load()
+	if handle != nil {
+		err = handle.Shutdown(context.Background())
+	} else {
+		tracerProvider := GetTracerProvider()
+		if tracerProvider != nil {
+			err = tracerProvider.Shutdown(context.Background())
 		}
 	}
 
+	if err != nil {
+		log.Printf("ttrace: tracer shutdown failed: %v", err)
+
+		return err
+	}
+
 	return nil
 }
 
-// startTracer constructs the resource, exporter, and sampler, then installs the global
-// TracerProvider for stdout or OTLP/HTTP export. Disabled or unknown modes delegate to
-// [installNoopTracing]. It returns an error when resource, sampler, or exporter construction fails.
-func startTracer(ctx context.Context, tracerMode int) error {
-	if tracerMode != TracerModeStdout && tracerMode != TracerModeOTLP {
-		if tracerMode != TracerModeDisable {
-			log.Printf("ttrace: unsupported tracer mode %d; installing noop tracing", tracerMode)
+// newHandle constructs the resource, exporter, and sampler described by cfg and returns a Handle
+// owning the resulting TracerProvider. It returns an error when the mode is unsupported or when
+// sampler or exporter construction fails.
+func newHandle(ctx context.Context, cfg *config) (*Handle, error) {
+	if cfg.err != nil {
+		return nil, cfg.err
+	}
+
+	if cfg.exporter == nil && cfg.mode == TracerModeDisable && len(cfg.destinations) == 0 {
+		return &Handle{
+			propagator: configuredPropagator(cfg),
+			settings:   cfg.settings,
+		}, nil
+	}
+
+	res := cfg.resource
+	if res == nil {
+		res = defaultResource()
+	}
+
+	var (
+		controller *SamplerController
+		adaptive   *AdaptiveSampler
+		remote     *RemoteSampler
+		counters   *samplingCounters
+	)
+
+	sampler := cfg.sampler
+	if sampler == nil && cfg.consistentSampling != nil {
+		consistent, err := NewConsistentSampler(*cfg.consistentSampling)
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: configure consistent sampler: %w", err)
 		}
 
-		return installNoopTracing()
+		// ConsistentSampler follows parent decisions itself and is not wrapped with ParentBased.
+		sampler = consistent
 	}
 
-	res, err := newResource()
-	if err != nil {
-		log.Printf("ttrace: build tracing resource: %v", err)
+	if sampler == nil {
+		var (
+			root sdktrace.Sampler
+			err  error
+		)
+
+		if cfg.adaptiveSampling != nil {
+			adaptive, err = NewAdaptiveSampler(*cfg.adaptiveSampling)
+			if err != nil {
+				return nil, fmt.Errorf("ttrace: configure adaptive sampler: %w", err)
+			}
+
+			root = adaptive
+			counters = adaptive.counters
+		} else {
+			controller, err = NewSamplerController(cfg.samplingFraction, cfg.maxTracesPerSecond)
+			if err != nil {
+				return nil, fmt.Errorf("ttrace: configure sampler: %w", err)
+			}
+
+			root = controller
+			counters = controller.counters
+		}
 
-		return err
+		if strings.TrimSpace(cfg.remoteSamplingURL) != "" {
+			serviceName, _ := res.Set().Value(semconv.ServiceNameKey)
+
+			remote, err = NewRemoteSampler(RemoteSamplerConfig{
+				URL:             cfg.remoteSamplingURL,
+				ServiceName:     serviceName.AsString(),
+				RefreshInterval: cfg.remoteInterval,
+				Fallback:        root,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("ttrace: configure remote sampler: %w", err)
+			}
+
+			// The strategies count into the handle counters next to the fallback.
+			remote.controller.counters = counters
+			remote.perOperation.counters = counters
+
+			root = remote
+		}
+
+		if cfg.samplingRules != nil {
+			ruleSampler, err := NewRuleSampler(*cfg.samplingRules, root)
+			if err != nil {
+				return nil, fmt.Errorf("ttrace: configure sampling rules: %w", err)
+			}
+
+			ruleSampler.counters = counters
+			root = ruleSampler
+		}
+
+		sampler = newStatsParentBased(root, counters)
 	}
 
-	samplingFraction := tcfg.DefaultFloat64(tcfg.LocalKey(TracerSamplingFraction), 0.1)
-	maxTracesPerSecond := tcfg.DefaultFloat64(tcfg.LocalKey(TracerMaxTracesPerSec), 1.0)
-	sampler, err := configuredSampler(samplingFraction, maxTracesPerSecond)
-	if err != nil {
-		log.Printf("ttrace: configure sampler: %v", err)
+	if cfg.debugSampling.enabled() {
+		debugSampler, err := NewDebugSampler(sampler, cfg.debugSampling)
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: configure debug sampling: %w", err)
+		}
 
-		return err
+		sampler = debugSampler
 	}
 
-	var tracerExporter sdktrace.SpanExporter
+	var (
+		tracerExporter sdktrace.SpanExporter
+		queue          *PersistentQueueExporter
+		err            error
+	)
 
-	if tracerMode == TracerModeStdout {
-		tracerExporter, err = newStdoutExporter()
+	if cfg.exporter != nil || cfg.mode != TracerModeDisable {
+		tracerExporter, queue, err = newQueuedExporter(ctx, cfg)
 		if err != nil {
-			log.Printf("ttrace: create stdout exporter: %v", err)
-
-			return err
+			return nil, err
 		}
-	} else {
-		otlpEndpoint := strings.TrimSpace(tcfg.DefaultString(tcfg.LocalKey(OTLPEndpoint), ""))
-		if otlpEndpoint == "" {
-			log.Printf("ttrace: missing %s for OTLP exporter", OTLPEndpoint)
+	}
+
+	var (
+		spanProcessors []sdktrace.SpanProcessor
+		tailSampling   *TailSamplingProcessor
+	)
+
+	if cfg.tailSampling != nil {
+		tailExporter := tracerExporter
 
-			return fmt.Errorf("ttrace: missing %s for OTLP exporter", OTLPEndpoint)
+		if len(cfg.destinations) > 0 {
+			tailExporter, err = newFanoutExporter(ctx, tracerExporter, cfg.destinations)
+			if err != nil {
+				return nil, errors.Join(err, shutdownExporter(ctx, tracerExporter))
+			}
 		}
 
-		tracerExporter, err = newTraceExporter(ctx, otlpEndpoint)
+		tailSampling, err = NewTailSamplingProcessor(tailExporter, *cfg.tailSampling)
 		if err != nil {
-			log.Printf("ttrace: create OTLP exporter for %s: %v", otlpEndpoint, err)
+			return nil, errors.Join(fmt.Errorf("ttrace: configure tail sampling: %w", err), tailExporter.Shutdown(ctx))
+		}
 
-			return err
+		spanProcessors = []sdktrace.SpanProcessor{tailSampling}
+		sampler = tailRecordingSampler{delegate: sampler}
+	} else {
+		spanProcessors, err = newSpanProcessors(ctx, tracerExporter, cfg.destinations)
+		if err != nil {
+			return nil, errors.Join(err, shutdownExporter(ctx, tracerExporter))
 		}
 	}
 
-	tracerProvider = sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(tracerExporter),
+	providerOptions := []sdktrace.TracerProviderOption{
 		sdktrace.WithSampler(sampler),
 		sdktrace.WithResource(res),
-	)
+	}
 
-	otel.SetTracerProvider(tracerProvider)
-	installPropagator()
+	for _, spanProcessor := range spanProcessors {
+		providerOptions = append(providerOptions, sdktrace.WithSpanProcessor(spanProcessor))
+	}
 
-	return nil
+	handle := &Handle{
+		tracerProvider: sdktrace.NewTracerProvider(providerOptions...),
+		propagator:     configuredPropagator(cfg),
+		sampler:        controller,
+		adaptive:       adaptive,
+		counters:       counters,
+		tailSampling:   tailSampling,
+		queue:          queue,
+		settings:       cfg.settings,
+	}
+
+	watch := controller != nil && cfg.watchSource != nil && cfg.watchInterval > 0
+	if watch || remote != nil {
+		backgroundCtx, cancel := context.WithCancel(context.Background())
+
+		if watch {
+			controller.Watch(backgroundCtx, cfg.watchInterval, cfg.watchSource)
+		}
+
+		if remote != nil {
+			remote.Start(backgroundCtx)
+		}
+
+		handle.stopBackground = cancel
+	}
+
+	return handle, nil
 }
 
-// configuredSampler interprets -1 as disabling an individual sampling stage. When both values are
-// -1, root sampling is always enabled. Values below -1 are rejected. The returned sampler is
-// parent-based so child spans inherit their parent decision.
-func configuredSampler(samplingFraction, maxTracesPerSecond float64) (sdktrace.Sampler, error) {
-	if err := validateSamplingConfigValue(TracerSamplingFraction, samplingFraction); err != nil {
-		return nil, err
+// configuredPropagator returns the propagator from cfg, extended with [DebugHeaderPropagator] when
+// debug sampling uses a header. It returns nil when cfg leaves the propagator unset and no debug
+// header is configured.
+func configuredPropagator(cfg *config) propagation.TextMapPropagator {
+	if cfg.debugSampling.Header == "" {
+		return cfg.propagator
 	}
 
-	if err := validateSamplingConfigValue(TracerMaxTracesPerSec, maxTracesPerSecond); err != nil {
-		return nil, err
+	propagator := cfg.propagator
+	if propagator == nil {
+		propagator = defaultPropagator()
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagator, DebugHeaderPropagator(cfg.debugSampling.Header))
+}
+
+// newQueuedExporter returns the exporter described by cfg, wrapped in a [PersistentQueueExporter]
+// when [WithPersistentQueue] is set. The queue is also returned, or nil without one.
+func newQueuedExporter(ctx context.Context, cfg *config) (sdktrace.SpanExporter, *PersistentQueueExporter, error) {
+	tracerExporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.persistentQueue == nil {
+		return tracerExporter, nil, nil
 	}
 
-	switch {
-	case samplingFraction == -1 && maxTracesPerSecond == -1:
-		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
-	case samplingFraction == -1:
-		return RateLimitingSampler(maxTracesPerSecond), nil
-	case maxTracesPerSecond == -1:
-		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingFraction)), nil
+	queue, err := NewPersistentQueueExporter(tracerExporter, *cfg.persistentQueue)
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("ttrace: configure persistent queue: %w", err), tracerExporter.Shutdown(ctx))
+	}
+
+	return queue, queue, nil
+}
+
+// newExporter returns the exporter supplied through [WithExporter], or builds the exporter implied
+// by cfg.mode.
+func newExporter(ctx context.Context, cfg *config) (sdktrace.SpanExporter, error) {
+	if cfg.exporter != nil {
+		return cfg.exporter, nil
+	}
+
+	switch cfg.mode {
+	case TracerModeStdout:
+		tracerExporter, err := newStdoutExporter()
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: create stdout exporter: %w", err)
+		}
+
+		return tracerExporter, nil
+	case TracerModeOTLP:
+		otlpEndpoint := strings.TrimSpace(cfg.endpoint)
+		if otlpEndpoint == "" {
+			return nil, fmt.Errorf("ttrace: missing %s for OTLP exporter", OTLPEndpoint)
+		}
+
+		tracerExporter, err := newTraceExporter(ctx, otlpEndpoint, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: create OTLP exporter for %s: %w", otlpEndpoint, err)
+		}
+
+		return tracerExporter, nil
+	case TracerModeOTLPGRPC:
+		otlpEndpoint := strings.TrimSpace(cfg.endpoint)
+		if otlpEndpoint == "" {
+			return nil, fmt.Errorf("ttrace: missing %s for OTLP/gRPC exporter", OTLPEndpoint)
+		}
+
+		tracerExporter, err := newGRPCTraceExporter(ctx, otlpEndpoint, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: create OTLP/gRPC exporter for %s: %w", otlpEndpoint, err)
+		}
+
+		return tracerExporter, nil
+	case TracerModeFile:
+		tracerExporter, err := NewFileExporter(cfg.file)
+		if err != nil {
+			return nil, fmt.Errorf("ttrace: create file exporter: %w", err)
+		}
+
+		return tracerExporter, nil
 	default:
-		return GuaranteedThroughputProbabilitySampler(samplingFraction, maxTracesPerSecond), nil
+		return nil, fmt.Errorf("ttrace: unsupported tracer mode %d", cfg.mode)
+	}
+}
+
+// installHandle registers the provider owned by handle as the global TracerProvider, or a noop
+// provider when handle has none, and installs the global propagators. It returns the Handle that
+// was installed before, if any.
+func installHandle(handle *Handle) *Handle {
+	previous := installedHandle.Swap(handle)
+	installedSampler.Store(handle.sampler)
+
+	if handle.tracerProvider == nil {
+		installNoopTracing(handle.Propagator())
+
+		return previous
 	}
+
+	otel.SetTracerProvider(handle.tracerProvider)
+	installPropagator(handle.Propagator())
+
+	return previous
 }
 
 func validateSamplingConfigValue(key string, value float64) error {
@@ -161,68 +542,132 @@ func validateSamplingConfigValue(key string, value float64) error {
 	return nil
 }
 
-// installNoopTracing registers a noop global TracerProvider, clears the SDK provider pointer, and
-// reinstalls propagators so context propagation remains available without exporting spans.
-func installNoopTracing() error {
-	tracerProvider = nil
-
+// installNoopTracing registers a noop global TracerProvider and reinstalls propagators so context
+// propagation remains available without exporting spans.
+func installNoopTracing(propagator propagation.TextMapPropagator) {
 	otel.SetTracerProvider(noop.NewTracerProvider())
-	installPropagator()
+	installPropagator(propagator)
+}
 
-	return nil
+// installPropagator installs propagator as the global TextMapPropagator.
+func installPropagator(propagator propagation.TextMapPropagator) {
+	otel.SetTextMapPropagator(propagator)
+}
+
+// defaultResource builds a [resource.Resource] whose service.name is the executable base name.
+func defaultResource() *resource.Resource {
+	return resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(executableName()))
 }
 
-// installPropagator installs a global TextMapPropagator composed of W3C Trace Context and W3C
-// Baggage propagators.
-func installPropagator() {
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+// executableName returns the base name of the running executable without its extension.
+func executableName() string {
+	basePath := filepath.Base(os.Args[0])
+
+	return strings.TrimSuffix(basePath, filepath.Ext(basePath))
 }
 
-// newResource builds a [resource.Resource] containing service.name and optional attributes derived
-// from tcfg keys such as [ServiceVersion] and [DeploymentEnvironmentName].
-func newResource() (*resource.Resource, error) {
-	appName := tcfg.DefaultString(AppName, "")
-	if appName == "" {
-		basePath := filepath.Base(os.Args[0])
-		appName = strings.TrimSuffix(basePath, filepath.Ext(basePath))
+// newTraceExporter creates an OTLP/HTTP trace exporter for endpoint (host:port). The client uses
+// TLS when cfg requests it and plaintext otherwise, and applies the configured headers, header
+// provider, compression, and URL path.
+func newTraceExporter(ctx context.Context, endpoint string, cfg *config) (*otlptrace.Exporter, error) {
+	err := validateCompression(cfg.compression)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(endpoint),
 	}
 
-	attrs := []attribute.KeyValue{
-		semconv.ServiceNameKey.String(appName),
+	var tlsConfig *tls.Config
+
+	if cfg.tls.enabled() {
+		tlsConfig, err = newTLSConfig(cfg.tls, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+	} else {
+		opts = append(opts, otlptracehttp.WithInsecure())
 	}
 
-	version := strings.TrimSpace(tcfg.DefaultString(tcfg.LocalKey(ServiceVersion), ""))
-	if version != "" {
-		attrs = append(attrs, semconv.ServiceVersionKey.String(version))
+	if len(cfg.headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.headers))
 	}
 
-	namespace := strings.TrimSpace(tcfg.DefaultString(tcfg.LocalKey(ServiceNamespace), ""))
-	if namespace != "" {
-		attrs = append(attrs, semconv.ServiceNamespaceKey.String(namespace))
+	if cfg.compression == CompressionGzip {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
 	}
 
-	instanceId := strings.TrimSpace(tcfg.DefaultString(tcfg.LocalKey(ServiceInstanceID), ""))
-	if instanceId != "" {
-		attrs = append(attrs, semconv.ServiceInstanceIDKey.String(instanceId))
+	if cfg.urlPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(cfg.urlPath))
 	}
 
-	environment := strings.TrimSpace(tcfg.DefaultString(tcfg.LocalKey(DeploymentEnvironmentName), ""))
-	if environment != "" {
-		attrs = append(attrs, semconv.DeploymentEnvironmentNameKey.String(environment))
+	if cfg.headerProvider != nil {
+		// A custom client bypasses the TLS option above, so the transport carries the TLS
+		// configuration itself.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+
+		opts = append(opts, otlptracehttp.WithHTTPClient(&http.Client{
+			Transport: &headerRoundTripper{
+				base:     transport,
+				provider: cfg.headerProvider,
+			},
+			Timeout: otlpExportTimeout,
+		}))
 	}
 
-	r := resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+	client := otlptracehttp.NewClient(opts...)
 
-	return r, nil
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return exporter, err
 }
 
-// newTraceExporter creates an OTLP/HTTP trace exporter for endpoint (host:port) using an insecure
-// client configuration.
-func newTraceExporter(ctx context.Context, endpoint string) (*otlptrace.Exporter, error) {
-	client := otlptracehttp.NewClient(
-		otlptracehttp.WithInsecure(),
-		otlptracehttp.WithEndpoint(endpoint),
-	)
+// newGRPCTraceExporter creates an OTLP/gRPC trace exporter for endpoint (host:port). The client
+// uses TLS when cfg requests it and plaintext otherwise, and applies the configured headers, header
+// provider, and compression. The URL path setting does not apply to gRPC.
+func newGRPCTraceExporter(ctx context.Context, endpoint string, cfg *config) (*otlptrace.Exporter, error) {
+	err := validateCompression(cfg.compression)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(endpoint),
+	}
+
+	if cfg.compression == CompressionGzip {
+		opts = append(opts, otlptracegrpc.WithCompressor(CompressionGzip))
+	}
+
+	// With a provider, the static headers travel in the per-RPC credentials, which merge both sets.
+	if cfg.headerProvider != nil {
+		opts = append(opts, otlptracegrpc.WithDialOption(grpc.WithPerRPCCredentials(headerCredentials{
+			headers:  cfg.headers,
+			provider: cfg.headerProvider,
+		})))
+	} else if len(cfg.headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(cfg.headers))
+	}
+
+	if cfg.tls.enabled() {
+		tlsConfig, err := newTLSConfig(cfg.tls, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	client := otlptracegrpc.NewClient(opts...)
 
 	exporter, err := otlptrace.New(ctx, client)
 	if err != nil {
@@ -0,0 +1,334 @@
+package ttrace
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// traceCollector is an in-process OTLP/gRPC trace service recording the requests it receives.
+type traceCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	lock     sync.Mutex
+	spans    []string
+	metadata []metadata.MD
+}
+
+// Export records the span names and request metadata of req.
+func (c *traceCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.metadata = append(c.metadata, md)
+
+	for _, resourceSpans := range req.GetResourceSpans() {
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				c.spans = append(c.spans, span.GetName())
+			}
+		}
+	}
+
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// spanNames returns the names of the received spans.
+func (c *traceCollector) spanNames() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return slices.Clone(c.spans)
+}
+
+// requestMetadata returns the metadata of every received request.
+func (c *traceCollector) requestMetadata() []metadata.MD {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return slices.Clone(c.metadata)
+}
+
+// startTraceCollector serves a [traceCollector] on a loopback port and returns it with its
+// address. The server stops when the test ends.
+func startTraceCollector(t *testing.T) (*traceCollector, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	collector := &traceCollector{}
+
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, collector)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	return collector, listener.Addr().String()
+}
+
+func TestNewExportsOverGRPC(t *testing.T) {
+	collector, endpoint := startTraceCollector(t)
+
+	handle, err := New(context.Background(),
+		WithMode(TracerModeOTLPGRPC),
+		WithEndpoint(endpoint),
+		WithSampling(-1, -1),
+		WithHeaders(map[string]string{"x-tenant": "acme"}),
+		WithCompression(CompressionGzip),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, span := handle.TracerProvider().Tracer("test").Start(context.Background(), "checkout")
+	span.End()
+
+	err = handle.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	names := collector.spanNames()
+	if !slices.Equal(names, []string{"checkout"}) {
+		t.Fatalf("exported spans = %v, want [checkout]", names)
+	}
+
+	for _, md := range collector.requestMetadata() {
+		got := md.Get("x-tenant")
+		if !slices.Equal(got, []string{"acme"}) {
+			t.Errorf("x-tenant metadata = %v, want [acme]", got)
+		}
+	}
+}
+
+func TestGRPCHeaderProviderOverridesStaticHeaders(t *testing.T) {
+	collector, endpoint := startTraceCollector(t)
+
+	handle, err := New(context.Background(),
+		WithMode(TracerModeOTLPGRPC),
+		WithEndpoint(endpoint),
+		WithSampling(-1, -1),
+		WithHeaders(map[string]string{"Authorization": "Bearer static", "x-tenant": "acme"}),
+		WithHeaderProvider(func(context.Context) (map[string]string, error) {
+			return map[string]string{"authorization": "Bearer fresh"}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, span := handle.TracerProvider().Tracer("test").Start(context.Background(), "checkout")
+	span.End()
+
+	err = handle.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	requests := collector.requestMetadata()
+	if len(requests) == 0 {
+		t.Fatal("collector received no requests")
+	}
+
+	for _, md := range requests {
+		got := md.Get("authorization")
+		if !slices.Equal(got, []string{"Bearer fresh"}) {
+			t.Errorf("authorization metadata = %v, want [Bearer fresh]", got)
+		}
+
+		got = md.Get("x-tenant")
+		if !slices.Equal(got, []string{"acme"}) {
+			t.Errorf("x-tenant metadata = %v, want [acme]", got)
+		}
+	}
+}
+
+func TestNewGRPCRequiresEndpoint(t *testing.T) {
+	_, err := New(context.Background(), WithMode(TracerModeOTLPGRPC))
+	if err == nil {
+		t.Fatal("New without endpoint succeeded, want error")
+	}
+}
+
+// restoreGlobals restores the global providers and the installed Handle when the test ends.
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+
+	tracerProvider := otel.GetTracerProvider()
+	propagator := otel.GetTextMapPropagator()
+	handle := installedHandle.Load()
+	sampler := installedSampler.Load()
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+		installedHandle.Store(handle)
+		installedSampler.Store(sampler)
+	})
+}
+
+func TestInitShutsDownPreviousHandle(t *testing.T) {
+	restoreGlobals(t)
+
+	first := &shutdownRecorder{}
+	second := &shutdownRecorder{}
+
+	firstHandle, err := Init(context.Background(), WithExporter(first))
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	secondHandle, err := Init(context.Background(), WithExporter(second))
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	if !first.shutdown.Load() {
+		t.Error("exporter of the replaced Handle was not shut down")
+	}
+
+	if second.shutdown.Load() {
+		t.Error("exporter of the installed Handle was shut down")
+	}
+
+	if GetTracerProvider() != secondHandle.TracerProvider() || GetTracerProvider() == firstHandle.TracerProvider() {
+		t.Error("GetTracerProvider() is not the provider of the second Handle")
+	}
+
+	err = Shutdown()
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if !second.shutdown.Load() {
+		t.Error("Shutdown did not shut down the installed Handle")
+	}
+}
+
+func TestInitLeavesGlobalsOnError(t *testing.T) {
+	restoreGlobals(t)
+
+	exporter := &shutdownRecorder{}
+
+	handle, err := Init(context.Background(), WithExporter(exporter), WithPropagator(propagation.Baggage{}))
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer handle.Shutdown(context.Background())
+
+	failed, err := Init(context.Background(), WithExporter(&shutdownRecorder{}), WithTailSampling(TailSamplingConfig{MaxTraces: -1}))
+	if err == nil || failed != nil {
+		t.Fatalf("Init with an invalid configuration = %v, %v; want nil and an error", failed, err)
+	}
+
+	if GetTracerProvider() != handle.TracerProvider() {
+		t.Error("failed Init replaced the global TracerProvider")
+	}
+
+	_, ok := otel.GetTextMapPropagator().(propagation.Baggage)
+	if !ok {
+		t.Errorf("failed Init replaced the global propagator with %T", otel.GetTextMapPropagator())
+	}
+
+	if installedHandle.Load() != handle || exporter.shutdown.Load() {
+		t.Error("failed Init replaced or shut down the installed Handle")
+	}
+}
+
+func TestInitNoopFallback(t *testing.T) {
+	restoreGlobals(t)
+
+	handle, err := Init(context.Background(),
+		WithExporter(&shutdownRecorder{}),
+		WithTailSampling(TailSamplingConfig{MaxTraces: -1}),
+		WithPropagator(propagation.Baggage{}),
+		WithNoopFallback(true),
+	)
+	if err != nil {
+		t.Fatalf("Init with the noop fallback: %v", err)
+	}
+
+	if handle.TracerProvider() != nil || GetTracerProvider() != nil {
+		t.Error("noop fallback installed an SDK TracerProvider")
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "op")
+	if span.IsRecording() {
+		t.Error("span of the noop fallback is recording")
+	}
+
+	_, ok := otel.GetTextMapPropagator().(propagation.Baggage)
+	if !ok {
+		t.Errorf("global propagator = %T, want the configured one", otel.GetTextMapPropagator())
+	}
+
+	err = handle.Shutdown(context.Background())
+	if err != nil {
+		t.Errorf("Shutdown of the noop fallback Handle: %v", err)
+	}
+}
+
+func TestHandleShutdown(t *testing.T) {
+	exporter := &shutdownRecorder{}
+	server := newStrategyServer(t, http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1}}`)
+
+	handle, err := New(context.Background(),
+		WithExporter(exporter),
+		WithRemoteSampling(server.URL+"/sampling", 10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	err = handle.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if !exporter.shutdown.Load() {
+		t.Error("Shutdown did not shut down the exporter")
+	}
+
+	// Let an in-flight poll finish before counting.
+	time.Sleep(50 * time.Millisecond)
+
+	polls := len(server.requestedServices())
+
+	time.Sleep(50 * time.Millisecond)
+
+	got := len(server.requestedServices())
+	if got != polls {
+		t.Errorf("remote sampler polled %d times after Shutdown", got-polls)
+	}
+
+	_, span := handle.TracerProvider().Tracer("test").Start(context.Background(), "op")
+	if span.IsRecording() {
+		t.Error("span started after Shutdown is recording")
+	}
+
+	var nilHandle *Handle
+
+	err = nilHandle.Shutdown(context.Background())
+	if err != nil {
+		t.Errorf("Shutdown of a nil Handle: %v", err)
+	}
+}
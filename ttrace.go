@@ -128,13 +128,14 @@ func ValidTraceId(traceId trace.TraceID) bool {
 }
 
 // Inject writes trace context and baggage from ctx into supplier by using the global
-// TextMapPropagator.
+// TextMapPropagator, which writes every format configured with [TracerPropagators].
 func Inject(ctx context.Context, supplier propagation.TextMapCarrier) {
 	otel.GetTextMapPropagator().Inject(ctx, supplier)
 }
 
 // Extract returns a child of ctx whose trace and baggage state is deserialized from supplier by
-// using the global TextMapPropagator.
+// using the global TextMapPropagator. With several configured formats, the first one present in
+// supplier determines the parent span context.
 func Extract(ctx context.Context, supplier propagation.TextMapCarrier) context.Context {
 	return otel.GetTextMapPropagator().Extract(ctx, supplier)
 }
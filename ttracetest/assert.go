@@ -0,0 +1,219 @@
+package ttracetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AssertSpan reports a test error unless an ended span named name carries every attribute in
+// attrs. It returns the first matching span, or the zero span when none matches.
+func (r *Recorder) AssertSpan(name string, attrs ...attribute.KeyValue) tracetest.SpanStub {
+	r.t.Helper()
+
+	for _, span := range r.SpansByName(name) {
+		if hasAttributes(span, attrs) {
+			return span
+		}
+	}
+
+	if len(attrs) == 0 {
+		r.t.Errorf("ttracetest: no span named %q\n%s", name, r.Tree())
+	} else {
+		r.t.Errorf("ttracetest: no span named %q with attributes %s\n%s", name, formatAttributes(attrs), r.Tree())
+	}
+
+	return tracetest.SpanStub{}
+}
+
+// AssertNoSpan reports a test error when an ended span named name exists.
+func (r *Recorder) AssertNoSpan(name string) {
+	r.t.Helper()
+
+	if len(r.SpansByName(name)) > 0 {
+		r.t.Errorf("ttracetest: unexpected span named %q\n%s", name, r.Tree())
+	}
+}
+
+// AssertChildOf reports a test error unless some span named child has a span named parent as its
+// direct parent.
+func (r *Recorder) AssertChildOf(child, parent string) {
+	r.t.Helper()
+
+	parents := r.SpansByName(parent)
+
+	for _, span := range r.SpansByName(child) {
+		for _, candidate := range parents {
+			if isChildOf(span, candidate) {
+				return
+			}
+		}
+	}
+
+	r.t.Errorf("ttracetest: no span named %q is a child of a span named %q\n%s", child, parent, r.Tree())
+}
+
+// AssertStatus reports a test error unless a span named name has status code code.
+func (r *Recorder) AssertStatus(name string, code codes.Code) {
+	r.t.Helper()
+
+	for _, span := range r.SpansByName(name) {
+		if span.Status.Code == code {
+			return
+		}
+	}
+
+	r.t.Errorf("ttracetest: no span named %q with status %s\n%s", name, code, r.Tree())
+}
+
+// AssertError reports a test error unless a span named name has an error status or a recorded
+// exception event.
+func (r *Recorder) AssertError(name string) {
+	r.t.Helper()
+
+	for _, span := range r.SpansByName(name) {
+		if isError(span) {
+			return
+		}
+	}
+
+	r.t.Errorf("ttracetest: no span named %q recorded an error\n%s", name, r.Tree())
+}
+
+// AssertNoError reports a test error when any span named name has an error status or a recorded
+// exception event.
+func (r *Recorder) AssertNoError(name string) {
+	r.t.Helper()
+
+	for _, span := range r.SpansByName(name) {
+		if isError(span) {
+			r.t.Errorf("ttracetest: span named %q recorded an error\n%s", name, r.Tree())
+
+			return
+		}
+	}
+}
+
+// Tree renders the recorded spans as an indented tree grouped by trace, listing each span's kind,
+// status, and attributes. It is intended for test failure messages.
+func (r *Recorder) Tree() string {
+	spans := r.Spans()
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+
+	children := make(map[trace.SpanID][]tracetest.SpanStub)
+	recorded := make(map[trace.SpanID]bool)
+
+	for _, span := range spans {
+		recorded[span.SpanContext.SpanID()] = true
+	}
+
+	var roots []tracetest.SpanStub
+
+	for _, span := range spans {
+		parentID := span.Parent.SpanID()
+		if span.Parent.IsValid() && recorded[parentID] {
+			children[parentID] = append(children[parentID], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	// Roots of one trace need not be adjacent in start order, for example when a trace has spans
+	// whose parents were not recorded, so they are grouped by trace before rendering. Traces keep
+	// the order of their earliest root.
+	var traceIDs []trace.TraceID
+
+	traceRoots := make(map[trace.TraceID][]tracetest.SpanStub)
+
+	for _, root := range roots {
+		traceID := root.SpanContext.TraceID()
+		if _, ok := traceRoots[traceID]; !ok {
+			traceIDs = append(traceIDs, traceID)
+		}
+
+		traceRoots[traceID] = append(traceRoots[traceID], root)
+	}
+
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "recorded spans (%d):\n", len(spans))
+
+	for _, traceID := range traceIDs {
+		fmt.Fprintf(&builder, "trace %s\n", traceID)
+
+		for _, root := range traceRoots[traceID] {
+			writeTree(&builder, root, children, 1)
+		}
+	}
+
+	return builder.String()
+}
+
+// writeTree writes span and its descendants to builder at the given depth.
+func writeTree(builder *strings.Builder, span tracetest.SpanStub, children map[trace.SpanID][]tracetest.SpanStub, depth int) {
+	fmt.Fprintf(builder, "%s- %s [%s, %s]", strings.Repeat("  ", depth), span.Name, span.SpanKind, span.Status.Code)
+
+	if len(span.Attributes) > 0 {
+		fmt.Fprintf(builder, " %s", formatAttributes(span.Attributes))
+	}
+
+	builder.WriteString("\n")
+
+	for _, child := range children[span.SpanContext.SpanID()] {
+		writeTree(builder, child, children, depth+1)
+	}
+}
+
+// formatAttributes renders attrs as {key=value, ...}.
+func formatAttributes(attrs []attribute.KeyValue) string {
+	items := make([]string, 0, len(attrs))
+
+	for _, attr := range attrs {
+		items = append(items, fmt.Sprintf("%s=%s", attr.Key, attr.Value.Emit()))
+	}
+
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// hasAttributes reports whether span carries every attribute in attrs with an equal value.
+func hasAttributes(span tracetest.SpanStub, attrs []attribute.KeyValue) bool {
+	set := attribute.NewSet(span.Attributes...)
+
+	for _, attr := range attrs {
+		val, ok := set.Value(attr.Key)
+		if !ok || val != attr.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isChildOf reports whether parent is the direct parent of child.
+func isChildOf(child, parent tracetest.SpanStub) bool {
+	return child.Parent.TraceID() == parent.SpanContext.TraceID() && child.Parent.SpanID() == parent.SpanContext.SpanID()
+}
+
+// isError reports whether span has an error status or an exception event.
+func isError(span tracetest.SpanStub) bool {
+	if span.Status.Code == codes.Error {
+		return true
+	}
+
+	for _, event := range span.Events {
+		if event.Name == semconv.ExceptionEventName {
+			return true
+		}
+	}
+
+	return false
+}
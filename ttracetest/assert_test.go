@@ -0,0 +1,189 @@
+package ttracetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingTB is a [testing.TB] that records Errorf calls instead of failing the test, so that
+// failing assertions can be checked. Other methods are delegated to the real test.
+type recordingTB struct {
+	testing.TB
+
+	errors []string
+}
+
+// Errorf records the formatted message.
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+// newRecordingRecorder returns a [Recorder] whose assertion failures are collected in the returned
+// [recordingTB].
+func newRecordingRecorder(t *testing.T) (*Recorder, *recordingTB) {
+	t.Helper()
+
+	tb := &recordingTB{TB: t}
+
+	return New(tb), tb
+}
+
+// recordCheckout records a server span "checkout" with a child "charge" that failed.
+func recordCheckout(r *Recorder) {
+	tracer := r.TracerProvider().Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "checkout",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("order.id", "42")),
+	)
+
+	_, child := tracer.Start(ctx, "charge")
+	child.RecordError(errors.New("card declined"))
+	child.SetStatus(codes.Error, "card declined")
+	child.End()
+
+	parent.End()
+}
+
+func TestAssertionsPass(t *testing.T) {
+	r, tb := newRecordingRecorder(t)
+
+	recordCheckout(r)
+
+	span := r.AssertSpan("checkout", attribute.String("order.id", "42"))
+	if span.Name != "checkout" {
+		t.Errorf("AssertSpan returned span %q, want checkout", span.Name)
+	}
+
+	r.AssertNoSpan("refund")
+	r.AssertChildOf("charge", "checkout")
+	r.AssertStatus("charge", codes.Error)
+	r.AssertError("charge")
+	r.AssertNoError("checkout")
+
+	if len(tb.errors) > 0 {
+		t.Fatalf("assertions failed: %q", tb.errors)
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert func(r *Recorder)
+		want   string
+	}{
+		{
+			name:   "AssertSpan missing",
+			assert: func(r *Recorder) { r.AssertSpan("refund") },
+			want:   `no span named "refund"`,
+		},
+		{
+			name:   "AssertSpan attributes",
+			assert: func(r *Recorder) { r.AssertSpan("checkout", attribute.String("order.id", "7")) },
+			want:   `no span named "checkout" with attributes {order.id=7}`,
+		},
+		{
+			name:   "AssertNoSpan",
+			assert: func(r *Recorder) { r.AssertNoSpan("charge") },
+			want:   `unexpected span named "charge"`,
+		},
+		{
+			name:   "AssertChildOf",
+			assert: func(r *Recorder) { r.AssertChildOf("checkout", "charge") },
+			want:   `no span named "checkout" is a child of a span named "charge"`,
+		},
+		{
+			name:   "AssertStatus",
+			assert: func(r *Recorder) { r.AssertStatus("checkout", codes.Ok) },
+			want:   `no span named "checkout" with status Ok`,
+		},
+		{
+			name:   "AssertError",
+			assert: func(r *Recorder) { r.AssertError("checkout") },
+			want:   `no span named "checkout" recorded an error`,
+		},
+		{
+			name:   "AssertNoError",
+			assert: func(r *Recorder) { r.AssertNoError("charge") },
+			want:   `span named "charge" recorded an error`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, tb := newRecordingRecorder(t)
+
+			recordCheckout(r)
+			tt.assert(r)
+
+			if len(tb.errors) != 1 {
+				t.Fatalf("got %d errors %q, want 1", len(tb.errors), tb.errors)
+			}
+
+			if !strings.Contains(tb.errors[0], tt.want) {
+				t.Errorf("error = %q, want it to contain %q", tb.errors[0], tt.want)
+			}
+
+			if !strings.Contains(tb.errors[0], "recorded spans (2):") {
+				t.Errorf("error = %q, want it to include the span tree", tb.errors[0])
+			}
+		})
+	}
+}
+
+func TestTreeGroupsRootsByTrace(t *testing.T) {
+	r := New(t)
+	tracer := r.TracerProvider().Tracer("test")
+
+	start := time.Now()
+
+	ctxA, rootA := tracer.Start(context.Background(), "a", trace.WithTimestamp(start))
+	_, childA := tracer.Start(ctxA, "a.child", trace.WithTimestamp(start.Add(time.Millisecond)))
+
+	_, rootB := tracer.Start(context.Background(), "b",
+		trace.WithNewRoot(),
+		trace.WithTimestamp(start.Add(2*time.Millisecond)),
+		trace.WithAttributes(attribute.Int("n", 1)),
+	)
+
+	// A span of trace A whose parent was not recorded starts after trace B and is rendered as a
+	// second root of trace A.
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    rootA.SpanContext().TraceID(),
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	_, orphan := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "a.orphan",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(start.Add(3*time.Millisecond)),
+	)
+
+	orphan.End()
+	rootB.End()
+	childA.End()
+	rootA.End()
+
+	want := fmt.Sprintf(`recorded spans (4):
+trace %s
+  - a [internal, Unset]
+    - a.child [internal, Unset]
+  - a.orphan [consumer, Unset]
+trace %s
+  - b [internal, Unset] {n=1}
+`, rootA.SpanContext().TraceID(), rootB.SpanContext().TraceID())
+
+	got := r.Tree()
+	if got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,8 @@
+// Package ttracetest provides an in-memory span recorder and assertions for tests of code
+// instrumented with [github.com/choveylee/ttrace].
+//
+// [New] installs an always-sampling SDK TracerProvider backed by a span recorder, together with
+// the W3C Trace Context and Baggage propagators, as the global OpenTelemetry providers for the
+// duration of a test. The previous providers are restored through [testing.TB.Cleanup]. Because
+// the providers are global, tests that use [New] must not run in parallel with each other.
+package ttracetest
@@ -0,0 +1,85 @@
+package ttracetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/choveylee/ttrace"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Recorder captures the spans ended while it is installed and offers assertions over them.
+type Recorder struct {
+	t testing.TB
+
+	recorder       *tracetest.SpanRecorder
+	tracerProvider *sdktrace.TracerProvider
+}
+
+// New installs a Recorder as the global TracerProvider and registers a cleanup on t that shuts it
+// down and restores the previous global TracerProvider and TextMapPropagator.
+func New(t testing.TB) *Recorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+
+	propagator, err := ttrace.NewPropagator(ttrace.PropagatorTraceContext, ttrace.PropagatorBaggage)
+	if err != nil {
+		t.Fatalf("ttracetest: build propagator: %v", err)
+	}
+
+	prevTracerProvider := otel.GetTracerProvider()
+	prevPropagator := otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagator)
+
+	t.Cleanup(func() {
+		_ = tracerProvider.Shutdown(context.Background())
+
+		otel.SetTracerProvider(prevTracerProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	return &Recorder{
+		t: t,
+
+		recorder:       recorder,
+		tracerProvider: tracerProvider,
+	}
+}
+
+// TracerProvider returns the SDK TracerProvider installed by [New].
+func (r *Recorder) TracerProvider() *sdktrace.TracerProvider {
+	return r.tracerProvider
+}
+
+// Spans returns the spans ended so far, in the order they ended.
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return tracetest.SpanStubsFromReadOnlySpans(r.recorder.Ended())
+}
+
+// SpansByName returns the ended spans named name, in the order they ended.
+func (r *Recorder) SpansByName(name string) tracetest.SpanStubs {
+	var spans tracetest.SpanStubs
+
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// Reset discards all recorded spans.
+func (r *Recorder) Reset() {
+	r.recorder.Reset()
+}